@@ -2,39 +2,76 @@ package main
 
 import (
 	"context"
-	"errors"
+	"crypto/tls"
+	"crypto/x509"
 	"log"
 	"net/http"
 	"os"
 	"os/signal"
 	"strconv"
+	"strings"
 	"syscall"
 	"time"
 
-	"github.com/golang-migrate/migrate/v4"
-	_ "github.com/golang-migrate/migrate/v4/database/postgres"
-	"github.com/golang-migrate/migrate/v4/source/iofs"
+	chimw "github.com/go-chi/chi/v5/middleware"
 	"github.com/prometheus/client_golang/prometheus/promhttp"
 
 	"github.com/locplace/scanner/internal/coordinator"
 	"github.com/locplace/scanner/internal/coordinator/db"
 	"github.com/locplace/scanner/internal/coordinator/feeder"
+	"github.com/locplace/scanner/internal/coordinator/handlers"
 	"github.com/locplace/scanner/internal/coordinator/metrics"
+	"github.com/locplace/scanner/internal/coordinator/middleware"
 	"github.com/locplace/scanner/internal/coordinator/reaper"
-	"github.com/locplace/scanner/migrations"
+	"github.com/locplace/scanner/internal/coordinator/tldfilter"
 )
 
 func main() {
 	// Configuration from environment
 	databaseURL := getEnv("DATABASE_URL", "postgres://localhost:5432/locscanner?sslmode=disable")
 	dbMaxConns := parseInt("DB_MAX_CONNS", 0) // 0 = use pgxpool default
+	timeOrderedIDs := parseBool("TIME_ORDERED_IDS", false)
+	migrateOnStart := parseBool("MIGRATE_ON_START", true)
 	adminAPIKey := os.Getenv("ADMIN_API_KEY")
 	listenAddr := getEnv("LISTEN_ADDR", ":8080")
 	metricsAddr := getEnv("METRICS_ADDR", ":9090")
+	tlsCertFile := os.Getenv("TLS_CERT_FILE") // Optional: serve HTTPS directly
+	tlsKeyFile := os.Getenv("TLS_KEY_FILE")
+	scannerClientCAFile := os.Getenv("SCANNER_CLIENT_CA_FILE") // CA bundle for scanner client certificates
 	metricsInterval := parseDuration("METRICS_INTERVAL", 15*time.Second)
+	metricsStartupJitter := parseDuration("METRICS_STARTUP_JITTER", 5*time.Second)
+	metricsIntervalJitter := parseDuration("METRICS_INTERVAL_JITTER", 0)
+	metricsTopTLDs := parseInt("METRICS_TOP_TLDS", 20)
 	heartbeatTimeout := parseDuration("HEARTBEAT_TIMEOUT", 2*time.Minute)
 	reaperInterval := parseDuration("REAPER_INTERVAL", 60*time.Second)
 	batchTimeout := parseDuration("BATCH_TIMEOUT", 10*time.Minute)
+	feedSize := parseInt("FEED_SIZE", 50)
+	parseRateLimit := parseInt("PARSE_RATE_LIMIT", 30)
+	idempotencyTTL := parseDuration("IDEMPOTENCY_TTL", 15*time.Minute)
+	statsCacheTTL := parseDuration("STATS_CACHE_TTL", 30*time.Second)
+	slowRequestThreshold := parseDuration("SLOW_REQUEST_THRESHOLD", 5*time.Second) // 0 = no slow request logs
+	minScannerAPIVersion := parseInt("MIN_SCANNER_API_VERSION", 1)
+	maintenanceMode := parseBool("MAINTENANCE_MODE", false)
+	maxConcurrentExports := parseInt("MAX_CONCURRENT_EXPORTS", 4)
+	keepUnparsedRecords := parseBool("KEEP_UNPARSED_RECORDS", true)
+	staleAfter := parseDuration("STALE_AFTER", handlers.DefaultStaleAfter)
+	changesRetention := parseDuration("CHANGES_RETENTION", 30*24*time.Hour)
+	maxHorizPrecM := parseInt("MAX_ACCEPTABLE_HORIZ_PREC_M", 0)
+	referrerAllowlist := os.Getenv("REFERRER_ALLOWLIST") // Comma-separated domains
+	referrerMaxDomains := parseInt("REFERRER_MAX_DOMAINS", 50)
+	referrerHashSalt := os.Getenv("REFERRER_HASH_SALT")
+
+	// Cache-Control max-age per export format (0 = no header)
+	cacheTTLs := handlers.DefaultCacheTTLs()
+	cacheTTLs.Records = parseDuration("CACHE_TTL_RECORDS", cacheTTLs.Records)
+	cacheTTLs.GeoJSON = parseDuration("CACHE_TTL_GEOJSON", cacheTTLs.GeoJSON)
+	cacheTTLs.JSONL = parseDuration("CACHE_TTL_JSONL", cacheTTLs.JSONL)
+	cacheTTLs.Feed = parseDuration("CACHE_TTL_FEED", cacheTTLs.Feed)
+
+	// Coordinate rounding per export format (negative = full precision)
+	exportDecimals := handlers.DefaultExportDecimals()
+	exportDecimals.GeoJSON = parseInt("GEOJSON_DECIMALS", exportDecimals.GeoJSON)
+	exportDecimals.JSONL = parseInt("JSONL_DECIMALS", exportDecimals.JSONL)
 
 	// Feeder configuration
 	batchSize := parseInt("BATCH_SIZE", 1000)
@@ -46,14 +83,71 @@ func main() {
 		log.Fatal("ADMIN_API_KEY environment variable is required")
 	}
 
+	if (tlsCertFile == "") != (tlsKeyFile == "") {
+		log.Fatal("TLS_CERT_FILE and TLS_KEY_FILE must be set together")
+	}
+	useTLS := tlsCertFile != ""
+
+	scannerAuth, err := middleware.ParseScannerAuthMode(os.Getenv("SCANNER_AUTH"))
+	if err != nil {
+		log.Fatalf("Invalid SCANNER_AUTH: %v", err)
+	}
+	var scannerClientCAs *x509.CertPool
+	if scannerAuth.UsesCertificates() {
+		if !useTLS || scannerClientCAFile == "" {
+			log.Fatalf("SCANNER_AUTH=%s requires TLS_CERT_FILE, TLS_KEY_FILE and SCANNER_CLIENT_CA_FILE", scannerAuth)
+		}
+		pem, err := os.ReadFile(scannerClientCAFile)
+		if err != nil {
+			log.Fatalf("Failed to read SCANNER_CLIENT_CA_FILE: %v", err)
+		}
+		scannerClientCAs = x509.NewCertPool()
+		if !scannerClientCAs.AppendCertsFromPEM(pem) {
+			log.Fatal("SCANNER_CLIENT_CA_FILE contains no PEM certificates")
+		}
+	}
+
+	var robotsTxt string
+	if path := os.Getenv("ROBOTS_TXT_FILE"); path != "" {
+		b, err := os.ReadFile(path)
+		if err != nil {
+			log.Fatalf("Failed to read ROBOTS_TXT_FILE: %v", err)
+		}
+		robotsTxt = string(b)
+	}
+
+	var publicBaseURL string
+	if s := os.Getenv("PUBLIC_BASE_URL"); s != "" {
+		var err error
+		if publicBaseURL, err = handlers.ParseBaseURL(s); err != nil {
+			log.Fatalf("Invalid PUBLIC_BASE_URL: %v", err)
+		}
+	}
+
+	referrerMode, err := metrics.ParseReferrerMode(os.Getenv("REFERRER_MODE"))
+	if err != nil {
+		log.Fatalf("Invalid REFERRER_MODE: %v", err)
+	}
+
+	trustedProxies, err := middleware.ParseTrustedProxies(os.Getenv("TRUSTED_PROXIES"))
+	if err != nil {
+		log.Fatalf("Invalid TRUSTED_PROXIES: %v", err)
+	}
+
+	tldFilter, err := tldfilter.Parse(os.Getenv("TLD_ALLOWLIST"), os.Getenv("TLD_DENYLIST"))
+	if err != nil {
+		log.Fatalf("Invalid TLD_ALLOWLIST or TLD_DENYLIST: %v", err)
+	}
+
 	// Register Prometheus metrics
 	metrics.Register()
 
 	// Connect to database
 	ctx := context.Background()
 	database, err := db.New(ctx, db.Config{
-		URL:      databaseURL,
-		MaxConns: int32(dbMaxConns),
+		URL:            databaseURL,
+		MaxConns:       int32(dbMaxConns),
+		TimeOrderedIDs: timeOrderedIDs,
 	})
 	if err != nil {
 		log.Fatalf("Failed to connect to database: %v", err)
@@ -62,24 +156,67 @@ func main() {
 	log.Println("Connected to database")
 
 	// Run migrations
-	if err := runMigrations(databaseURL); err != nil {
-		log.Fatalf("Failed to run migrations: %v", err)
+	if migrateOnStart {
+		if err := db.MigrateUp(databaseURL); err != nil {
+			log.Fatalf("Failed to run migrations: %v", err)
+		}
+		log.Println("Migrations completed")
+	} else {
+		checkSchemaVersion(ctx, database)
 	}
 
+	// Shared by the API, reaper and feeder, which all pause with it
+	maintenance := middleware.NewMaintenance(maintenanceMode)
+
 	// Create server
 	cfg := coordinator.Config{
-		AdminAPIKey:      adminAPIKey,
-		HeartbeatTimeout: heartbeatTimeout,
+		AdminAPIKey:          adminAPIKey,
+		HeartbeatTimeout:     heartbeatTimeout,
+		FeedSize:             feedSize,
+		ParseRateLimit:       parseRateLimit,
+		IdempotencyTTL:       idempotencyTTL,
+		TrustedProxies:       trustedProxies,
+		ScannerAuth:          scannerAuth,
+		CacheTTLs:            cacheTTLs,
+		ExportDecimals:       exportDecimals,
+		StatsCacheTTL:        statsCacheTTL,
+		Maintenance:          maintenance,
+		MaxConcurrentExports: maxConcurrentExports,
+		RobotsTxt:            robotsTxt,
+		PublicBaseURL:        publicBaseURL,
+		KeepUnparsedRecords:  keepUnparsedRecords,
+		StaleAfter:           staleAfter,
+		ChangesRetention:     changesRetention,
+		TLDFilter:            tldFilter,
+		MaxHorizPrecM:        float64(maxHorizPrecM),
+		MinScannerAPIVersion: minScannerAPIVersion,
 	}
 	handler := coordinator.NewServer(database, cfg)
 
-	// Wrap with metrics middleware
+	// Wrap with metrics middleware. RequestID runs first so slow request logs
+	// and the router's access log share the same ID.
+	referrers := metrics.NewReferrerBucketer(strings.Split(referrerAllowlist, ","), referrerMaxDomains)
+	referrers.Mode, referrers.HashSalt = referrerMode, referrerHashSalt
+	metricsMiddleware := metrics.NewMiddleware(metrics.MiddlewareConfig{
+		Referrers:            referrers,
+		SlowRequestThreshold: slowRequestThreshold,
+	})
 	server := &http.Server{
 		Addr:         listenAddr,
-		Handler:      metrics.Middleware(handler),
+		Handler:      chimw.RequestID(metricsMiddleware(handler)),
 		ReadTimeout:  30 * time.Second,
 		WriteTimeout: 30 * time.Second,
 	}
+	if useTLS {
+		// ListenAndServeTLS negotiates HTTP/2 via ALPN when NextProtos is unset
+		server.TLSConfig = &tls.Config{MinVersion: tls.VersionTLS12}
+		if scannerClientCAs != nil {
+			// Public endpoints stay open to browsers, so certificates are optional
+			// at the TLS layer; ScannerAuth decides whether one is required
+			server.TLSConfig.ClientCAs = scannerClientCAs
+			server.TLSConfig.ClientAuth = tls.VerifyClientCertIfGiven
+		}
+	}
 
 	// Create background context for all goroutines
 	bgCtx, cancelBg := context.WithCancel(context.Background())
@@ -89,6 +226,9 @@ func main() {
 	metricsUpdater := metrics.NewUpdater(database, metrics.UpdaterConfig{
 		Interval:         metricsInterval,
 		HeartbeatTimeout: heartbeatTimeout,
+		StartupJitter:    metricsStartupJitter,
+		IntervalJitter:   metricsIntervalJitter,
+		TopTLDs:          metricsTopTLDs,
 	})
 	go metricsUpdater.Run(bgCtx)
 
@@ -110,6 +250,8 @@ func main() {
 		Interval:         reaperInterval,
 		BatchTimeout:     batchTimeout,
 		HeartbeatTimeout: heartbeatTimeout,
+		ChangesRetention: changesRetention,
+		Paused:           maintenance.Enabled,
 	}
 	go r.Run(bgCtx)
 
@@ -119,6 +261,8 @@ func main() {
 		MaxPendingBatches: maxPendingBatches,
 		PollInterval:      feederPollInterval,
 		GitHubToken:       githubToken,
+		TLDFilter:         tldFilter,
+		Paused:            maintenance.Enabled,
 	}
 	if githubToken != "" {
 		log.Println("Feeder: using authenticated GitHub LFS downloads")
@@ -141,8 +285,15 @@ func main() {
 
 	// Start main server
 	go func() {
-		log.Printf("Coordinator listening on %s", listenAddr)
-		if err := server.ListenAndServe(); err != http.ErrServerClosed {
+		var err error
+		if useTLS {
+			log.Printf("Coordinator listening on %s (TLS)", listenAddr)
+			err = server.ListenAndServeTLS(tlsCertFile, tlsKeyFile)
+		} else {
+			log.Printf("Coordinator listening on %s", listenAddr)
+			err = server.ListenAndServe()
+		}
+		if err != http.ErrServerClosed {
 			log.Fatalf("Server error: %v", err)
 		}
 	}()
@@ -201,25 +352,40 @@ func parseInt(key string, defaultVal int) int {
 	return v
 }
 
-func runMigrations(databaseURL string) error {
-	// Create migration source from embedded files
-	source, err := iofs.New(migrations.FS, ".")
+func parseBool(key string, defaultVal bool) bool {
+	s := os.Getenv(key)
+	if s == "" {
+		return defaultVal
+	}
+	v, err := strconv.ParseBool(s)
 	if err != nil {
-		return err
+		log.Printf("Invalid bool for %s: %v, using default", key, err)
+		return defaultVal
 	}
+	return v
+}
 
-	// Create migrator using database URL
-	m, err := migrate.NewWithSourceInstance("iofs", source, databaseURL)
+// checkSchemaVersion warns when migrations are left to an operator and the
+// schema does not match this build.
+func checkSchemaVersion(ctx context.Context, database *db.DB) {
+	version, dirty, err := database.SchemaVersion(ctx)
 	if err != nil {
-		return err
+		log.Printf("Warning: failed to read schema version: %v", err)
+		return
 	}
-	defer m.Close() //nolint:errcheck // Close error not actionable
-
-	// Run migrations
-	if err := m.Up(); err != nil && !errors.Is(err, migrate.ErrNoChange) {
-		return err
+	latest, err := db.LatestMigration()
+	if err != nil {
+		log.Printf("Warning: failed to read embedded migrations: %v", err)
+		return
+	}
+	switch {
+	case dirty:
+		log.Printf("Warning: schema version %d is dirty (a migration failed halfway), fix it before migrating", version)
+	case version < latest:
+		log.Printf("Warning: schema version %d is behind this build (%d), run migrations or set MIGRATE_ON_START=true", version, latest)
+	case version > latest:
+		log.Printf("Warning: schema version %d is ahead of this build (%d)", version, latest)
+	default:
+		log.Printf("Schema version %d", version)
 	}
-
-	log.Println("Migrations completed")
-	return nil
 }
@@ -25,8 +25,14 @@ func main() {
 	}
 
 	config.Token = os.Getenv("SCANNER_TOKEN")
-	if config.Token == "" {
-		log.Fatal("SCANNER_TOKEN environment variable is required")
+	if certFile := os.Getenv("SCANNER_TLS_CERT_FILE"); certFile != "" {
+		tlsConfig, err := scanner.ClientTLSConfig(certFile, os.Getenv("SCANNER_TLS_KEY_FILE"), os.Getenv("COORDINATOR_CA_FILE"))
+		if err != nil {
+			log.Fatalf("Invalid scanner TLS configuration: %v", err)
+		}
+		config.TLSConfig = tlsConfig
+	} else if config.Token == "" {
+		log.Fatal("SCANNER_TOKEN (or SCANNER_TLS_CERT_FILE and SCANNER_TLS_KEY_FILE) environment variable is required")
 	}
 
 	if v := os.Getenv("WORKER_COUNT"); v != "" {
@@ -48,12 +54,42 @@ func main() {
 		}
 	}
 
+	if v := os.Getenv("DNS_SERVERS"); v != "" {
+		nameservers, err := scanner.ParseNameservers(v)
+		if err != nil {
+			log.Fatalf("Invalid DNS_SERVERS: %v", err)
+		}
+		config.DNSConfig.Nameservers = nameservers
+	}
+
 	if v := os.Getenv("DNS_TIMEOUT"); v != "" {
 		if d, err := time.ParseDuration(v); err == nil {
 			config.DNSConfig.Timeout = d
 		}
 	}
 
+	config.StateFile = os.Getenv("SCANNER_STATE_FILE")
+
+	if v := os.Getenv("KEEP_BEST_PRECISION"); v != "" {
+		if b, err := strconv.ParseBool(v); err == nil {
+			config.KeepBestPrecision = b
+		}
+	}
+
+	if v := os.Getenv("REPLACE_IF_NEWER"); v != "" {
+		if b, err := strconv.ParseBool(v); err == nil {
+			config.ReplaceIfNewer = b
+		}
+	}
+
+	if v := os.Getenv("COLLECT_DOMAIN_FACTS"); v != "" {
+		if b, err := strconv.ParseBool(v); err == nil {
+			config.CollectDomainFacts = b
+		}
+	}
+
+	log.Printf("Scanner version %s", scanner.Version)
+
 	// Create scanner
 	s := scanner.New(config)
 
@@ -4,20 +4,34 @@ package frontend
 import (
 	"embed"
 	"io/fs"
+	"log"
 	"net/http"
 	"strings"
 )
 
+// build/.gitkeep keeps the pattern matching when the frontend was not built.
+//
 //go:embed build/*
 var assets embed.FS
 
 // Handler returns an http.Handler that serves the embedded frontend.
 // It strips the "build" prefix and serves index.html for SPA routes.
+// If the frontend was not built, every request gets a 503 instead.
 func Handler() http.Handler {
 	// Strip the "build" prefix
 	sub, err := fs.Sub(assets, "build")
 	if err != nil {
-		panic(err)
+		log.Printf("Frontend assets unavailable: %v", err)
+		return notBuiltHandler()
+	}
+	return handler(sub)
+}
+
+// handler serves the frontend from sub, which holds the build output.
+func handler(sub fs.FS) http.Handler {
+	if _, err := fs.Stat(sub, "index.html"); err != nil {
+		log.Printf("Frontend not built (index.html missing): run `npm run build` in frontend/ before building the server")
+		return notBuiltHandler()
 	}
 
 	fileServer := http.FileServer(http.FS(sub))
@@ -45,6 +59,15 @@ func Handler() http.Handler {
 	})
 }
 
+// notBuiltHandler responds 503 to every request, for binaries built
+// without frontend assets. The API routes are unaffected.
+func notBuiltHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Cache-Control", "no-store")
+		http.Error(w, "frontend not built: this server was compiled without frontend assets", http.StatusServiceUnavailable)
+	})
+}
+
 // setCacheHeaders sets appropriate Cache-Control headers based on the file path.
 func setCacheHeaders(w http.ResponseWriter, path string) {
 	// SvelteKit puts hashed assets in /_app/immutable/ - cache forever
@@ -0,0 +1,47 @@
+package frontend
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+func TestHandler_NotBuilt(t *testing.T) {
+	h := handler(fstest.MapFS{})
+
+	for _, path := range []string{"/", "/records", "/favicon.png"} {
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+		if rec.Code != http.StatusServiceUnavailable {
+			t.Errorf("GET %s: status = %d, want 503", path, rec.Code)
+		}
+		if !strings.Contains(rec.Body.String(), "frontend not built") {
+			t.Errorf("GET %s: body = %q", path, rec.Body.String())
+		}
+	}
+}
+
+func TestHandler_SPAFallback(t *testing.T) {
+	h := handler(fstest.MapFS{
+		"index.html":  {Data: []byte("<html>app</html>")},
+		"favicon.png": {Data: []byte("png")},
+	})
+
+	tests := []struct {
+		path string
+		want string
+	}{
+		{"/", "<html>app</html>"},
+		{"/some/route", "<html>app</html>"},
+		{"/favicon.png", "png"},
+	}
+	for _, tt := range tests {
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, tt.path, nil))
+		if rec.Code != http.StatusOK || rec.Body.String() != tt.want {
+			t.Errorf("GET %s = %d %q, want 200 %q", tt.path, rec.Code, rec.Body.String(), tt.want)
+		}
+	}
+}
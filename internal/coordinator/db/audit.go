@@ -0,0 +1,84 @@
+package db
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/locplace/scanner/pkg/api"
+)
+
+// Audit log actions.
+const (
+	AuditRecordUpdate   = "record.update"
+	AuditRecordsReparse = "records.reparse"
+	AuditClientCreate   = "client.create"
+	AuditClientDelete   = "client.delete"
+	AuditClientsPrune   = "clients.prune"
+	AuditFilesDiscover  = "files.discover"
+	AuditFileRescan     = "file.rescan"
+	AuditScanReset      = "scan.reset"
+	AuditScanManual     = "scan.manual"
+	AuditMaintenanceSet = "maintenance.set"
+)
+
+// AuditEntry is an administrative change to record in audit_log. Target
+// identifies what was changed, e.g. an FQDN or client ID, if anything in
+// particular; Details is stored as JSON. Actor is the admin key's
+// identifier, "" if unknown.
+type AuditEntry struct {
+	Action  string
+	Target  string
+	Details any
+	Actor   string
+}
+
+// InsertAuditEntry writes e to the audit log.
+func (db *DB) InsertAuditEntry(ctx context.Context, e AuditEntry) error {
+	return insertAuditEntry(ctx, db.Pool, e)
+}
+
+// insertAuditEntry writes e with q, so it can share the transaction of the
+// change it describes.
+func insertAuditEntry(ctx context.Context, q querier, e AuditEntry) error {
+	details, err := json.Marshal(e.Details)
+	if err != nil {
+		return err
+	}
+	_, err = q.Exec(ctx, `
+		INSERT INTO audit_log (action, target, details, actor) VALUES ($1, $2, $3, NULLIF($4, ''))
+	`, e.Action, e.Target, details, e.Actor)
+	return err
+}
+
+// ListAuditEntries returns a page of the audit log entries made after since
+// (all if zero), oldest first, and the total number of such entries.
+func (db *DB) ListAuditEntries(ctx context.Context, since time.Time, limit, offset int) ([]api.AuditEntry, int, error) {
+	var total int
+	err := db.Pool.QueryRow(ctx, `SELECT COUNT(*) FROM audit_log WHERE created_at > $1`, since).Scan(&total)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	rows, err := db.Pool.Query(ctx, `
+		SELECT id, action, target, details, COALESCE(actor, ''), created_at
+		FROM audit_log
+		WHERE created_at > $1
+		ORDER BY created_at, id
+		LIMIT $2 OFFSET $3
+	`, since, limit, offset)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	var entries []api.AuditEntry
+	for rows.Next() {
+		var e api.AuditEntry
+		if err := rows.Scan(&e.ID, &e.Action, &e.Target, &e.Details, &e.Actor, &e.CreatedAt); err != nil {
+			return nil, 0, err
+		}
+		entries = append(entries, e)
+	}
+	return entries, total, rows.Err()
+}
@@ -2,7 +2,10 @@ package db
 
 import (
 	"context"
+	"errors"
 	"time"
+
+	"github.com/jackc/pgx/v5"
 )
 
 // ScanBatch represents a batch of domains to scan.
@@ -127,19 +130,14 @@ func (db *DB) ClaimBatch(ctx context.Context, scannerID, sessionID string) (*Sca
 	return &b, nil
 }
 
-// CompleteBatch marks a batch as complete (deletes it) and increments file counter.
-// Returns the file ID and the time the batch was assigned (for duration tracking).
-func (db *DB) CompleteBatch(ctx context.Context, batchID int64) (int, *time.Time, error) {
-	tx, err := db.Pool.Begin(ctx)
-	if err != nil {
-		return 0, nil, err
-	}
-	defer tx.Rollback(ctx) //nolint:errcheck
-
+// completeBatch marks a batch as complete (deletes it) and increments the
+// file counter within tx. Returns the file ID and the time the batch was
+// assigned (for duration tracking).
+func completeBatch(ctx context.Context, tx pgx.Tx, batchID int64) (int, *time.Time, error) {
 	// Get file_id and assigned_at before deleting
 	var fileID int
 	var assignedAt *time.Time
-	err = tx.QueryRow(ctx, `
+	err := tx.QueryRow(ctx, `
 		SELECT file_id, assigned_at FROM scan_batches WHERE id = $1
 	`, batchID).Scan(&fileID, &assignedAt)
 	if err != nil {
@@ -162,10 +160,6 @@ func (db *DB) CompleteBatch(ctx context.Context, batchID int64) (int, *time.Time
 		return 0, nil, err
 	}
 
-	if err := tx.Commit(ctx); err != nil {
-		return 0, nil, err
-	}
-
 	return fileID, assignedAt, nil
 }
 
@@ -203,6 +197,35 @@ func (db *DB) ResetBatchesFromDeadSessions(ctx context.Context, heartbeatTimeout
 	return int(result.RowsAffected()), nil
 }
 
+// ResumeSessionBatch hands one in_flight batch leased to a previous session
+// of the given client over to its new session, renewing the lease, and
+// returns it. Used when a restarted scanner resumes, so it picks up its own
+// outstanding leases instead of leaving them to the reaper. Returns nil once
+// the previous session holds no more batches.
+func (db *DB) ResumeSessionBatch(ctx context.Context, clientID, previousSessionID, sessionID string) (*ScanBatch, error) {
+	var b ScanBatch
+	err := db.Pool.QueryRow(ctx, `
+		UPDATE scan_batches
+		SET assigned_at = NOW(), session_id = $3
+		WHERE id = (
+			SELECT id FROM scan_batches
+			WHERE status = 'in_flight' AND session_id = $1 AND scanner_id = $2
+			ORDER BY id
+			LIMIT 1
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING id, file_id, line_start, line_end, domains
+	`, previousSessionID, clientID, sessionID).Scan(&b.ID, &b.FileID, &b.LineStart, &b.LineEnd, &b.Domains)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	b.Status = "in_flight"
+	return &b, nil
+}
+
 // DeleteBatchesForFile deletes all batches for a file.
 func (db *DB) DeleteBatchesForFile(ctx context.Context, fileID int) error {
 	_, err := db.Pool.Exec(ctx, `DELETE FROM scan_batches WHERE file_id = $1`, fileID)
@@ -0,0 +1,150 @@
+package db
+
+import (
+	"cmp"
+	"context"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+
+	"github.com/locplace/scanner/pkg/api"
+)
+
+// markChanged is the SET clause every write to loc_records includes, so the
+// changes feed sees it (see migration 000023).
+const markChanged = `changed_at = clock_timestamp(), change_id = nextval('loc_change_id_seq')`
+
+// ChangesSettleDelay is how old a change must be before the changes feed
+// returns it. changed_at is stamped when a row is written, not when its
+// transaction commits; holding recent changes back lets transactions still
+// in flight commit before a client's cursor moves past them.
+const ChangesSettleDelay = time.Minute
+
+// ChangeCursor is a position in the changes feed. Changes are ordered by
+// when they were written, then by change ID.
+type ChangeCursor struct {
+	ChangedAt time.Time
+	ChangeID  int64
+}
+
+// Changes is a page of the changes feed.
+type Changes struct {
+	Records []api.PublicLOCRecord
+	Removed []api.RemovedRecord
+	Next    ChangeCursor // Position after the last change, or the start if none
+	More    bool         // More changes are ready
+}
+
+// change is a record or tombstone with its feed position.
+type change struct {
+	ChangeCursor
+	record  *api.PublicLOCRecord
+	removed *api.RemovedRecord
+}
+
+// ListChanges returns up to limit records written and removed after the
+// cursor, oldest change first. Changes newer than ChangesSettleDelay are
+// held back. Tombstones of records that have since been stored again are
+// left out, as the stored record is reported instead.
+func (db *DB) ListChanges(ctx context.Context, after ChangeCursor, limit int) (Changes, error) {
+	tx, err := db.Pool.BeginTx(ctx, pgx.TxOptions{IsoLevel: pgx.RepeatableRead, AccessMode: pgx.ReadOnly})
+	if err != nil {
+		return Changes{}, err
+	}
+	defer tx.Rollback(ctx) //nolint:errcheck // Read-only
+
+	var now time.Time
+	if err := tx.QueryRow(ctx, `SELECT NOW()`).Scan(&now); err != nil {
+		return Changes{}, err
+	}
+	horizon := now.Add(-ChangesSettleDelay)
+
+	// Each side is read one past the limit, so merging can tell whether
+	// anything is left
+	var records []change
+	rows, err := tx.Query(ctx, `
+		SELECT `+publicRecordColumns+`, changed_at, change_id
+		FROM loc_records
+		WHERE (changed_at, change_id) > ($1, $2) AND changed_at < $3
+		ORDER BY changed_at, change_id
+		LIMIT $4
+	`, after.ChangedAt, after.ChangeID, horizon, limit+1)
+	if err != nil {
+		return Changes{}, err
+	}
+	for rows.Next() {
+		var c change
+		r, err := scanPublicRecord(rows, &c.ChangedAt, &c.ChangeID)
+		if err != nil {
+			rows.Close()
+			return Changes{}, err
+		}
+		c.record = &r
+		records = append(records, c)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return Changes{}, err
+	}
+
+	var removed []change
+	rows, err = tx.Query(ctx, `
+		SELECT d.fqdn, d.root_domain, d.raw_record, d.deleted_at, d.change_id
+		FROM loc_record_deletions d
+		WHERE (d.deleted_at, d.change_id) > ($1, $2) AND d.deleted_at < $3
+		  AND NOT EXISTS (
+			SELECT 1 FROM loc_records l WHERE l.fqdn = d.fqdn AND l.raw_record = d.raw_record
+		  )
+		ORDER BY d.deleted_at, d.change_id
+		LIMIT $4
+	`, after.ChangedAt, after.ChangeID, horizon, limit+1)
+	if err != nil {
+		return Changes{}, err
+	}
+	for rows.Next() {
+		var r api.RemovedRecord
+		var c change
+		if err := rows.Scan(&r.FQDN, &r.RootDomain, &r.RawRecord, &r.RemovedAt, &c.ChangeID); err != nil {
+			rows.Close()
+			return Changes{}, err
+		}
+		c.ChangedAt, c.removed = r.RemovedAt, &r
+		removed = append(removed, c)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return Changes{}, err
+	}
+
+	return mergeChanges(after, records, removed, limit), nil
+}
+
+// mergeChanges merges the records and tombstones read after the cursor,
+// each in feed order, into a page of at most limit changes.
+func mergeChanges(after ChangeCursor, records, removed []change, limit int) Changes {
+	out := Changes{Next: after}
+	var i, j int
+	for i+j < limit && (i < len(records) || j < len(removed)) {
+		var c change
+		if j == len(removed) || (i < len(records) && compareCursors(records[i].ChangeCursor, removed[j].ChangeCursor) < 0) {
+			c = records[i]
+			i++
+			out.Records = append(out.Records, *c.record)
+		} else {
+			c = removed[j]
+			j++
+			out.Removed = append(out.Removed, *c.removed)
+		}
+		out.Next = c.ChangeCursor
+	}
+	out.More = i < len(records) || j < len(removed)
+	return out
+}
+
+// compareCursors orders two feed positions.
+func compareCursors(a, b ChangeCursor) int {
+	if c := a.ChangedAt.Compare(b.ChangedAt); c != 0 {
+		return c
+	}
+	return cmp.Compare(a.ChangeID, b.ChangeID)
+}
@@ -0,0 +1,45 @@
+package db
+
+import (
+	"testing"
+	"time"
+
+	"github.com/locplace/scanner/pkg/api"
+)
+
+func TestMergeChanges(t *testing.T) {
+	t0 := time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)
+	rec := func(fqdn string, at time.Time, id int64) change {
+		return change{ChangeCursor: ChangeCursor{ChangedAt: at, ChangeID: id}, record: &api.PublicLOCRecord{FQDN: fqdn}}
+	}
+	del := func(fqdn string, at time.Time, id int64) change {
+		return change{ChangeCursor: ChangeCursor{ChangedAt: at, ChangeID: id}, removed: &api.RemovedRecord{FQDN: fqdn}}
+	}
+	after := ChangeCursor{ChangedAt: t0}
+	records := []change{rec("a", t0.Add(time.Second), 5), rec("c", t0.Add(2*time.Second), 3)}
+	removed := []change{del("b", t0.Add(time.Second), 7), del("d", t0.Add(3*time.Second), 1)}
+
+	t.Run("page cut by limit", func(t *testing.T) {
+		got := mergeChanges(after, records, removed, 2)
+		if len(got.Records) != 1 || got.Records[0].FQDN != "a" || len(got.Removed) != 1 || got.Removed[0].FQDN != "b" {
+			t.Errorf("page = %+v / %+v, want a and b", got.Records, got.Removed)
+		}
+		if !got.More || got.Next != removed[0].ChangeCursor {
+			t.Errorf("next = %+v, more = %v; want b's position and more", got.Next, got.More)
+		}
+	})
+
+	t.Run("everything fits", func(t *testing.T) {
+		got := mergeChanges(after, records, removed, 10)
+		if len(got.Records) != 2 || len(got.Removed) != 2 || got.More || got.Next != removed[1].ChangeCursor {
+			t.Errorf("merge = %+v, want all four changes ending at d", got)
+		}
+	})
+
+	t.Run("nothing new keeps the position", func(t *testing.T) {
+		got := mergeChanges(after, nil, nil, 10)
+		if got.Next != after || got.More {
+			t.Errorf("next = %+v, more = %v; want %+v, false", got.Next, got.More, after)
+		}
+	})
+}
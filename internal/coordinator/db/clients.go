@@ -5,9 +5,14 @@ import (
 	"crypto/rand"
 	"crypto/sha256"
 	"encoding/hex"
+	"errors"
 	"time"
 
+	"github.com/google/uuid"
 	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+
+	"github.com/locplace/scanner/pkg/api"
 )
 
 // ScannerClient represents a registered scanner client.
@@ -17,8 +22,15 @@ type ScannerClient struct {
 	TokenHash     string
 	CreatedAt     time.Time
 	LastHeartbeat *time.Time
+	Version       *string  // Reported in heartbeats; nil until the first one
+	Capabilities  []string // Reported in heartbeats
+	CertIdentity  *string  // TLS client certificate identity for mTLS auth, if set
 }
 
+// ErrCertIdentityTaken is returned by CreateClient when another client
+// already uses the certificate identity.
+var ErrCertIdentityTaken = errors.New("certificate identity is already registered")
+
 // generateToken creates a secure random token.
 func generateToken() (string, error) {
 	bytes := make([]byte, 32)
@@ -34,8 +46,23 @@ func hashToken(token string) string {
 	return hex.EncodeToString(h[:])
 }
 
+// newClientID returns a client ID, or nil to let Postgres generate a random one.
+func newClientID(timeOrdered bool) (*string, error) {
+	if !timeOrdered {
+		return nil, nil
+	}
+	id, err := uuid.NewV7()
+	if err != nil {
+		return nil, err
+	}
+	s := id.String()
+	return &s, nil
+}
+
 // CreateClient creates a new scanner client and returns the plaintext token.
-func (db *DB) CreateClient(ctx context.Context, name string) (id, token string, err error) {
+// A non-empty certIdentity lets the client authenticate with a TLS client
+// certificate instead (see GetClientByCertIdentity).
+func (db *DB) CreateClient(ctx context.Context, name, certIdentity string) (id, token string, err error) {
 	token, err = generateToken()
 	if err != nil {
 		return "", "", err
@@ -43,11 +70,20 @@ func (db *DB) CreateClient(ctx context.Context, name string) (id, token string,
 
 	tokenHash := hashToken(token)
 
+	clientID, err := newClientID(db.timeOrderedIDs)
+	if err != nil {
+		return "", "", err
+	}
+
 	err = db.Pool.QueryRow(ctx, `
-		INSERT INTO scanner_clients (name, token_hash)
-		VALUES ($1, $2)
+		INSERT INTO scanner_clients (id, name, token_hash, cert_identity)
+		VALUES (COALESCE($1::uuid, gen_random_uuid()), $2, $3, NULLIF($4, ''))
 		RETURNING id
-	`, name, tokenHash).Scan(&id)
+	`, clientID, name, tokenHash, certIdentity).Scan(&id)
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && pgErr.Code == "23505" && pgErr.ConstraintName == "scanner_clients_cert_identity_key" {
+		return "", "", ErrCertIdentityTaken
+	}
 	if err != nil {
 		return "", "", err
 	}
@@ -61,9 +97,33 @@ func (db *DB) GetClientByToken(ctx context.Context, token string) (*ScannerClien
 
 	var client ScannerClient
 	err := db.Pool.QueryRow(ctx, `
-		SELECT id, name, token_hash, created_at, last_heartbeat
+		SELECT id, name, token_hash, created_at, last_heartbeat, cert_identity
 		FROM scanner_clients WHERE token_hash = $1
-	`, tokenHash).Scan(&client.ID, &client.Name, &client.TokenHash, &client.CreatedAt, &client.LastHeartbeat)
+	`, tokenHash).Scan(&client.ID, &client.Name, &client.TokenHash, &client.CreatedAt, &client.LastHeartbeat, &client.CertIdentity)
+
+	if err == pgx.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &client, nil
+}
+
+// GetClientByCertIdentity retrieves the client registered for one of the
+// identities of a client certificate, tried in order, or nil if none is.
+func (db *DB) GetClientByCertIdentity(ctx context.Context, identities []string) (*ScannerClient, error) {
+	if len(identities) == 0 {
+		return nil, nil
+	}
+
+	var client ScannerClient
+	err := db.Pool.QueryRow(ctx, `
+		SELECT id, name, token_hash, created_at, last_heartbeat, cert_identity
+		FROM scanner_clients WHERE cert_identity = ANY($1)
+		ORDER BY array_position($1, cert_identity)
+		LIMIT 1
+	`, identities).Scan(&client.ID, &client.Name, &client.TokenHash, &client.CreatedAt, &client.LastHeartbeat, &client.CertIdentity)
 
 	if err == pgx.ErrNoRows {
 		return nil, nil
@@ -78,9 +138,9 @@ func (db *DB) GetClientByToken(ctx context.Context, token string) (*ScannerClien
 func (db *DB) GetClientByID(ctx context.Context, id string) (*ScannerClient, error) {
 	var client ScannerClient
 	err := db.Pool.QueryRow(ctx, `
-		SELECT id, name, token_hash, created_at, last_heartbeat
+		SELECT id, name, token_hash, created_at, last_heartbeat, cert_identity
 		FROM scanner_clients WHERE id = $1
-	`, id).Scan(&client.ID, &client.Name, &client.TokenHash, &client.CreatedAt, &client.LastHeartbeat)
+	`, id).Scan(&client.ID, &client.Name, &client.TokenHash, &client.CreatedAt, &client.LastHeartbeat, &client.CertIdentity)
 
 	if err == pgx.ErrNoRows {
 		return nil, nil
@@ -102,6 +162,7 @@ func (db *DB) ListClients(ctx context.Context) ([]ClientWithStats, error) {
 	rows, err := db.Pool.Query(ctx, `
 		SELECT
 			c.id, c.name, c.token_hash, c.created_at, c.last_heartbeat,
+			c.version, c.capabilities, c.cert_identity,
 			COUNT(b.id) as active_batches
 		FROM scanner_clients c
 		LEFT JOIN scan_batches b ON b.scanner_id = c.id AND b.status = 'in_flight'
@@ -116,7 +177,8 @@ func (db *DB) ListClients(ctx context.Context) ([]ClientWithStats, error) {
 	var clients []ClientWithStats
 	for rows.Next() {
 		var c ClientWithStats
-		if err := rows.Scan(&c.ID, &c.Name, &c.TokenHash, &c.CreatedAt, &c.LastHeartbeat, &c.ActiveBatches); err != nil {
+		if err := rows.Scan(&c.ID, &c.Name, &c.TokenHash, &c.CreatedAt, &c.LastHeartbeat,
+			&c.Version, &c.Capabilities, &c.CertIdentity, &c.ActiveBatches); err != nil {
 			return nil, err
 		}
 		clients = append(clients, c)
@@ -144,6 +206,18 @@ func (db *DB) UpdateHeartbeat(ctx context.Context, clientID, sessionID string) e
 	return err
 }
 
+// UpdateClientVersion stores the version and capabilities reported by the
+// client's scanner. An empty version (older scanners) is stored as NULL.
+func (db *DB) UpdateClientVersion(ctx context.Context, clientID, version string, capabilities []string) error {
+	if capabilities == nil {
+		capabilities = []string{} // Column is NOT NULL
+	}
+	_, err := db.Pool.Exec(ctx, `
+		UPDATE scanner_clients SET version = NULLIF($2, ''), capabilities = $3 WHERE id = $1
+	`, clientID, version, capabilities)
+	return err
+}
+
 // UpdateSessionID updates the client's session_id.
 func (db *DB) UpdateSessionID(ctx context.Context, clientID, sessionID string) error {
 	_, err := db.Pool.Exec(ctx, `
@@ -152,6 +226,30 @@ func (db *DB) UpdateSessionID(ctx context.Context, clientID, sessionID string) e
 	return err
 }
 
+// PruneClients deletes clients whose last heartbeat (or creation time, if
+// they never sent one) is older than olderThan, returning the deleted IDs.
+// With dryRun, the matching IDs are returned without deleting anything.
+func (db *DB) PruneClients(ctx context.Context, olderThan time.Duration, dryRun bool) ([]string, error) {
+	const where = `WHERE COALESCE(last_heartbeat, created_at) < NOW() - $1::interval`
+	query := `DELETE FROM scanner_clients ` + where + ` RETURNING id`
+	if dryRun {
+		query = `SELECT id FROM scanner_clients ` + where
+	}
+
+	rows, err := db.Pool.Query(ctx, query, olderThan.String())
+	if err != nil {
+		return nil, err
+	}
+	ids, err := pgx.CollectRows(rows, pgx.RowTo[string])
+	if err != nil {
+		return nil, err
+	}
+	if ids == nil {
+		ids = []string{}
+	}
+	return ids, nil
+}
+
 // CountActiveClients returns the number of clients with recent heartbeats.
 func (db *DB) CountActiveClients(ctx context.Context, timeout time.Duration) (int, error) {
 	var count int
@@ -162,6 +260,18 @@ func (db *DB) CountActiveClients(ctx context.Context, timeout time.Duration) (in
 	return count, err
 }
 
+// GetStatsSummary returns the LOC record count and the number of scanner
+// sessions with recent heartbeats in a single query.
+func (db *DB) GetStatsSummary(ctx context.Context, timeout time.Duration) (api.StatsSummaryResponse, error) {
+	var s api.StatsSummaryResponse
+	err := db.Pool.QueryRow(ctx, `
+		SELECT
+			(SELECT COUNT(*) FROM loc_records),
+			(SELECT COUNT(*) FROM scanner_sessions WHERE last_heartbeat > NOW() - $1::interval)
+	`, timeout.String()).Scan(&s.TotalLOCRecords, &s.ActiveScanners)
+	return s, err
+}
+
 // ScannerSession represents an individual scanner instance.
 // Multiple sessions can share the same client (token).
 type ScannerSession struct {
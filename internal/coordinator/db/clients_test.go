@@ -2,6 +2,8 @@ package db
 
 import (
 	"testing"
+
+	"github.com/google/uuid"
 )
 
 func TestHashToken(t *testing.T) {
@@ -157,3 +159,29 @@ func TestClientWithStats_Embedding(t *testing.T) {
 		t.Errorf("ActiveBatches = %d, want %d", client.ActiveBatches, 5)
 	}
 }
+
+func TestNewClientID(t *testing.T) {
+	id, err := newClientID(false)
+	if err != nil || id != nil {
+		t.Errorf("newClientID(false) = %v, %v; want nil so Postgres generates it", id, err)
+	}
+
+	first, err := newClientID(true)
+	if err != nil {
+		t.Fatalf("newClientID(true) error: %v", err)
+	}
+	second, err := newClientID(true)
+	if err != nil {
+		t.Fatalf("newClientID(true) error: %v", err)
+	}
+	parsed, err := uuid.Parse(*first)
+	if err != nil {
+		t.Fatalf("invalid UUID %q: %v", *first, err)
+	}
+	if parsed.Version() != 7 {
+		t.Errorf("version = %d, want 7", parsed.Version())
+	}
+	if *second <= *first {
+		t.Errorf("IDs not time-ordered: %s then %s", *first, *second)
+	}
+}
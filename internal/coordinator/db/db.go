@@ -5,18 +5,32 @@ import (
 	"context"
 	"fmt"
 
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
 	"github.com/jackc/pgx/v5/pgxpool"
 )
 
+// querier is satisfied by both *pgxpool.Pool and pgx.Tx, so small helpers
+// can run standalone or as part of a transaction.
+type querier interface {
+	Exec(ctx context.Context, sql string, args ...any) (pgconn.CommandTag, error)
+	QueryRow(ctx context.Context, sql string, args ...any) pgx.Row
+}
+
 // DB wraps a PostgreSQL connection pool.
 type DB struct {
 	Pool *pgxpool.Pool
+
+	timeOrderedIDs bool
 }
 
 // Config holds database configuration options.
 type Config struct {
 	URL      string
 	MaxConns int32 // Maximum number of connections in the pool (0 = use default)
+	// TimeOrderedIDs makes new client IDs UUIDv7 instead of random UUIDv4,
+	// so ORDER BY id follows creation time.
+	TimeOrderedIDs bool
 }
 
 // New creates a new database connection pool.
@@ -40,7 +54,7 @@ func New(ctx context.Context, cfg Config) (*DB, error) {
 		return nil, fmt.Errorf("failed to ping database: %w", err)
 	}
 
-	return &DB{Pool: pool}, nil
+	return &DB{Pool: pool, timeOrderedIDs: cfg.TimeOrderedIDs}, nil
 }
 
 // Close closes the database connection pool.
@@ -0,0 +1,19 @@
+package db
+
+import (
+	"context"
+	"time"
+)
+
+// PruneLOCRecordDeletions removes tombstones older than retention and
+// returns how many it removed. Clients of the changes feed that fall further
+// behind than that have to download the records again.
+func (db *DB) PruneLOCRecordDeletions(ctx context.Context, retention time.Duration) (int, error) {
+	tag, err := db.Pool.Exec(ctx, `
+		DELETE FROM loc_record_deletions WHERE deleted_at < NOW() - $1::interval
+	`, retention.String())
+	if err != nil {
+		return 0, err
+	}
+	return int(tag.RowsAffected()), nil
+}
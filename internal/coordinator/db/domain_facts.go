@@ -0,0 +1,34 @@
+package db
+
+import (
+	"context"
+
+	"github.com/locplace/scanner/pkg/api"
+)
+
+// recordDomainFacts upserts the latest facts reported for each FQDN.
+func recordDomainFacts(ctx context.Context, q querier, facts []api.DomainFacts) error {
+	if len(facts) == 0 {
+		return nil
+	}
+
+	fqdns := make([]string, len(facts))
+	resolves := make([]bool, len(facts))
+	aaaa := make([]bool, len(facts))
+	mx := make([]bool, len(facts))
+	for i, f := range facts {
+		fqdns[i], resolves[i], aaaa[i], mx[i] = f.FQDN, f.Resolves, f.HasAAAA, f.HasMX
+	}
+
+	_, err := q.Exec(ctx, `
+		INSERT INTO domain_facts (fqdn, resolves, has_aaaa, has_mx)
+		SELECT DISTINCT ON (fqdn) fqdn, resolves, has_aaaa, has_mx
+		FROM unnest($1::text[], $2::bool[], $3::bool[], $4::bool[]) AS f(fqdn, resolves, has_aaaa, has_mx)
+		ON CONFLICT (fqdn) DO UPDATE SET
+			resolves = EXCLUDED.resolves,
+			has_aaaa = EXCLUDED.has_aaaa,
+			has_mx = EXCLUDED.has_mx,
+			checked_at = NOW()
+	`, fqdns, resolves, aaaa, mx)
+	return err
+}
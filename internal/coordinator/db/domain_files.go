@@ -2,7 +2,10 @@ package db
 
 import (
 	"context"
+	"errors"
 	"time"
+
+	"github.com/jackc/pgx/v5"
 )
 
 // DomainFile represents a .xz file from the domains project.
@@ -194,3 +197,43 @@ func (db *DB) ResetAllFiles(ctx context.Context) error {
 	`)
 	return err
 }
+
+// ErrFileProcessing is returned when a file cannot be reset because the feeder
+// is still creating batches from it.
+var ErrFileProcessing = errors.New("file is being processed")
+
+// ResetFile resets a single completed file to pending so it is fed again.
+// Returns the number of domains (lines) that will be re-scanned, or found=false
+// if the file does not exist. Files still being processed return ErrFileProcessing.
+func (db *DB) ResetFile(ctx context.Context, fileID int) (domains int64, found bool, err error) {
+	err = db.Pool.QueryRow(ctx, `
+		UPDATE domain_files f
+		SET status = 'pending',
+		    processed_lines = 0,
+		    batches_created = 0,
+		    batches_completed = 0,
+		    feeding_complete = false,
+		    started_at = NULL,
+		    completed_at = NULL
+		FROM (SELECT id, processed_lines FROM domain_files WHERE id = $1 FOR UPDATE) old
+		WHERE f.id = old.id AND f.status <> 'processing'
+		RETURNING old.processed_lines
+	`, fileID).Scan(&domains)
+	if err == nil {
+		return domains, true, nil
+	}
+	if !errors.Is(err, pgx.ErrNoRows) {
+		return 0, false, err
+	}
+
+	// Nothing updated: either missing or still processing
+	var status string
+	err = db.Pool.QueryRow(ctx, `SELECT status FROM domain_files WHERE id = $1`, fileID).Scan(&status)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return 0, false, nil
+	}
+	if err != nil {
+		return 0, false, err
+	}
+	return 0, true, ErrFileProcessing
+}
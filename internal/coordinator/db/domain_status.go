@@ -0,0 +1,75 @@
+package db
+
+import (
+	"context"
+	"errors"
+
+	"github.com/jackc/pgx/v5"
+
+	"github.com/locplace/scanner/pkg/api"
+)
+
+// GetDomainStatus reports what is known about domain: its stored records,
+// whether it waits in a batch, its last failed lookup and its domain facts.
+// domain may be a root domain or a FQDN and must already be normalized
+// (lowercase, no trailing dot).
+func (db *DB) GetDomainStatus(ctx context.Context, domain string) (api.DomainStatusResponse, error) {
+	s := api.DomainStatusResponse{Domain: domain}
+
+	facts := api.DomainFacts{FQDN: domain}
+	err := db.Pool.QueryRow(ctx, `
+		SELECT resolves, has_aaaa, has_mx, checked_at
+		FROM domain_facts
+		WHERE fqdn = $1
+	`, domain).Scan(&facts.Resolves, &facts.HasAAAA, &facts.HasMX, &facts.CheckedAt)
+	switch {
+	case err == nil:
+		s.Facts = &facts
+	case !errors.Is(err, pgx.ErrNoRows):
+		return s, err
+	}
+
+	err = db.Pool.QueryRow(ctx, `
+		SELECT COUNT(*), MIN(first_seen_at), MAX(last_seen_at)
+		FROM loc_records
+		WHERE root_domain = $1 OR fqdn = $1
+	`, domain).Scan(&s.RecordCount, &s.FirstSeenAt, &s.LastSeenAt)
+	if err != nil {
+		return s, err
+	}
+	if s.RecordCount > 0 {
+		s.Status = api.DomainStatusHasLOC
+		return s, nil
+	}
+
+	// Batch domains are newline-separated
+	var queued bool
+	err = db.Pool.QueryRow(ctx, `
+		SELECT EXISTS (
+			SELECT 1 FROM scan_batches
+			WHERE position(E'\n' || $1 || E'\n' IN E'\n' || domains || E'\n') > 0
+		)
+	`, domain).Scan(&queued)
+	if err != nil {
+		return s, err
+	}
+	if queued {
+		s.Status = api.DomainStatusQueued
+		return s, nil
+	}
+
+	err = db.Pool.QueryRow(ctx, `
+		SELECT error_class, last_failed_at
+		FROM scan_errors
+		WHERE fqdn = $1
+	`, domain).Scan(&s.ErrorClass, &s.LastFailedAt)
+	switch {
+	case errors.Is(err, pgx.ErrNoRows):
+		s.Status = api.DomainStatusNoLOC
+		return s, nil
+	case err != nil:
+		return s, err
+	}
+	s.Status = api.DomainStatusError
+	return s, nil
+}
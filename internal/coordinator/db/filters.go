@@ -0,0 +1,266 @@
+package db
+
+import (
+	"fmt"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/locplace/scanner/pkg/api"
+)
+
+// BBox is a geographic bounding box in decimal degrees.
+// If MinLon > MaxLon the box crosses the antimeridian.
+type BBox struct {
+	MinLon float64
+	MinLat float64
+	MaxLon float64
+	MaxLat float64
+}
+
+// geohashPrefix returns the longest geohash prefix whose cell contains the
+// whole box, or "" if there is none (large boxes, or boxes crossing the
+// antimeridian). Geohash cells are rectangles, so a cell containing the
+// south-west and north-east corners contains the box.
+func (b BBox) geohashPrefix() string {
+	if b.MinLon > b.MaxLon {
+		return ""
+	}
+	sw := api.Geohash(b.MinLat, b.MinLon, api.GeohashPrecision)
+	ne := api.Geohash(b.MaxLat, b.MaxLon, api.GeohashPrecision)
+	n := 0
+	for n < len(sw) && sw[n] == ne[n] {
+		n++
+	}
+	return sw[:n]
+}
+
+// bboxAround returns a box enclosing every point within km kilometers of
+// (lat, lon). Near the poles the box spans all longitudes.
+func bboxAround(lat, lon, km float64) BBox {
+	dLat := km / (api.EarthRadiusKm * math.Pi / 180)
+	b := BBox{
+		MinLat: math.Max(-90, lat-dLat),
+		MaxLat: math.Min(90, lat+dLat),
+		MinLon: -180,
+		MaxLon: 180,
+	}
+	// The widest longitude span is at the box's edge nearest a pole
+	cos := math.Cos(math.Max(math.Abs(b.MinLat), math.Abs(b.MaxLat)) * math.Pi / 180)
+	if b.MinLat == -90 || b.MaxLat == 90 || cos < 1e-9 {
+		return b
+	}
+	dLon := dLat / cos
+	if dLon >= 180 {
+		return b
+	}
+	b.MinLon, b.MaxLon = lon-dLon, lon+dLon
+	if b.MinLon < -180 {
+		b.MinLon += 360 // Crosses the antimeridian; MinLon > MaxLon
+	}
+	if b.MaxLon > 180 {
+		b.MaxLon -= 360
+	}
+	return b
+}
+
+// RecordFilter narrows the LOC records returned by list and export queries.
+// Zero values (empty strings, nil pointers) mean "no filter".
+type RecordFilter struct {
+	Domain        string // Exact root domain match
+	FQDNPattern   string // Glob over the FQDN: * matches any run, ? one character
+	BBox          *BBox
+	MinAltitudeM  *float64
+	MaxAltitudeM  *float64
+	MaxHorizPrecM *float64
+	MaxVertPrecM  *float64
+	MinSizeM      *float64 // Bounds on size_m, the diameter of the entity
+	MaxSizeM      *float64
+	UpdatedSince  *time.Time // Only records seen after this time, oldest first
+	LatHemisphere string     // "N" (latitude >= 0) or "S" (latitude < 0)
+	LonHemisphere string     // "E" (longitude >= 0) or "W" (longitude < 0)
+	// ExcludeZeroAltitude drops records without a meaningful altitude
+	// (see api.LOCRecord.HasMeaningfulAltitude).
+	ExcludeZeroAltitude bool
+	// ExcludeImplausible drops records with plausibility warnings
+	// (see api.LOCRecord.PlausibilityWarnings).
+	ExcludeImplausible bool
+	// FirstSeenSince and FirstSeenUntil bound first_seen_at to
+	// [FirstSeenSince, FirstSeenUntil).
+	FirstSeenSince *time.Time
+	FirstSeenUntil *time.Time
+	// LastSeenSince drops records not seen since this time. Unlike
+	// UpdatedSince it doesn't change the order.
+	LastSeenSince *time.Time
+	// SampleFraction keeps roughly this share (0 < f <= 1) of the matching
+	// records; 0 disables sampling. Membership is decided by hashing each
+	// record with SampleSeed, so the same seed always selects the same
+	// records (see samplePredicate).
+	SampleFraction float64
+	SampleSeed     int64
+}
+
+// sampleBuckets is the resolution of the sampling hash: a fraction is
+// rounded to the nearest multiple of 1/sampleBuckets.
+const sampleBuckets = 1_000_000
+
+// sampleThreshold returns the number of hash buckets a fraction keeps.
+func sampleThreshold(fraction float64) int64 {
+	return int64(math.Round(fraction * sampleBuckets))
+}
+
+// queryBuilder accumulates WHERE conditions and their positional arguments.
+type queryBuilder struct {
+	conds []string
+	args  []any
+}
+
+// arg appends a query argument and returns its placeholder.
+func (q *queryBuilder) arg(v any) string {
+	q.args = append(q.args, v)
+	return fmt.Sprintf("$%d", len(q.args))
+}
+
+func (q *queryBuilder) where() string {
+	if len(q.conds) == 0 {
+		return ""
+	}
+	return "WHERE " + strings.Join(q.conds, " AND ")
+}
+
+// apply adds the filter's conditions to the builder.
+func (f RecordFilter) apply(q *queryBuilder) {
+	if f.Domain != "" {
+		q.conds = append(q.conds, "root_domain = "+q.arg(f.Domain))
+	}
+	if f.FQDNPattern != "" {
+		q.conds = append(q.conds, "fqdn LIKE "+q.arg(globToLike(f.FQDNPattern)))
+	}
+	if f.BBox != nil {
+		if prefix := f.BBox.geohashPrefix(); prefix != "" {
+			// Index-friendly prefilter; the exact bounds below still apply
+			q.conds = append(q.conds, "geohash LIKE "+q.arg(prefix+"%"))
+		}
+		q.conds = append(q.conds, fmt.Sprintf("latitude BETWEEN %s AND %s", q.arg(f.BBox.MinLat), q.arg(f.BBox.MaxLat)))
+		if f.BBox.MinLon <= f.BBox.MaxLon {
+			q.conds = append(q.conds, fmt.Sprintf("longitude BETWEEN %s AND %s", q.arg(f.BBox.MinLon), q.arg(f.BBox.MaxLon)))
+		} else {
+			// Box crosses the antimeridian: match both sides
+			q.conds = append(q.conds, fmt.Sprintf("(longitude >= %s OR longitude <= %s)", q.arg(f.BBox.MinLon), q.arg(f.BBox.MaxLon)))
+		}
+	}
+	if f.MinAltitudeM != nil {
+		q.conds = append(q.conds, "altitude_m >= "+q.arg(*f.MinAltitudeM))
+	}
+	if f.MaxAltitudeM != nil {
+		q.conds = append(q.conds, "altitude_m <= "+q.arg(*f.MaxAltitudeM))
+	}
+	if f.MaxHorizPrecM != nil {
+		q.conds = append(q.conds, "horiz_prec_m <= "+q.arg(*f.MaxHorizPrecM))
+	}
+	if f.MaxVertPrecM != nil {
+		q.conds = append(q.conds, "vert_prec_m <= "+q.arg(*f.MaxVertPrecM))
+	}
+	if f.MinSizeM != nil {
+		q.conds = append(q.conds, "size_m >= "+q.arg(*f.MinSizeM))
+	}
+	if f.MaxSizeM != nil {
+		q.conds = append(q.conds, "size_m <= "+q.arg(*f.MaxSizeM))
+	}
+	switch f.LatHemisphere {
+	case "N":
+		q.conds = append(q.conds, "latitude >= 0")
+	case "S":
+		q.conds = append(q.conds, "latitude < 0")
+	}
+	switch f.LonHemisphere {
+	case "E":
+		q.conds = append(q.conds, "longitude >= 0")
+	case "W":
+		q.conds = append(q.conds, "longitude < 0")
+	}
+	if f.ExcludeZeroAltitude {
+		q.conds = append(q.conds, "(altitude_m <> 0 OR vert_prec_m <> "+q.arg(api.DefaultVertPrecM)+")")
+	}
+	if f.ExcludeImplausible {
+		// Mirrors api.LOCRecord.PlausibilityWarnings
+		q.conds = append(q.conds,
+			fmt.Sprintf("altitude_m BETWEEN %s AND %s", q.arg(api.MinPlausibleAltitudeM), q.arg(api.MaxPlausibleAltitudeM)),
+			"size_m <= "+q.arg(api.MaxPlausibleSizeM),
+			fmt.Sprintf("horiz_prec_m > 0 AND horiz_prec_m <= %s", q.arg(api.MaxPlausibleExtentM)),
+			fmt.Sprintf("vert_prec_m > 0 AND vert_prec_m <= %s", q.arg(api.MaxPlausibleExtentM)),
+		)
+	}
+	if f.FirstSeenSince != nil {
+		q.conds = append(q.conds, "first_seen_at >= "+q.arg(*f.FirstSeenSince))
+	}
+	if f.FirstSeenUntil != nil {
+		q.conds = append(q.conds, "first_seen_at < "+q.arg(*f.FirstSeenUntil))
+	}
+	if f.LastSeenSince != nil {
+		q.conds = append(q.conds, "last_seen_at >= "+q.arg(*f.LastSeenSince))
+	}
+	if f.UpdatedSince != nil {
+		// last_seen_at is bumped on every observation and is never older than first_seen_at
+		q.conds = append(q.conds, "last_seen_at > "+q.arg(*f.UpdatedSince))
+	}
+	if f.SampleFraction > 0 && f.SampleFraction < 1 {
+		q.conds = append(q.conds, samplePredicate(q, f.SampleFraction, f.SampleSeed))
+	}
+}
+
+// samplePredicate keeps a record when its seeded hash falls in the first
+// fraction of sampleBuckets. Unlike TABLESAMPLE, which samples pages and is
+// only repeatable while the table is unchanged, the hash depends on the
+// record alone: a record stays in (or out of) the sample as other rows come
+// and go, and a smaller fraction with the same seed selects a subset of a
+// larger one.
+func samplePredicate(q *queryBuilder, fraction float64, seed int64) string {
+	// Masking the sign bit keeps the modulus non-negative
+	return fmt.Sprintf("(hashtextextended(fqdn || ' ' || raw_record, %s) & 9223372036854775807) %% %d < %s",
+		q.arg(seed), sampleBuckets, q.arg(sampleThreshold(fraction)))
+}
+
+// globToLike translates a glob (* and ?) into a LIKE pattern, escaping the
+// LIKE metacharacters % and _ and the default escape character \.
+func globToLike(glob string) string {
+	var b strings.Builder
+	for _, c := range glob {
+		switch c {
+		case '*':
+			b.WriteByte('%')
+		case '?':
+			b.WriteByte('_')
+		case '%', '_', '\\':
+			b.WriteByte('\\')
+			b.WriteRune(c)
+		default:
+			b.WriteRune(c)
+		}
+	}
+	return b.String()
+}
+
+// orderBy returns the ORDER BY expression for the filter. Incremental syncs
+// (UpdatedSince) are ordered by update time so clients can checkpoint on the
+// last last_seen_at they received; otherwise def is used.
+func (f RecordFilter) orderBy(def string) string {
+	if f.UpdatedSince != nil {
+		return "last_seen_at ASC, fqdn, raw_record"
+	}
+	return def
+}
+
+// listAscending reports whether the records list is shown oldest first.
+// It matches orderBy for the list: incremental syncs ascend, otherwise the
+// most recently seen records come first.
+func (f RecordFilter) listAscending() bool {
+	return f.UpdatedSince != nil
+}
+
+// whereClause returns the SQL WHERE clause (or "") and its arguments for the filter.
+func (f RecordFilter) whereClause() (string, []any) {
+	var q queryBuilder
+	f.apply(&q)
+	return q.where(), q.args
+}
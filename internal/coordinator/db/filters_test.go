@@ -0,0 +1,214 @@
+package db
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/locplace/scanner/pkg/api"
+)
+
+func TestRecordFilter_WhereClause(t *testing.T) {
+	minAlt, maxAlt, maxHoriz, maxVert := -10.0, 500.0, 100.0, 10.0
+	minSize, maxSize := 100.0, 5000.0
+	since := time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name      string
+		filter    RecordFilter
+		wantWhere string
+		wantArgs  []any
+	}{
+		{
+			name:      "empty filter",
+			filter:    RecordFilter{},
+			wantWhere: "",
+			wantArgs:  nil,
+		},
+		{
+			name:      "domain only",
+			filter:    RecordFilter{Domain: "nikhef.nl"},
+			wantWhere: "WHERE root_domain = $1",
+			wantArgs:  []any{"nikhef.nl"},
+		},
+		{
+			name:      "fqdn pattern",
+			filter:    RecordFilter{FQDNPattern: "*.edu"},
+			wantWhere: "WHERE fqdn LIKE $1",
+			wantArgs:  []any{"%.edu"},
+		},
+		{
+			name:      "exclude implausible",
+			filter:    RecordFilter{ExcludeImplausible: true},
+			wantWhere: "WHERE altitude_m BETWEEN $1 AND $2 AND size_m <= $3 AND horiz_prec_m > 0 AND horiz_prec_m <= $4 AND vert_prec_m > 0 AND vert_prec_m <= $5",
+			wantArgs:  []any{api.MinPlausibleAltitudeM, api.MaxPlausibleAltitudeM, api.MaxPlausibleSizeM, api.MaxPlausibleExtentM, api.MaxPlausibleExtentM},
+		},
+		{
+			name:      "southern and western hemispheres",
+			filter:    RecordFilter{LatHemisphere: "S", LonHemisphere: "W"},
+			wantWhere: "WHERE latitude < 0 AND longitude < 0",
+		},
+		{
+			name:      "northern hemisphere with domain",
+			filter:    RecordFilter{Domain: "example.com", LatHemisphere: "N"},
+			wantWhere: "WHERE root_domain = $1 AND latitude >= 0",
+			wantArgs:  []any{"example.com"},
+		},
+		{
+			name:      "exclude zero altitude",
+			filter:    RecordFilter{ExcludeZeroAltitude: true},
+			wantWhere: "WHERE (altitude_m <> 0 OR vert_prec_m <> $1)",
+			wantArgs:  []any{10.0},
+		},
+		{
+			name:      "last seen since",
+			filter:    RecordFilter{LastSeenSince: &since},
+			wantWhere: "WHERE last_seen_at >= $1",
+			wantArgs:  []any{since},
+		},
+		{
+			name:      "updated since",
+			filter:    RecordFilter{UpdatedSince: &since},
+			wantWhere: "WHERE last_seen_at > $1",
+			wantArgs:  []any{since},
+		},
+		{
+			name:      "bbox",
+			filter:    RecordFilter{BBox: &BBox{MinLon: 4, MinLat: 52, MaxLon: 5, MaxLat: 53}},
+			wantWhere: "WHERE geohash LIKE $1 AND latitude BETWEEN $2 AND $3 AND longitude BETWEEN $4 AND $5",
+			wantArgs:  []any{"u1%", 52.0, 53.0, 4.0, 5.0},
+		},
+		{
+			name:      "bbox crossing antimeridian",
+			filter:    RecordFilter{BBox: &BBox{MinLon: 170, MinLat: -50, MaxLon: -170, MaxLat: -30}},
+			wantWhere: "WHERE latitude BETWEEN $1 AND $2 AND (longitude >= $3 OR longitude <= $4)",
+			wantArgs:  []any{-50.0, -30.0, 170.0, -170.0},
+		},
+		{
+			name:      "size range",
+			filter:    RecordFilter{MinSizeM: &minSize, MaxSizeM: &maxSize},
+			wantWhere: "WHERE size_m >= $1 AND size_m <= $2",
+			wantArgs:  []any{100.0, 5000.0},
+		},
+		{
+			name:      "sample fraction",
+			filter:    RecordFilter{Domain: "nikhef.nl", SampleFraction: 0.1, SampleSeed: 42},
+			wantWhere: "WHERE root_domain = $1 AND (hashtextextended(fqdn || ' ' || raw_record, $2) & 9223372036854775807) % 1000000 < $3",
+			wantArgs:  []any{"nikhef.nl", int64(42), int64(100000)},
+		},
+		{
+			name:      "full sample adds no condition",
+			filter:    RecordFilter{SampleFraction: 1},
+			wantWhere: "",
+		},
+		{
+			name: "all fields",
+			filter: RecordFilter{
+				Domain:        "caida.org",
+				MinAltitudeM:  &minAlt,
+				MaxAltitudeM:  &maxAlt,
+				MaxHorizPrecM: &maxHoriz,
+				MaxVertPrecM:  &maxVert,
+			},
+			wantWhere: "WHERE root_domain = $1 AND altitude_m >= $2 AND altitude_m <= $3 AND horiz_prec_m <= $4 AND vert_prec_m <= $5",
+			wantArgs:  []any{"caida.org", -10.0, 500.0, 100.0, 10.0},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			where, args := tt.filter.whereClause()
+			if where != tt.wantWhere {
+				t.Errorf("where = %q, want %q", where, tt.wantWhere)
+			}
+			if !reflect.DeepEqual(args, tt.wantArgs) {
+				t.Errorf("args = %v, want %v", args, tt.wantArgs)
+			}
+		})
+	}
+}
+
+func TestGlobToLike(t *testing.T) {
+	tests := []struct {
+		glob string
+		want string
+	}{
+		{"*.edu", "%.edu"},
+		{"www.?.example.com", "www._.example.com"},
+		{"host_1.example.com", `host\_1.example.com`},
+		{"100%.example", `100\%.example`},
+		{"nikhef.nl", "nikhef.nl"},
+	}
+
+	for _, tt := range tests {
+		if got := globToLike(tt.glob); got != tt.want {
+			t.Errorf("globToLike(%q) = %q, want %q", tt.glob, got, tt.want)
+		}
+	}
+}
+
+func TestRecordFilter_OrderBy(t *testing.T) {
+	if got := (RecordFilter{}).orderBy("fqdn"); got != "fqdn" {
+		t.Errorf("orderBy() = %q, want default", got)
+	}
+	since := time.Now()
+	if got := (RecordFilter{UpdatedSince: &since}).orderBy("fqdn"); got != "last_seen_at ASC, fqdn, raw_record" {
+		t.Errorf("orderBy() = %q, want update order", got)
+	}
+}
+
+func TestBBox_GeohashPrefix(t *testing.T) {
+	tests := []struct {
+		name string
+		bbox BBox
+		want string
+	}{
+		{"netherlands", BBox{MinLon: 4, MinLat: 52, MaxLon: 5, MaxLat: 53}, "u1"},
+		{"amsterdam block", BBox{MinLon: 4.89, MinLat: 52.37, MaxLon: 4.90, MaxLat: 52.38}, "u173z"},
+		{"straddles the equator", BBox{MinLon: 10, MinLat: -1, MaxLon: 11, MaxLat: 1}, ""},
+		{"crosses the antimeridian", BBox{MinLon: 170, MinLat: -50, MaxLon: -170, MaxLat: -30}, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.bbox.geohashPrefix(); got != tt.want {
+				t.Errorf("geohashPrefix() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBBoxAround(t *testing.T) {
+	tests := []struct {
+		name         string
+		lat, lon, km float64
+		wantCrossing bool
+		wantAllLon   bool
+		// A point within km of (lat, lon) that the box must contain
+		checkLat, checkLon float64
+	}{
+		{name: "amsterdam", lat: 52.37, lon: 4.89, km: 10, checkLat: 52.37, checkLon: 5.03},
+		{name: "antimeridian", lat: -17.7, lon: 179.99, km: 5, wantCrossing: true, checkLat: -17.7, checkLon: -179.98},
+		{name: "pole", lat: 89.99, lon: 10, km: 5, wantAllLon: true, checkLat: 89.99, checkLon: -170},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := bboxAround(tt.lat, tt.lon, tt.km)
+			if crossing := b.MinLon > b.MaxLon; crossing != tt.wantCrossing {
+				t.Errorf("crossing = %v, want %v (%+v)", crossing, tt.wantCrossing, b)
+			}
+			if allLon := b.MinLon == -180 && b.MaxLon == 180; allLon != tt.wantAllLon {
+				t.Errorf("all longitudes = %v, want %v (%+v)", allLon, tt.wantAllLon, b)
+			}
+			if api.DistanceKm(tt.lat, tt.lon, tt.checkLat, tt.checkLon) > tt.km {
+				t.Fatalf("check point is not within %v km", tt.km)
+			}
+			inLon := tt.checkLon >= b.MinLon && tt.checkLon <= b.MaxLon
+			if b.MinLon > b.MaxLon {
+				inLon = tt.checkLon >= b.MinLon || tt.checkLon <= b.MaxLon
+			}
+			if tt.checkLat < b.MinLat || tt.checkLat > b.MaxLat || !inLon {
+				t.Errorf("bboxAround(%v, %v, %v) = %+v excludes (%v, %v)", tt.lat, tt.lon, tt.km, b, tt.checkLat, tt.checkLon)
+			}
+		})
+	}
+}
@@ -62,3 +62,34 @@ func (db *DB) GetMetricsSnapshot(ctx context.Context, heartbeatTimeout time.Dura
 
 	return &m, err
 }
+
+// TLDCount is the number of LOC records under a top-level domain.
+type TLDCount struct {
+	TLD   string
+	Count int
+}
+
+// CountLOCRecordsByTLD returns the number of LOC records per TLD (the last
+// label of the FQDN), largest first.
+func (db *DB) CountLOCRecordsByTLD(ctx context.Context) ([]TLDCount, error) {
+	rows, err := db.Pool.Query(ctx, `
+		SELECT lower(substring(fqdn from '[^.]+$')) AS tld, COUNT(*)
+		FROM loc_records
+		GROUP BY tld
+		ORDER BY COUNT(*) DESC, tld
+	`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var counts []TLDCount
+	for rows.Next() {
+		var c TLDCount
+		if err := rows.Scan(&c.TLD, &c.Count); err != nil {
+			return nil, err
+		}
+		counts = append(counts, c)
+	}
+	return counts, rows.Err()
+}
@@ -0,0 +1,86 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"io/fs"
+
+	"github.com/golang-migrate/migrate/v4"
+	_ "github.com/golang-migrate/migrate/v4/database/postgres" // Registers the postgres:// driver
+	"github.com/golang-migrate/migrate/v4/source"
+	"github.com/golang-migrate/migrate/v4/source/iofs"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+
+	"github.com/locplace/scanner/migrations"
+)
+
+// MigrateUp applies the embedded migrations that databaseURL has not seen
+// yet. It is a no-op on an up-to-date schema.
+func MigrateUp(databaseURL string) error {
+	src, err := iofs.New(migrations.FS, ".")
+	if err != nil {
+		return err
+	}
+
+	m, err := migrate.NewWithSourceInstance("iofs", src, databaseURL)
+	if err != nil {
+		return err
+	}
+	defer m.Close() //nolint:errcheck // Close error not actionable
+
+	if err := m.Up(); err != nil && !errors.Is(err, migrate.ErrNoChange) {
+		return err
+	}
+	return nil
+}
+
+// LatestMigration returns the version of the newest embedded migration,
+// which is the schema version this build expects.
+func LatestMigration() (uint, error) {
+	src, err := iofs.New(migrations.FS, ".")
+	if err != nil {
+		return 0, err
+	}
+	defer src.Close() //nolint:errcheck // Close error not actionable
+	return lastVersion(src)
+}
+
+// lastVersion walks src to its last migration version.
+func lastVersion(src source.Driver) (uint, error) {
+	v, err := src.First()
+	if err != nil {
+		return 0, err
+	}
+	for {
+		next, err := src.Next(v)
+		if errors.Is(err, fs.ErrNotExist) {
+			return v, nil
+		}
+		if err != nil {
+			return 0, err
+		}
+		v = next
+	}
+}
+
+// SchemaVersion returns the applied migration version and whether the last
+// migration failed halfway (dirty). The version is 0 before the first
+// migration.
+func (db *DB) SchemaVersion(ctx context.Context) (version uint, dirty bool, err error) {
+	var v int64
+	err = db.Pool.QueryRow(ctx, `SELECT version, dirty FROM schema_migrations LIMIT 1`).Scan(&v, &dirty)
+	if errors.Is(err, pgx.ErrNoRows) || isUndefinedTable(err) {
+		return 0, false, nil
+	}
+	if err != nil {
+		return 0, false, err
+	}
+	return uint(v), dirty, nil
+}
+
+// isUndefinedTable reports whether err is PostgreSQL's undefined_table.
+func isUndefinedTable(err error) bool {
+	var pgErr *pgconn.PgError
+	return errors.As(err, &pgErr) && pgErr.Code == "42P01"
+}
@@ -0,0 +1,29 @@
+package db
+
+import (
+	"fmt"
+	"io/fs"
+	"testing"
+
+	"github.com/locplace/scanner/migrations"
+)
+
+func TestLatestMigration(t *testing.T) {
+	latest, err := LatestMigration()
+	if err != nil {
+		t.Fatalf("LatestMigration() error: %v", err)
+	}
+
+	// Every embedded up migration is at or below the latest version
+	ups, err := fs.Glob(migrations.FS, "*.up.sql")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if uint(len(ups)) != latest {
+		t.Errorf("LatestMigration() = %d, but %d up migrations are embedded", latest, len(ups))
+	}
+	pattern := fmt.Sprintf("%06d_*.up.sql", latest)
+	if m, _ := fs.Glob(migrations.FS, pattern); len(m) != 1 {
+		t.Errorf("no up migration matches %s", pattern)
+	}
+}
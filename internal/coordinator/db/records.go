@@ -2,6 +2,11 @@ package db
 
 import (
 	"context"
+	"fmt"
+	"math"
+	"slices"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/jackc/pgx/v5"
@@ -25,64 +30,203 @@ type StoredLOCRecord struct {
 	LastSeenAt  time.Time
 }
 
-// UpsertLOCRecord inserts or updates a LOC record.
-// If the FQDN already exists, updates last_seen_at.
-func (db *DB) UpsertLOCRecord(ctx context.Context, rootDomain string, rec api.LOCRecord) error {
-	_, err := db.Pool.Exec(ctx, `
-		INSERT INTO loc_records (root_domain, fqdn, raw_record, latitude, longitude, altitude_m, size_m, horiz_prec_m, vert_prec_m)
-		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
-		ON CONFLICT (fqdn) DO UPDATE SET
-			raw_record = EXCLUDED.raw_record,
-			latitude = EXCLUDED.latitude,
-			longitude = EXCLUDED.longitude,
-			altitude_m = EXCLUDED.altitude_m,
-			size_m = EXCLUDED.size_m,
-			horiz_prec_m = EXCLUDED.horiz_prec_m,
-			vert_prec_m = EXCLUDED.vert_prec_m,
-			last_seen_at = NOW()
-	`, rootDomain, rec.FQDN, rec.RawRecord, rec.Latitude, rec.Longitude, rec.AltitudeM, rec.SizeM, rec.HorizPrecM, rec.VertPrecM)
+// publicRecordColumns is the column list read by scanPublicRecord.
+const publicRecordColumns = `fqdn, root_domain, raw_record, latitude, longitude,
+		       altitude_m, size_m, horiz_prec_m, vert_prec_m,
+		       first_seen_at, last_seen_at, cname_chain, geohash`
+
+// scanPublicRecord scans a row selected with publicRecordColumns and fills in
+// the derived size fields. Columns selected after those are scanned into
+// extra.
+func scanPublicRecord(row pgx.Row, extra ...any) (api.PublicLOCRecord, error) {
+	var r api.PublicLOCRecord
+	dest := []any{&r.FQDN, &r.RootDomain, &r.RawRecord, &r.Latitude, &r.Longitude,
+		&r.AltitudeM, &r.SizeM, &r.HorizPrecM, &r.VertPrecM, &r.FirstSeenAt, &r.LastSeenAt, &r.CNAMEChain, &r.Geohash}
+	err := row.Scan(append(dest, extra...)...)
+	r.SizeDiameterM = r.SizeM
+	r.SizeRadiusM = r.Record().SizeRadiusM()
+	return r, err
+}
+
+// replaceLOCRecords stores the LOC records one lookup found for a name and
+// returns how many it accepted. A name may publish several LOC records, each
+// kept as its own row; stored records the lookup no longer returned are
+// removed, unless they were seen after it, and logged in
+// loc_record_deletions for the changes feed. A partial lookup, with answers
+// that failed to parse or were rejected, can't tell which records are gone,
+// so it removes nothing.
+//
+// With keepBestPrecision, a lookup whose best horizontal or vertical
+// precision is worse than that of the stored records is taken as a truncated
+// view of them: the stored records are kept and only marked as seen. With
+// replaceIfNewer as well, such a lookup still replaces them if it was made
+// after they were last seen (see keepStoredRecords).
+func replaceLOCRecords(ctx context.Context, q querier, rootDomain string, recs []api.LOCRecord, keepBestPrecision, replaceIfNewer, partial bool) (int, error) {
+	// The records come from a single lookup, so they share a name and time
+	fqdn, observedAt := recs[0].FQDN, recs[0].ObservedAt
+
+	if keepBestPrecision {
+		var storedHoriz, storedVert *float64
+		var storedSeen *time.Time
+		err := q.QueryRow(ctx, `
+			SELECT MIN(horiz_prec_m), MIN(vert_prec_m), MAX(last_seen_at) FROM loc_records WHERE fqdn = $1
+		`, fqdn).Scan(&storedHoriz, &storedVert, &storedSeen)
+		if err != nil {
+			return 0, err
+		}
+		lookup := lookupPrecision{SeenAt: time.Now()}
+		if observedAt != nil {
+			lookup.SeenAt = *observedAt
+		}
+		lookup.HorizPrecM, lookup.VertPrecM = bestPrecision(recs)
+		var stored *lookupPrecision
+		if storedHoriz != nil {
+			stored = &lookupPrecision{HorizPrecM: *storedHoriz, VertPrecM: *storedVert, SeenAt: *storedSeen}
+		}
+		if replaceIfNewer && stored != nil && lookup.SeenAt.After(stored.SeenAt) {
+			// A newer lookup wins row by row too
+			keepBestPrecision = false
+		}
+		if keepStoredRecords(stored, lookup, replaceIfNewer) {
+			_, err := q.Exec(ctx, `
+				UPDATE loc_records SET
+					first_seen_at = LEAST(first_seen_at, COALESCE($2::timestamptz, NOW())),
+					last_seen_at = GREATEST(last_seen_at, COALESCE($2::timestamptz, NOW())),
+					`+markChanged+`
+				WHERE fqdn = $1
+			`, fqdn, observedAt)
+			return len(recs), err
+		}
+	}
+
+	raws := make([]string, len(recs))
+	for i, rec := range recs {
+		if err := upsertLOCRecord(ctx, q, rootDomain, rec, keepBestPrecision); err != nil {
+			return 0, err
+		}
+		raws[i] = rec.RawRecord
+	}
+	if partial {
+		return len(recs), nil
+	}
+	_, err := q.Exec(ctx, `
+		WITH deleted AS (
+			DELETE FROM loc_records
+			WHERE fqdn = $1 AND raw_record <> ALL($2) AND last_seen_at < COALESCE($3::timestamptz, NOW())
+			RETURNING root_domain, fqdn, raw_record
+		)
+		INSERT INTO loc_record_deletions (root_domain, fqdn, raw_record)
+		SELECT root_domain, fqdn, raw_record FROM deleted
+	`, fqdn, raws, observedAt)
+	return len(recs), err
+}
+
+// lookupPrecision is the best precision of a name's records and when they
+// were seen.
+type lookupPrecision struct {
+	HorizPrecM float64
+	VertPrecM  float64
+	SeenAt     time.Time
+}
+
+// keepStoredRecords reports whether a lookup must leave a name's stored
+// records alone under keepBestPrecision: when its best horizontal or
+// vertical precision is worse than theirs, unless replaceIfNewer is set and
+// the lookup was made after they were last seen. Nothing stored (nil) is
+// always replaced.
+func keepStoredRecords(stored *lookupPrecision, lookup lookupPrecision, replaceIfNewer bool) bool {
+	if stored == nil {
+		return false
+	}
+	if lookup.HorizPrecM <= stored.HorizPrecM && lookup.VertPrecM <= stored.VertPrecM {
+		return false
+	}
+	return !replaceIfNewer || !lookup.SeenAt.After(stored.SeenAt)
+}
+
+// bestPrecision returns the smallest horizontal and vertical precision
+// values among recs.
+func bestPrecision(recs []api.LOCRecord) (horiz, vert float64) {
+	horiz, vert = math.Inf(1), math.Inf(1)
+	for _, rec := range recs {
+		horiz, vert = min(horiz, rec.HorizPrecM), min(vert, rec.VertPrecM)
+	}
+	return horiz, vert
+}
+
+// upsertLOCRecord inserts or updates a LOC record. A new row takes the
+// name's root_domain_overrides entry, if any, instead of rootDomain.
+// The observation time is rec.ObservedAt, or now if unset. An existing row
+// keeps the earliest first_seen_at and the latest last_seen_at, so delayed
+// submissions neither move last_seen_at back nor lose an earlier sighting.
+// With keepBestPrecision,
+// the stored data is only replaced when the incoming record is at least as
+// precise (see upsertAssignments).
+func upsertLOCRecord(ctx context.Context, q querier, rootDomain string, rec api.LOCRecord, keepBestPrecision bool) error {
+	// Callers validate already; NaN would otherwise be stored and poison aggregates
+	if err := rec.Validate(); err != nil {
+		return fmt.Errorf("invalid LOC record %s: %w", rec.FQDN, err)
+	}
+	cnameChain := rec.CNAMEChain
+	if cnameChain == nil {
+		cnameChain = []string{} // Column is NOT NULL
+	}
+	_, err := q.Exec(ctx, `
+		INSERT INTO loc_records (root_domain, fqdn, raw_record, latitude, longitude, altitude_m, size_m, horiz_prec_m, vert_prec_m, cname_chain, geohash, first_seen_at, last_seen_at)
+		VALUES (
+			COALESCE((SELECT root_domain FROM root_domain_overrides WHERE fqdn = $2), $1),
+			$2, $3, $4, $5, $6, $7, $8, $9, $10, $11, COALESCE($12::timestamptz, NOW()), COALESCE($12::timestamptz, NOW()))
+		ON CONFLICT (fqdn, raw_record) DO UPDATE SET
+			`+upsertAssignments(keepBestPrecision)+`,
+			first_seen_at = LEAST(loc_records.first_seen_at, EXCLUDED.first_seen_at),
+			last_seen_at = GREATEST(loc_records.last_seen_at, EXCLUDED.last_seen_at),
+			`+markChanged+`
+	`, rootDomain, rec.FQDN, rec.RawRecord, rec.Latitude, rec.Longitude, rec.AltitudeM, rec.SizeM, rec.HorizPrecM, rec.VertPrecM, cnameChain, rec.Geohash(api.GeohashPrecision), rec.ObservedAt)
 	return err
 }
 
-// ListLOCRecords returns paginated LOC records with optional domain filter.
-func (db *DB) ListLOCRecords(ctx context.Context, limit, offset int, domainFilter string) ([]api.PublicLOCRecord, int, error) {
-	// Count total
-	var total int
-	countQuery := `SELECT COUNT(*) FROM loc_records`
-	countArgs := []any{}
+// upsertDataColumns are the loc_records columns replaced on conflict.
+var upsertDataColumns = []string{
+	"raw_record", "latitude", "longitude", "altitude_m", "size_m", "horiz_prec_m", "vert_prec_m", "cname_chain", "geohash",
+}
+
+// upsertAssignments returns the ON CONFLICT SET list for the data columns.
+// Normally the last writer wins. With keepBestPrecision, the existing row is
+// kept unless the incoming horizontal and vertical precision values are both
+// equal or smaller, so a truncated observation cannot downgrade stored data.
+func upsertAssignments(keepBestPrecision bool) string {
+	const better = "EXCLUDED.horiz_prec_m <= loc_records.horiz_prec_m AND EXCLUDED.vert_prec_m <= loc_records.vert_prec_m"
 
-	if domainFilter != "" {
-		countQuery += ` WHERE root_domain = $1`
-		countArgs = append(countArgs, domainFilter)
+	sets := make([]string, len(upsertDataColumns))
+	for i, col := range upsertDataColumns {
+		if keepBestPrecision {
+			sets[i] = fmt.Sprintf("%s = CASE WHEN %s THEN EXCLUDED.%s ELSE loc_records.%s END", col, better, col, col)
+		} else {
+			sets[i] = fmt.Sprintf("%s = EXCLUDED.%s", col, col)
+		}
 	}
+	return strings.Join(sets, ",\n\t\t\t")
+}
 
-	if err := db.Pool.QueryRow(ctx, countQuery, countArgs...).Scan(&total); err != nil {
+// ListLOCRecords returns paginated LOC records matching the filter.
+func (db *DB) ListLOCRecords(ctx context.Context, limit, offset int, filter RecordFilter) ([]api.PublicLOCRecord, int, error) {
+	var q queryBuilder
+	filter.apply(&q)
+	where := q.where()
+
+	// Count total
+	var total int
+	if err := db.Pool.QueryRow(ctx, `SELECT COUNT(*) FROM loc_records `+where, q.args...).Scan(&total); err != nil {
 		return nil, 0, err
 	}
 
 	// Get records
-	var rows pgx.Rows
-	var err error
-	if domainFilter != "" {
-		rows, err = db.Pool.Query(ctx, `
-			SELECT fqdn, root_domain, raw_record, latitude, longitude,
-			       altitude_m, size_m, horiz_prec_m, vert_prec_m,
-			       first_seen_at, last_seen_at
-			FROM loc_records
-			WHERE root_domain = $1
-			ORDER BY last_seen_at DESC
-			LIMIT $2 OFFSET $3
-		`, domainFilter, limit, offset)
-	} else {
-		rows, err = db.Pool.Query(ctx, `
-			SELECT fqdn, root_domain, raw_record, latitude, longitude,
-			       altitude_m, size_m, horiz_prec_m, vert_prec_m,
-			       first_seen_at, last_seen_at
-			FROM loc_records
-			ORDER BY last_seen_at DESC
-			LIMIT $1 OFFSET $2
-		`, limit, offset)
-	}
+	rows, err := db.Pool.Query(ctx, `
+		SELECT `+publicRecordColumns+`
+		FROM loc_records
+		`+where+`
+		ORDER BY `+filter.orderBy("last_seen_at DESC, fqdn DESC, raw_record DESC")+`
+		LIMIT `+q.arg(limit)+` OFFSET `+q.arg(offset), q.args...)
 	if err != nil {
 		return nil, 0, err
 	}
@@ -90,9 +234,8 @@ func (db *DB) ListLOCRecords(ctx context.Context, limit, offset int, domainFilte
 
 	var records []api.PublicLOCRecord
 	for rows.Next() {
-		var r api.PublicLOCRecord
-		if err := rows.Scan(&r.FQDN, &r.RootDomain, &r.RawRecord, &r.Latitude, &r.Longitude,
-			&r.AltitudeM, &r.SizeM, &r.HorizPrecM, &r.VertPrecM, &r.FirstSeenAt, &r.LastSeenAt); err != nil {
+		r, err := scanPublicRecord(rows)
+		if err != nil {
 			return nil, 0, err
 		}
 		records = append(records, r)
@@ -101,6 +244,310 @@ func (db *DB) ListLOCRecords(ctx context.Context, limit, offset int, domainFilte
 	return records, total, rows.Err()
 }
 
+// RecordCursor is a position in the records list, as used for keyset
+// pagination. (last_seen_at, fqdn, raw_record) is unique because
+// (fqdn, raw_record) is.
+type RecordCursor struct {
+	LastSeenAt time.Time
+	FQDN       string
+	RawRecord  string
+}
+
+// ListLOCRecordsPage returns up to limit records matching the filter that
+// come after cursor in list order, or before it if backward is set. A nil
+// cursor starts from the beginning (or, backward, from the end). Records are
+// always returned in list order. more reports whether further records lie
+// beyond the page in the scan direction.
+//
+// Unlike ListLOCRecords with a large offset, this uses
+// idx_loc_records_last_seen and costs the same on every page.
+func (db *DB) ListLOCRecordsPage(ctx context.Context, limit int, filter RecordFilter, cursor *RecordCursor, backward bool) (records []api.PublicLOCRecord, total int, more bool, err error) {
+	var q queryBuilder
+	filter.apply(&q)
+	if err := db.Pool.QueryRow(ctx, `SELECT COUNT(*) FROM loc_records `+q.where(), q.args...).Scan(&total); err != nil {
+		return nil, 0, false, err
+	}
+
+	// Scanning backward walks the list order in reverse
+	ascending := filter.listAscending() != backward
+	dir, op := "DESC", "<"
+	if ascending {
+		dir, op = "ASC", ">"
+	}
+	if cursor != nil {
+		q.conds = append(q.conds, fmt.Sprintf("(last_seen_at, fqdn, raw_record) %s (%s, %s, %s)",
+			op, q.arg(cursor.LastSeenAt), q.arg(cursor.FQDN), q.arg(cursor.RawRecord)))
+	}
+
+	// Fetch one extra row to learn whether there is more
+	rows, err := db.Pool.Query(ctx, `
+		SELECT `+publicRecordColumns+`
+		FROM loc_records
+		`+q.where()+`
+		ORDER BY last_seen_at `+dir+`, fqdn `+dir+`, raw_record `+dir+`
+		LIMIT `+q.arg(limit+1), q.args...)
+	if err != nil {
+		return nil, 0, false, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		r, err := scanPublicRecord(rows)
+		if err != nil {
+			return nil, 0, false, err
+		}
+		records = append(records, r)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, 0, false, err
+	}
+
+	if len(records) > limit {
+		records, more = records[:limit], true
+	}
+	if backward {
+		slices.Reverse(records)
+	}
+	return records, total, more, nil
+}
+
+// ListLOCRecordsAfter returns up to limit records in (fqdn, raw_record)
+// order that come after the given name and raw record; empty strings start
+// from the beginning. The key is unique and not changed by scans, so walking
+// the table this way neither skips nor repeats records that are updated
+// meanwhile, and each page is a separate short query.
+func (db *DB) ListLOCRecordsAfter(ctx context.Context, fqdn, rawRecord string, limit int) ([]api.PublicLOCRecord, error) {
+	rows, err := db.Pool.Query(ctx, `
+		SELECT `+publicRecordColumns+`
+		FROM loc_records
+		WHERE (fqdn, raw_record) > ($1, $2)
+		ORDER BY fqdn, raw_record
+		LIMIT $3
+	`, fqdn, rawRecord, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var records []api.PublicLOCRecord
+	for rows.Next() {
+		r, err := scanPublicRecord(rows)
+		if err != nil {
+			return nil, err
+		}
+		records = append(records, r)
+	}
+	return records, rows.Err()
+}
+
+// ListRecentLOCRecords returns the most recently discovered LOC records,
+// ordered by first_seen_at descending.
+func (db *DB) ListRecentLOCRecords(ctx context.Context, limit int) ([]api.PublicLOCRecord, error) {
+	return db.listLOCRecordsOrdered(ctx, "first_seen_at DESC", limit)
+}
+
+// ListRecentlySeenLOCRecords returns the most recently seen LOC records,
+// ordered by last_seen_at descending. It walks idx_loc_records_last_seen.
+func (db *DB) ListRecentlySeenLOCRecords(ctx context.Context, limit int) ([]api.PublicLOCRecord, error) {
+	return db.listLOCRecordsOrdered(ctx, "last_seen_at DESC, fqdn DESC, raw_record DESC", limit)
+}
+
+// listLOCRecordsOrdered returns the first limit LOC records in orderBy order.
+func (db *DB) listLOCRecordsOrdered(ctx context.Context, orderBy string, limit int) ([]api.PublicLOCRecord, error) {
+	rows, err := db.Pool.Query(ctx, `
+		SELECT `+publicRecordColumns+`
+		FROM loc_records
+		ORDER BY `+orderBy+`
+		LIMIT $1
+	`, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var records []api.PublicLOCRecord
+	for rows.Next() {
+		r, err := scanPublicRecord(rows)
+		if err != nil {
+			return nil, err
+		}
+		records = append(records, r)
+	}
+
+	return records, rows.Err()
+}
+
+// GetLOCRecordsByFQDN returns the LOC records of an FQDN, most precise
+// first, or none if it has no record.
+func (db *DB) GetLOCRecordsByFQDN(ctx context.Context, fqdn string) ([]api.PublicLOCRecord, error) {
+	rows, err := db.Pool.Query(ctx, `
+		SELECT `+publicRecordColumns+`
+		FROM loc_records
+		WHERE fqdn = $1
+		ORDER BY horiz_prec_m, vert_prec_m, raw_record
+	`, fqdn)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var records []api.PublicLOCRecord
+	for rows.Next() {
+		r, err := scanPublicRecord(rows)
+		if err != nil {
+			return nil, err
+		}
+		records = append(records, r)
+	}
+	return records, rows.Err()
+}
+
+// UpdateRootDomain sets the root domain of all records of fqdn and writes an
+// audit entry for the change, attributed to actor, in one transaction. If
+// fqdn has records, rootDomain is also kept in root_domain_overrides so
+// records stored by later scans get it too. It returns the distinct root
+// domains the updated records had before; none if fqdn has no records or
+// they already have rootDomain.
+func (db *DB) UpdateRootDomain(ctx context.Context, fqdn, rootDomain, actor string) ([]string, error) {
+	tx, err := db.Pool.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx) //nolint:errcheck
+
+	_, err = tx.Exec(ctx, `
+		INSERT INTO root_domain_overrides (fqdn, root_domain)
+		SELECT $1, $2 WHERE EXISTS (SELECT 1 FROM loc_records WHERE fqdn = $1)
+		ON CONFLICT (fqdn) DO UPDATE SET root_domain = EXCLUDED.root_domain, updated_at = NOW()
+	`, fqdn, rootDomain)
+	if err != nil {
+		return nil, err
+	}
+
+	// The self-join sees the rows as they were before the update.
+	rows, err := tx.Query(ctx, `
+		UPDATE loc_records l SET root_domain = $2, `+markChanged+`
+		FROM loc_records old
+		WHERE l.fqdn = $1 AND old.id = l.id AND l.root_domain <> $2
+		RETURNING old.root_domain
+	`, fqdn, rootDomain)
+	if err != nil {
+		return nil, err
+	}
+	previous, err := pgx.CollectRows(rows, pgx.RowTo[string])
+	if err != nil {
+		return nil, err
+	}
+	if len(previous) == 0 {
+		return nil, tx.Commit(ctx)
+	}
+	updated := len(previous)
+	slices.Sort(previous)
+	previous = slices.Compact(previous)
+
+	err = insertAuditEntry(ctx, tx, AuditEntry{
+		Action: AuditRecordUpdate,
+		Target: fqdn,
+		Details: map[string]any{
+			"root_domain": rootDomain,
+			"previous":    previous,
+			"records":     updated,
+		},
+		Actor: actor,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return previous, tx.Commit(ctx)
+}
+
+// FindSimilarLOCRecords returns up to limit records other than fqdn within
+// toleranceM meters of (lat, lon), nearest first. Candidates are selected
+// with a bounding box, which the index serves, and then filtered, sorted and
+// cut to limit by great-circle distance in SQL, so a dense area doesn't load
+// every candidate.
+func (db *DB) FindSimilarLOCRecords(ctx context.Context, fqdn string, lat, lon, toleranceM float64, limit int) ([]api.SimilarRecord, error) {
+	km := toleranceM / 1000
+	box := bboxAround(lat, lon, km)
+	var q queryBuilder
+	RecordFilter{BBox: &box}.apply(&q)
+	q.conds = append(q.conds, "fqdn <> "+q.arg(fqdn))
+	distance := haversineKm(q.arg(lat), q.arg(lon))
+
+	rows, err := db.Pool.Query(ctx, `
+		SELECT `+publicRecordColumns+`, distance_km
+		FROM (
+			SELECT *, `+distance+` AS distance_km
+			FROM loc_records
+			`+q.where()+`
+		) candidates
+		WHERE distance_km <= `+q.arg(km)+`
+		ORDER BY distance_km, fqdn, raw_record
+		LIMIT `+q.arg(limit), q.args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var similar []api.SimilarRecord
+	for rows.Next() {
+		var d float64
+		r, err := scanPublicRecord(rows, &d)
+		if err != nil {
+			return nil, err
+		}
+		similar = append(similar, api.SimilarRecord{PublicLOCRecord: r, DistanceM: d * 1000})
+	}
+	return similar, rows.Err()
+}
+
+// haversineKm returns an SQL expression for the great-circle distance in
+// kilometers from a row's coordinates to the point given by the lat and lon
+// placeholders, matching api.DistanceKm.
+func haversineKm(lat, lon string) string {
+	return fmt.Sprintf(`2 * %v * asin(least(1, sqrt(
+				power(sin(radians(latitude - %[2]s::float8) / 2), 2) +
+				cos(radians(%[2]s::float8)) * cos(radians(latitude)) * power(sin(radians(longitude - %[3]s::float8) / 2), 2))))`,
+		api.EarthRadiusKm, lat, lon)
+}
+
+// StreamLOCRecords calls fn for every LOC record matching the filter, reading
+// rows from the cursor one at a time so memory stays flat for large result sets.
+// Iteration stops at the first error returned by fn.
+func (db *DB) StreamLOCRecords(ctx context.Context, filter RecordFilter, fn func(api.PublicLOCRecord) error) error {
+	where, args := filter.whereClause()
+	rows, err := db.Pool.Query(ctx, `
+		SELECT `+publicRecordColumns+`
+		FROM loc_records
+		`+where+`
+		ORDER BY `+filter.orderBy("fqdn, raw_record")+`
+	`, args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		r, err := scanPublicRecord(rows)
+		if err != nil {
+			return err
+		}
+		if err := fn(r); err != nil {
+			return err
+		}
+	}
+
+	return rows.Err()
+}
+
+// CountMatchingLOCRecords returns how many LOC records match the filter.
+func (db *DB) CountMatchingLOCRecords(ctx context.Context, filter RecordFilter) (int, error) {
+	where, args := filter.whereClause()
+	var count int
+	err := db.Pool.QueryRow(ctx, `SELECT COUNT(*) FROM loc_records `+where, args...).Scan(&count)
+	return count, err
+}
+
 // CountLOCRecords returns total LOC record count.
 func (db *DB) CountLOCRecords(ctx context.Context) (int, error) {
 	var count int
@@ -115,6 +562,63 @@ func (db *DB) CountUniqueRootDomainsWithLOC(ctx context.Context) (int, error) {
 	return count, err
 }
 
+// ListRootDomainsWithLOC returns paginated root domains that have at least one
+// LOC record, with per-domain record counts, plus the total number of such domains.
+func (db *DB) ListRootDomainsWithLOC(ctx context.Context, limit, offset int) ([]api.RootDomainCount, int, error) {
+	total, err := db.CountUniqueRootDomainsWithLOC(ctx)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	rows, err := db.Pool.Query(ctx, `
+		SELECT root_domain, COUNT(*)
+		FROM loc_records
+		GROUP BY root_domain
+		ORDER BY root_domain
+		LIMIT $1 OFFSET $2
+	`, limit, offset)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	var domains []api.RootDomainCount
+	for rows.Next() {
+		var d api.RootDomainCount
+		if err := rows.Scan(&d.RootDomain, &d.RecordCount); err != nil {
+			return nil, 0, err
+		}
+		domains = append(domains, d)
+	}
+
+	return domains, total, rows.Err()
+}
+
+// GetRecordBounds returns the coordinate and altitude extent of the LOC
+// records matching the filter. With no matching records, the whole world is
+// returned (see api.BoundsResponse).
+func (db *DB) GetRecordBounds(ctx context.Context, filter RecordFilter) (api.BoundsResponse, error) {
+	where, args := filter.whereClause()
+	var b api.BoundsResponse
+	var minLat, maxLat, minLon, maxLon, minAlt, maxAlt *float64
+	err := db.Pool.QueryRow(ctx, `
+		SELECT COUNT(*), MIN(latitude), MAX(latitude), MIN(longitude), MAX(longitude), MIN(altitude_m), MAX(altitude_m)
+		FROM loc_records
+		`+where, args...).Scan(&b.Count, &minLat, &maxLat, &minLon, &maxLon, &minAlt, &maxAlt)
+	if err != nil {
+		return b, err
+	}
+	if b.Count == 0 {
+		b.MinLatitude, b.MaxLatitude = -90, 90
+		b.MinLongitude, b.MaxLongitude = -180, 180
+		return b, nil
+	}
+	b.MinLatitude, b.MaxLatitude = *minLat, *maxLat
+	b.MinLongitude, b.MaxLongitude = *minLon, *maxLon
+	b.MinAltitudeM, b.MaxAltitudeM = *minAlt, *maxAlt
+	return b, nil
+}
+
 // CountUniqueLocations returns the number of unique coordinate locations.
 func (db *DB) CountUniqueLocations(ctx context.Context) (int, error) {
 	var count int
@@ -122,13 +626,120 @@ func (db *DB) CountUniqueLocations(ctx context.Context) (int, error) {
 	return count, err
 }
 
+// recordsPerDomainBounds are the upper bounds of the records-per-domain
+// buckets; a final open-ended bucket holds everything above the last one.
+var recordsPerDomainBounds = []int{1, 5, 20}
+
+// recordsPerDomainBuckets returns the empty buckets for recordsPerDomainBounds.
+func recordsPerDomainBuckets() []api.RecordsPerDomainBucket {
+	buckets := make([]api.RecordsPerDomainBucket, 0, len(recordsPerDomainBounds)+1)
+	lo := 1
+	for _, hi := range recordsPerDomainBounds {
+		label := strconv.Itoa(lo)
+		if hi != lo {
+			label += "-" + strconv.Itoa(hi)
+		}
+		buckets = append(buckets, api.RecordsPerDomainBucket{Label: label, Min: lo, Max: &hi})
+		lo = hi + 1
+	}
+	return append(buckets, api.RecordsPerDomainBucket{Label: strconv.Itoa(lo) + "+", Min: lo})
+}
+
+// GetRecordsPerDomain returns how many root domains have 1, 2-5, 6-20 or
+// more matching records, i.e. whether LOC records sit only at the apex or
+// are spread across subdomains.
+func (db *DB) GetRecordsPerDomain(ctx context.Context, filter RecordFilter) (api.RecordsPerDomainResponse, error) {
+	where, args := filter.whereClause()
+
+	// Map each domain's count to its bucket index
+	var bucketExpr strings.Builder
+	bucketExpr.WriteString("CASE")
+	for i, hi := range recordsPerDomainBounds {
+		fmt.Fprintf(&bucketExpr, " WHEN n <= %d THEN %d", hi, i)
+	}
+	fmt.Fprintf(&bucketExpr, " ELSE %d END", len(recordsPerDomainBounds))
+
+	rows, err := db.Pool.Query(ctx, `
+		SELECT `+bucketExpr.String()+` AS bucket, COUNT(*)
+		FROM (SELECT COUNT(*) AS n FROM loc_records `+where+` GROUP BY root_domain) per_domain
+		GROUP BY bucket
+	`, args...)
+	if err != nil {
+		return api.RecordsPerDomainResponse{}, err
+	}
+	defer rows.Close()
+
+	resp := api.RecordsPerDomainResponse{Buckets: recordsPerDomainBuckets()}
+	for rows.Next() {
+		var bucket, domains int
+		if err := rows.Scan(&bucket, &domains); err != nil {
+			return api.RecordsPerDomainResponse{}, err
+		}
+		resp.Buckets[bucket].Domains = domains
+		resp.TotalDomains += domains
+	}
+	return resp, rows.Err()
+}
+
+// GetDensity counts the matching records per cell of a grid of grid
+// degrees, anchored at 0,0, and returns the limit densest cells, densest
+// first. The cells are computed in numeric rather than float8, so a grid
+// such as 0.1 doesn't put records at a cell edge in the wrong cell.
+func (db *DB) GetDensity(ctx context.Context, grid float64, limit int, filter RecordFilter) ([]api.DensityCell, error) {
+	var q queryBuilder
+	filter.apply(&q)
+	where := q.where()
+	g := q.arg(grid) + "::numeric"
+
+	// Records on the north pole or at longitude 180 join the cell below
+	rows, err := db.Pool.Query(ctx, `
+		SELECT
+			LEAST(floor(latitude::numeric / `+g+`), ceil(90 / `+g+`) - 1)::bigint AS y,
+			LEAST(floor(longitude::numeric / `+g+`), ceil(180 / `+g+`) - 1)::bigint AS x,
+			COUNT(*)
+		FROM loc_records
+		`+where+`
+		GROUP BY y, x
+		ORDER BY COUNT(*) DESC, y, x
+		LIMIT `+q.arg(limit), q.args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var cells []api.DensityCell
+	for rows.Next() {
+		var y, x int64
+		var count int
+		if err := rows.Scan(&y, &x, &count); err != nil {
+			return nil, err
+		}
+		cells = append(cells, densityCell(y, x, grid, count))
+	}
+	return cells, rows.Err()
+}
+
+// densityCell returns the bounds of grid cell (y, x), clipped to the valid
+// coordinate range and rounded to clear float noise such as 0.30000000000000004.
+func densityCell(y, x int64, grid float64, count int) api.DensityCell {
+	edge := func(i int64, limit float64) float64 {
+		v := math.Round(float64(i)*grid*1e9) / 1e9
+		return math.Max(-limit, math.Min(v, limit))
+	}
+	return api.DensityCell{
+		MinLat: edge(y, 90),
+		MinLon: edge(x, 180),
+		MaxLat: edge(y+1, 90),
+		MaxLon: edge(x+1, 180),
+		Count:  count,
+	}
+}
+
 // GetAllLOCRecordsForGeoJSON returns all LOC records for GeoJSON export.
 // Returns records without pagination for map rendering.
 func (db *DB) GetAllLOCRecordsForGeoJSON(ctx context.Context) ([]api.PublicLOCRecord, error) {
 	rows, err := db.Pool.Query(ctx, `
-		SELECT fqdn, root_domain, raw_record, latitude, longitude,
-		       altitude_m, size_m, horiz_prec_m, vert_prec_m,
-		       first_seen_at, last_seen_at
+		SELECT `+publicRecordColumns+`
 		FROM loc_records
 		ORDER BY last_seen_at DESC
 	`)
@@ -139,9 +750,8 @@ func (db *DB) GetAllLOCRecordsForGeoJSON(ctx context.Context) ([]api.PublicLOCRe
 
 	var records []api.PublicLOCRecord
 	for rows.Next() {
-		var r api.PublicLOCRecord
-		if err := rows.Scan(&r.FQDN, &r.RootDomain, &r.RawRecord, &r.Latitude, &r.Longitude,
-			&r.AltitudeM, &r.SizeM, &r.HorizPrecM, &r.VertPrecM, &r.FirstSeenAt, &r.LastSeenAt); err != nil {
+		r, err := scanPublicRecord(rows)
+		if err != nil {
 			return nil, err
 		}
 		records = append(records, r)
@@ -150,9 +760,10 @@ func (db *DB) GetAllLOCRecordsForGeoJSON(ctx context.Context) ([]api.PublicLOCRe
 	return records, rows.Err()
 }
 
-// GetAggregatedLocationsForGeoJSON returns LOC records aggregated by coordinates.
+// GetAggregatedLocationsForGeoJSON returns LOC records matching the filter, aggregated by coordinates.
 // Multiple FQDNs at the same location are combined into a single feature.
-func (db *DB) GetAggregatedLocationsForGeoJSON(ctx context.Context) ([]api.AggregatedLocation, error) {
+func (db *DB) GetAggregatedLocationsForGeoJSON(ctx context.Context, filter RecordFilter) ([]api.AggregatedLocation, error) {
+	where, args := filter.whereClause()
 	rows, err := db.Pool.Query(ctx, `
 		SELECT
 			array_agg(fqdn ORDER BY fqdn) as fqdns,
@@ -161,13 +772,15 @@ func (db *DB) GetAggregatedLocationsForGeoJSON(ctx context.Context) ([]api.Aggre
 			latitude,
 			longitude,
 			altitude_m,
+			size_m,
 			COUNT(*) as count,
 			MIN(first_seen_at) as first_seen_at,
 			MAX(last_seen_at) as last_seen_at
 		FROM loc_records
-		GROUP BY latitude, longitude, altitude_m, raw_record
+		`+where+`
+		GROUP BY latitude, longitude, altitude_m, size_m, raw_record
 		ORDER BY MAX(last_seen_at) DESC
-	`)
+	`, args...)
 	if err != nil {
 		return nil, err
 	}
@@ -177,7 +790,7 @@ func (db *DB) GetAggregatedLocationsForGeoJSON(ctx context.Context) ([]api.Aggre
 	for rows.Next() {
 		var loc api.AggregatedLocation
 		if err := rows.Scan(&loc.FQDNs, &loc.RootDomains, &loc.RawRecord, &loc.Latitude, &loc.Longitude,
-			&loc.AltitudeM, &loc.Count, &loc.FirstSeenAt, &loc.LastSeenAt); err != nil {
+			&loc.AltitudeM, &loc.SizeM, &loc.Count, &loc.FirstSeenAt, &loc.LastSeenAt); err != nil {
 			return nil, err
 		}
 		locations = append(locations, loc)
@@ -0,0 +1,160 @@
+package db
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/locplace/scanner/pkg/api"
+)
+
+func TestUpsertAssignments(t *testing.T) {
+	t.Run("last writer wins", func(t *testing.T) {
+		got := upsertAssignments(false)
+		if !strings.Contains(got, "horiz_prec_m = EXCLUDED.horiz_prec_m") {
+			t.Errorf("expected unconditional assignment, got %q", got)
+		}
+		if strings.Contains(got, "CASE") {
+			t.Errorf("unexpected conditional assignment in %q", got)
+		}
+	})
+
+	t.Run("keep best precision", func(t *testing.T) {
+		got := upsertAssignments(true)
+		for _, col := range upsertDataColumns {
+			want := col + " = CASE WHEN EXCLUDED.horiz_prec_m <= loc_records.horiz_prec_m AND EXCLUDED.vert_prec_m <= loc_records.vert_prec_m THEN EXCLUDED." + col + " ELSE loc_records." + col + " END"
+			if !strings.Contains(got, want) {
+				t.Errorf("missing guarded assignment for %s in %q", col, got)
+			}
+		}
+	})
+}
+
+func TestKeepStoredRecords(t *testing.T) {
+	seen := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+	stored := &lookupPrecision{HorizPrecM: 10, VertPrecM: 2, SeenAt: seen}
+
+	tests := []struct {
+		name           string
+		stored         *lookupPrecision
+		lookup         lookupPrecision
+		replaceIfNewer bool
+		want           bool
+	}{
+		{name: "nothing stored", lookup: lookupPrecision{HorizPrecM: 10000, VertPrecM: 10, SeenAt: seen}},
+		{name: "same precision", stored: stored, lookup: lookupPrecision{HorizPrecM: 10, VertPrecM: 2, SeenAt: seen}},
+		{name: "better precision", stored: stored, lookup: lookupPrecision{HorizPrecM: 1, VertPrecM: 1, SeenAt: seen.Add(-time.Hour)}},
+		{name: "worse horizontal precision", stored: stored, lookup: lookupPrecision{HorizPrecM: 10000, VertPrecM: 2, SeenAt: seen.Add(time.Hour)}, want: true},
+		{name: "worse vertical precision", stored: stored, lookup: lookupPrecision{HorizPrecM: 1, VertPrecM: 10, SeenAt: seen.Add(time.Hour)}, want: true},
+		{name: "worse but newer", stored: stored, lookup: lookupPrecision{HorizPrecM: 10000, VertPrecM: 10, SeenAt: seen.Add(time.Hour)}, replaceIfNewer: true},
+		{name: "worse and equally old", stored: stored, lookup: lookupPrecision{HorizPrecM: 10000, VertPrecM: 10, SeenAt: seen}, replaceIfNewer: true, want: true},
+		{name: "worse and delayed", stored: stored, lookup: lookupPrecision{HorizPrecM: 10000, VertPrecM: 10, SeenAt: seen.Add(-time.Hour)}, replaceIfNewer: true, want: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := keepStoredRecords(tt.stored, tt.lookup, tt.replaceIfNewer); got != tt.want {
+				t.Errorf("keepStoredRecords() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRecordsPerDomainBuckets(t *testing.T) {
+	buckets := recordsPerDomainBuckets()
+
+	wantLabels := []string{"1", "2-5", "6-20", "21+"}
+	if len(buckets) != len(wantLabels) {
+		t.Fatalf("got %d buckets, want %d", len(buckets), len(wantLabels))
+	}
+	for i, b := range buckets {
+		if b.Label != wantLabels[i] {
+			t.Errorf("bucket %d label = %q, want %q", i, b.Label, wantLabels[i])
+		}
+		// Buckets must be contiguous
+		if i > 0 && b.Min != *buckets[i-1].Max+1 {
+			t.Errorf("bucket %q starts at %d, previous ends at %d", b.Label, b.Min, *buckets[i-1].Max)
+		}
+	}
+	if buckets[len(buckets)-1].Max != nil {
+		t.Error("last bucket should be open-ended")
+	}
+}
+
+func TestDensityCell(t *testing.T) {
+	tests := []struct {
+		name string
+		y, x int64
+		grid float64
+		want api.DensityCell
+	}{
+		{"one degree", 52, 4, 1, api.DensityCell{MinLat: 52, MinLon: 4, MaxLat: 53, MaxLon: 5, Count: 7}},
+		{"negative", -1, -1, 1, api.DensityCell{MinLat: -1, MinLon: -1, MaxLat: 0, MaxLon: 0, Count: 7}},
+		{"float noise", 3, 1, 0.1, api.DensityCell{MinLat: 0.3, MinLon: 0.1, MaxLat: 0.4, MaxLon: 0.2, Count: 7}},
+		{"clipped at the pole", 1, 2, 50, api.DensityCell{MinLat: 50, MinLon: 100, MaxLat: 90, MaxLon: 150, Count: 7}},
+		{"clipped at the antimeridian", -2, 2, 70, api.DensityCell{MinLat: -90, MinLon: 140, MaxLat: -70, MaxLon: 180, Count: 7}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := densityCell(tt.y, tt.x, tt.grid, 7); got != tt.want {
+				t.Errorf("densityCell(%d, %d, %g) = %+v, want %+v", tt.y, tt.x, tt.grid, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGroupByFQDN(t *testing.T) {
+	rec := func(fqdn, raw string, horiz, vert float64) BatchRecord {
+		return BatchRecord{RootDomain: "example.com", Record: api.LOCRecord{FQDN: fqdn, RawRecord: raw, HorizPrecM: horiz, VertPrecM: vert}}
+	}
+	// Two distinct LOC records on one name must both be kept
+	records := []BatchRecord{
+		rec("a.example.com", "52 22 23.000 N 4 53 32.000 E -2.00m 1m 10000m 10m", 10000, 10),
+		rec("b.example.com", "37 46 30.000 N 122 25 10.000 W 10.00m 1m 100m 10m", 100, 10),
+		rec("a.example.com", "51 30 12.000 N 0 7 39.000 W 5.00m 1m 500m 2m", 500, 2),
+	}
+
+	groups := groupByFQDN(records)
+	if len(groups) != 2 {
+		t.Fatalf("got %d groups, want 2", len(groups))
+	}
+	if len(groups[0]) != 2 || groups[0][0].Record.RawRecord != records[0].Record.RawRecord || groups[0][1].Record.RawRecord != records[2].Record.RawRecord {
+		t.Errorf("group a.example.com = %+v, want both of its records in order", groups[0])
+	}
+	if len(groups[1]) != 1 || groups[1][0].Record.FQDN != "b.example.com" {
+		t.Errorf("group b.example.com = %+v", groups[1])
+	}
+
+	var recs []api.LOCRecord
+	for _, r := range groups[0] {
+		recs = append(recs, r.Record)
+	}
+	if horiz, vert := bestPrecision(recs); horiz != 500 || vert != 2 {
+		t.Errorf("bestPrecision() = %v, %v, want 500, 2", horiz, vert)
+	}
+}
+
+func TestSucceededLookups(t *testing.T) {
+	domains := "a.example.com\n b.example.com \n\nc.example.com\n"
+	errs := []api.ScanError{{FQDN: "b.example.com"}}
+	got := succeededLookups(domains, errs)
+	want := []string{"a.example.com", "c.example.com"}
+	if strings.Join(got, ",") != strings.Join(want, ",") {
+		t.Errorf("succeededLookups() = %v, want %v", got, want)
+	}
+	if got := succeededLookups("", nil); len(got) != 0 {
+		t.Errorf("succeededLookups(empty) = %v, want none", got)
+	}
+}
+
+func TestSettledLookups(t *testing.T) {
+	resolved := []string{"mixed.example.com", "clean.example.com"}
+	got := settledLookups(resolved, []string{"mixed.example.com"})
+	if len(got) != 1 || got[0] != "clean.example.com" {
+		t.Errorf("settledLookups() = %v, want only clean.example.com", got)
+	}
+	if len(resolved) != 2 {
+		t.Errorf("settledLookups() modified its input: %v", resolved)
+	}
+}
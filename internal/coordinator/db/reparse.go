@@ -0,0 +1,75 @@
+package db
+
+import (
+	"context"
+	"slices"
+
+	"github.com/locplace/scanner/pkg/api"
+)
+
+// UpdateParsedFields rewrites the parsed columns of stored records, matched
+// by (fqdn, raw_record), after a parser change. The seen timestamps are
+// kept; the rows are marked changed for the changes feed. It returns the
+// number of rows updated.
+func (db *DB) UpdateParsedFields(ctx context.Context, recs []api.LOCRecord) (int, error) {
+	if len(recs) == 0 {
+		return 0, nil
+	}
+
+	n := len(recs)
+	fqdns, raws, geohashes := make([]string, n), make([]string, n), make([]string, n)
+	lats, lons, alts := make([]float64, n), make([]float64, n), make([]float64, n)
+	sizes, horiz, vert := make([]float64, n), make([]float64, n), make([]float64, n)
+	for i, r := range recs {
+		fqdns[i], raws[i], geohashes[i] = r.FQDN, r.RawRecord, r.Geohash(api.GeohashPrecision)
+		lats[i], lons[i], alts[i] = r.Latitude, r.Longitude, r.AltitudeM
+		sizes[i], horiz[i], vert[i] = r.SizeM, r.HorizPrecM, r.VertPrecM
+	}
+
+	tag, err := db.Pool.Exec(ctx, `
+		UPDATE loc_records l SET
+			latitude = u.latitude,
+			longitude = u.longitude,
+			altitude_m = u.altitude_m,
+			size_m = u.size_m,
+			horiz_prec_m = u.horiz_prec_m,
+			vert_prec_m = u.vert_prec_m,
+			geohash = u.geohash,
+			`+markChanged+`
+		FROM unnest($1::text[], $2::text[], $3::float8[], $4::float8[], $5::float8[], $6::float8[], $7::float8[], $8::float8[], $9::text[])
+			AS u(fqdn, raw_record, latitude, longitude, altitude_m, size_m, horiz_prec_m, vert_prec_m, geohash)
+		WHERE l.fqdn = u.fqdn AND l.raw_record = u.raw_record
+	`, fqdns, raws, lats, lons, alts, sizes, horiz, vert, geohashes)
+	if err != nil {
+		return 0, err
+	}
+	return int(tag.RowsAffected()), nil
+}
+
+// StoreFixedUnparsed stores records whose raw answers previously failed to
+// parse and removes their unparsed_records entries, in one transaction.
+// Each record's ObservedAt should be when the answer was last seen. The
+// entries of rejected, answers that now parse but are not to be stored, are
+// removed as well. Other unparsed answers of the same names are kept.
+func (db *DB) StoreFixedUnparsed(ctx context.Context, records []BatchRecord, rejected []api.LOCRecord) error {
+	if len(records) == 0 && len(rejected) == 0 {
+		return nil
+	}
+	tx, err := db.Pool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx) //nolint:errcheck
+
+	answers := slices.Clone(rejected)
+	for _, r := range records {
+		if err := upsertLOCRecord(ctx, tx, r.RootDomain, r.Record, false); err != nil {
+			return err
+		}
+		answers = append(answers, r.Record)
+	}
+	if err := clearUnparsedAnswers(ctx, tx, answers); err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
@@ -0,0 +1,154 @@
+package db
+
+import (
+	"context"
+	"log"
+	"slices"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+
+	"github.com/locplace/scanner/pkg/api"
+)
+
+// BatchRecord is a LOC record from a batch, with its root domain.
+type BatchRecord struct {
+	RootDomain string
+	Record     api.LOCRecord
+}
+
+// groupByFQDN splits records into one group per name, in order of first
+// appearance, so a name's LOC records are stored together.
+func groupByFQDN(records []BatchRecord) [][]BatchRecord {
+	index := make(map[string]int)
+	var groups [][]BatchRecord
+	for _, r := range records {
+		i, ok := index[r.Record.FQDN]
+		if !ok {
+			i = len(groups)
+			index[r.Record.FQDN] = i
+			groups = append(groups, nil)
+		}
+		groups[i] = append(groups[i], r)
+	}
+	return groups
+}
+
+// BatchResults is everything a scanner reports for a leased batch.
+type BatchResults struct {
+	BatchID           int64
+	Records           []BatchRecord
+	KeepBestPrecision bool
+	ScanErrors        []api.ScanError
+	// ReplaceIfNewer lets less precise records replace stored ones anyway
+	// when observed after them; only used with KeepBestPrecision.
+	ReplaceIfNewer bool
+	// Unparsed are LOC answers that failed to parse, kept for review in
+	// unparsed_records. Nil when KEEP_UNPARSED_RECORDS is off.
+	Unparsed []api.ParseError
+	// DomainFacts are the scanner's side observations, stored in domain_facts.
+	DomainFacts []api.DomainFacts
+	// Partial are the names with LOC answers that were not stored because
+	// they failed to parse or were rejected. Their stored records and
+	// unparsed entries are left alone, since the lookup only saw part of
+	// what the name publishes.
+	Partial []string
+}
+
+// settledLookups returns the names in resolved that are not in partial:
+// those whose every answer parsed, so their unparsed entries are obsolete.
+func settledLookups(resolved, partial []string) []string {
+	return slices.DeleteFunc(slices.Clone(resolved), func(fqdn string) bool {
+		return slices.Contains(partial, fqdn)
+	})
+}
+
+// BatchOutcome is what StoreBatchResults did.
+type BatchOutcome struct {
+	Accepted   int // Records stored
+	FileID     int
+	AssignedAt *time.Time // When the batch was leased, if known
+}
+
+// StoreBatchResults stores a batch's records, scan errors and side data and
+// completes the batch in one transaction, so a batch is either fully
+// reported or still leased. A record or the bookkeeping (scan errors,
+// unparsed records, domain facts) failing on its own is logged and rolled
+// back to a savepoint without failing the batch, as before; only completing
+// the batch itself is fatal.
+func (db *DB) StoreBatchResults(ctx context.Context, res BatchResults) (BatchOutcome, error) {
+	var out BatchOutcome
+	tx, err := db.Pool.Begin(ctx)
+	if err != nil {
+		return out, err
+	}
+	defer tx.Rollback(ctx) //nolint:errcheck
+
+	resolved := make([]string, 0, len(res.Records))
+	for _, group := range groupByFQDN(res.Records) {
+		recs := make([]api.LOCRecord, len(group))
+		for i, r := range group {
+			recs[i] = r.Record
+		}
+		var accepted int
+		err := withSavepoint(ctx, tx, func(sp pgx.Tx) error {
+			var err error
+			partial := slices.Contains(res.Partial, recs[0].FQDN)
+			accepted, err = replaceLOCRecords(ctx, sp, group[0].RootDomain, recs, res.KeepBestPrecision, res.ReplaceIfNewer, partial)
+			return err
+		})
+		if err != nil {
+			log.Printf("Failed to insert LOC records for %s: %v", recs[0].FQDN, err)
+			continue
+		}
+		out.Accepted += accepted
+		resolved = append(resolved, recs[0].FQDN)
+	}
+
+	// Scan error bookkeeping must not fail the batch
+	if err := withSavepoint(ctx, tx, func(sp pgx.Tx) error {
+		return recordScanErrors(ctx, sp, res.ScanErrors)
+	}); err != nil {
+		log.Printf("Failed to record %d scan errors for batch %d: %v", len(res.ScanErrors), res.BatchID, err)
+	}
+	if err := withSavepoint(ctx, tx, func(sp pgx.Tx) error {
+		return clearBatchScanErrors(ctx, sp, res.BatchID, res.ScanErrors)
+	}); err != nil {
+		log.Printf("Failed to clear scan errors for batch %d: %v", res.BatchID, err)
+	}
+	if err := withSavepoint(ctx, tx, func(sp pgx.Tx) error {
+		if err := recordUnparsed(ctx, sp, res.Unparsed); err != nil {
+			return err
+		}
+		return clearUnparsed(ctx, sp, settledLookups(resolved, res.Partial))
+	}); err != nil {
+		log.Printf("Failed to update unparsed records for batch %d: %v", res.BatchID, err)
+	}
+	if err := withSavepoint(ctx, tx, func(sp pgx.Tx) error {
+		return recordDomainFacts(ctx, sp, res.DomainFacts)
+	}); err != nil {
+		log.Printf("Failed to record %d domain facts for batch %d: %v", len(res.DomainFacts), res.BatchID, err)
+	}
+
+	if out.FileID, out.AssignedAt, err = completeBatch(ctx, tx, res.BatchID); err != nil {
+		return BatchOutcome{}, err
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return BatchOutcome{}, err
+	}
+	return out, nil
+}
+
+// withSavepoint runs fn in a savepoint of tx, rolling back only fn's
+// changes if it fails so the surrounding transaction stays usable.
+func withSavepoint(ctx context.Context, tx pgx.Tx, fn func(pgx.Tx) error) error {
+	sp, err := tx.Begin(ctx) // Nested Begin creates a savepoint
+	if err != nil {
+		return err
+	}
+	defer sp.Rollback(ctx) //nolint:errcheck // No-op after Commit
+	if err := fn(sp); err != nil {
+		return err
+	}
+	return sp.Commit(ctx)
+}
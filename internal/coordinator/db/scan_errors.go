@@ -0,0 +1,129 @@
+package db
+
+import (
+	"context"
+	"strings"
+
+	"github.com/locplace/scanner/pkg/api"
+)
+
+// recordScanErrors upserts failed lookups, incrementing scan_attempts for
+// FQDNs that failed before.
+func recordScanErrors(ctx context.Context, q querier, errs []api.ScanError) error {
+	if len(errs) == 0 {
+		return nil
+	}
+
+	fqdns := make([]string, len(errs))
+	classes := make([]string, len(errs))
+	messages := make([]string, len(errs))
+	for i, e := range errs {
+		fqdns[i], classes[i], messages[i] = e.FQDN, e.Class, e.Message
+	}
+
+	_, err := q.Exec(ctx, `
+		INSERT INTO scan_errors (fqdn, error_class, last_error)
+		SELECT DISTINCT ON (fqdn) fqdn, class, message
+		FROM unnest($1::text[], $2::text[], $3::text[]) AS e(fqdn, class, message)
+		ON CONFLICT (fqdn) DO UPDATE SET
+			error_class = EXCLUDED.error_class,
+			last_error = EXCLUDED.last_error,
+			scan_attempts = scan_errors.scan_attempts + 1,
+			last_failed_at = NOW()
+	`, fqdns, classes, messages)
+	return err
+}
+
+// clearBatchScanErrors removes the error entries of the names a batch
+// looked up without failing, whether or not they had a LOC record.
+func clearBatchScanErrors(ctx context.Context, q querier, batchID int64, errs []api.ScanError) error {
+	var domains string
+	err := q.QueryRow(ctx, `SELECT domains FROM scan_batches WHERE id = $1`, batchID).Scan(&domains)
+	if err != nil {
+		return err
+	}
+	return clearScanErrors(ctx, q, succeededLookups(domains, errs))
+}
+
+// succeededLookups returns the names of a batch's newline-separated domains
+// that have no scan error.
+func succeededLookups(domains string, errs []api.ScanError) []string {
+	failed := make(map[string]bool, len(errs))
+	for _, e := range errs {
+		failed[e.FQDN] = true
+	}
+	var ok []string
+	for _, d := range strings.Split(domains, "\n") {
+		if d = strings.TrimSpace(d); d != "" && !failed[d] {
+			ok = append(ok, d)
+		}
+	}
+	return ok
+}
+
+// clearScanErrors removes error entries for FQDNs that have since resolved.
+func clearScanErrors(ctx context.Context, q querier, fqdns []string) error {
+	if len(fqdns) == 0 {
+		return nil
+	}
+	_, err := q.Exec(ctx, `DELETE FROM scan_errors WHERE fqdn = ANY($1)`, fqdns)
+	return err
+}
+
+// ListScanErrors returns paginated scan errors, optionally filtered by class,
+// most recent first, plus the total matching count.
+func (db *DB) ListScanErrors(ctx context.Context, class string, limit, offset int) ([]api.ScanErrorInfo, int, error) {
+	var q queryBuilder
+	if class != "" {
+		q.conds = append(q.conds, "error_class = "+q.arg(class))
+	}
+	where := q.where()
+
+	var total int
+	if err := db.Pool.QueryRow(ctx, `SELECT COUNT(*) FROM scan_errors `+where, q.args...).Scan(&total); err != nil {
+		return nil, 0, err
+	}
+
+	rows, err := db.Pool.Query(ctx, `
+		SELECT fqdn, error_class, last_error, scan_attempts, first_failed_at, last_failed_at
+		FROM scan_errors
+		`+where+`
+		ORDER BY last_failed_at DESC
+		LIMIT `+q.arg(limit)+` OFFSET `+q.arg(offset), q.args...)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	var errs []api.ScanErrorInfo
+	for rows.Next() {
+		var e api.ScanErrorInfo
+		if err := rows.Scan(&e.FQDN, &e.Class, &e.LastError, &e.ScanAttempts, &e.FirstFailedAt, &e.LastFailedAt); err != nil {
+			return nil, 0, err
+		}
+		errs = append(errs, e)
+	}
+
+	return errs, total, rows.Err()
+}
+
+// CountScanErrorsByClass returns the number of failing FQDNs per error class.
+func (db *DB) CountScanErrorsByClass(ctx context.Context) (map[string]int, error) {
+	rows, err := db.Pool.Query(ctx, `SELECT error_class, COUNT(*) FROM scan_errors GROUP BY error_class`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	counts := make(map[string]int)
+	for rows.Next() {
+		var class string
+		var n int
+		if err := rows.Scan(&class, &n); err != nil {
+			return nil, err
+		}
+		counts[class] = n
+	}
+
+	return counts, rows.Err()
+}
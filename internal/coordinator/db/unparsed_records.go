@@ -0,0 +1,92 @@
+package db
+
+import (
+	"context"
+
+	"github.com/locplace/scanner/pkg/api"
+)
+
+// recordUnparsed upserts LOC answers that failed to parse, one entry per
+// name and raw string, keeping the latest error and counting repeats.
+func recordUnparsed(ctx context.Context, q querier, errs []api.ParseError) error {
+	if len(errs) == 0 {
+		return nil
+	}
+
+	fqdns := make([]string, len(errs))
+	raws := make([]string, len(errs))
+	messages := make([]string, len(errs))
+	for i, e := range errs {
+		fqdns[i], raws[i], messages[i] = e.FQDN, e.RawRecord, e.Message
+	}
+
+	_, err := q.Exec(ctx, `
+		INSERT INTO unparsed_records (fqdn, raw_record, parse_error)
+		SELECT DISTINCT ON (fqdn, raw) fqdn, raw, message
+		FROM unnest($1::text[], $2::text[], $3::text[]) AS e(fqdn, raw, message)
+		ON CONFLICT (fqdn, raw_record) DO UPDATE SET
+			parse_error = EXCLUDED.parse_error,
+			occurrences = unparsed_records.occurrences + 1,
+			last_seen_at = NOW()
+	`, fqdns, raws, messages)
+	return err
+}
+
+// clearUnparsed removes all entries of FQDNs whose lookups now parse.
+func clearUnparsed(ctx context.Context, q querier, fqdns []string) error {
+	if len(fqdns) == 0 {
+		return nil
+	}
+	_, err := q.Exec(ctx, `DELETE FROM unparsed_records WHERE fqdn = ANY($1)`, fqdns)
+	return err
+}
+
+// clearUnparsedAnswers removes the entries of single answers that now parse,
+// leaving other answers of the same names.
+func clearUnparsedAnswers(ctx context.Context, q querier, recs []api.LOCRecord) error {
+	if len(recs) == 0 {
+		return nil
+	}
+	fqdns := make([]string, len(recs))
+	raws := make([]string, len(recs))
+	for i, rec := range recs {
+		fqdns[i], raws[i] = rec.FQDN, rec.RawRecord
+	}
+	_, err := q.Exec(ctx, `
+		DELETE FROM unparsed_records u
+		USING unnest($1::text[], $2::text[]) AS a(fqdn, raw)
+		WHERE u.fqdn = a.fqdn AND u.raw_record = a.raw
+	`, fqdns, raws)
+	return err
+}
+
+// ListUnparsedRecords returns paginated unparseable LOC answers, most
+// recent first, plus the total count.
+func (db *DB) ListUnparsedRecords(ctx context.Context, limit, offset int) ([]api.UnparsedRecord, int, error) {
+	var total int
+	if err := db.Pool.QueryRow(ctx, `SELECT COUNT(*) FROM unparsed_records`).Scan(&total); err != nil {
+		return nil, 0, err
+	}
+
+	rows, err := db.Pool.Query(ctx, `
+		SELECT fqdn, raw_record, parse_error, occurrences, first_seen_at, last_seen_at
+		FROM unparsed_records
+		ORDER BY last_seen_at DESC, fqdn, raw_record
+		LIMIT $1 OFFSET $2
+	`, limit, offset)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	var records []api.UnparsedRecord
+	for rows.Next() {
+		var u api.UnparsedRecord
+		if err := rows.Scan(&u.FQDN, &u.RawRecord, &u.ParseError, &u.Occurrences, &u.FirstSeenAt, &u.LastSeenAt); err != nil {
+			return nil, 0, err
+		}
+		records = append(records, u)
+	}
+
+	return records, total, rows.Err()
+}
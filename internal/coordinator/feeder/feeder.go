@@ -15,6 +15,7 @@ import (
 	"github.com/ulikunitz/xz"
 
 	"github.com/locplace/scanner/internal/coordinator/db"
+	"github.com/locplace/scanner/internal/coordinator/tldfilter"
 )
 
 // Config holds feeder configuration.
@@ -33,6 +34,13 @@ type Config struct {
 	// Using a token allows downloads to count against your account's LFS quota
 	// instead of the repository owner's quota (which may be exceeded).
 	GitHubToken string
+
+	// TLDFilter skips domains outside the configured TLDs (nil = all).
+	TLDFilter *tldfilter.Filter
+
+	// Paused, if set, holds off creating batches while it returns true,
+	// e.g. during read-only maintenance.
+	Paused func() bool
 }
 
 // DefaultConfig returns sensible default configuration.
@@ -81,6 +89,11 @@ func (f *Feeder) Run(ctx context.Context) {
 		default:
 		}
 
+		if f.paused() {
+			time.Sleep(f.Config.PollInterval)
+			continue
+		}
+
 		// Get next file to process
 		file, err := f.DB.GetNextFileToProcess(ctx)
 		if err != nil {
@@ -139,6 +152,7 @@ func (f *Feeder) processFile(ctx context.Context, file *db.DomainFile) error {
 		batch      []string
 		batchStart int64
 		batchCount int
+		excluded   int
 		skipToLine = file.ProcessedLines
 	)
 
@@ -160,6 +174,10 @@ func (f *Feeder) processFile(ctx context.Context, file *db.DomainFile) error {
 		if line == "" || strings.HasPrefix(line, "#") {
 			continue
 		}
+		if !f.Config.TLDFilter.Allows(line) {
+			excluded++
+			continue
+		}
 
 		// Start a new batch if needed
 		if len(batch) == 0 {
@@ -196,6 +214,9 @@ func (f *Feeder) processFile(ctx context.Context, file *db.DomainFile) error {
 	}
 
 	log.Printf("Feeder: %s feeding done: %d batches created", file.Filename, batchCount)
+	if excluded > 0 {
+		log.Printf("Feeder: %s: skipped %d domains outside the TLD filter", file.Filename, excluded)
+	}
 
 	// Mark feeding complete now that we've read all lines
 	if markErr := f.DB.MarkFeedingComplete(ctx, file.ID); markErr != nil {
@@ -216,6 +237,11 @@ func (f *Feeder) processFile(ctx context.Context, file *db.DomainFile) error {
 	return nil
 }
 
+// paused reports whether batch creation is currently on hold.
+func (f *Feeder) paused() bool {
+	return f.Config.Paused != nil && f.Config.Paused()
+}
+
 // insertBatch waits for queue capacity and inserts a batch.
 func (f *Feeder) insertBatch(ctx context.Context, fileID int, lineStart, lineEnd int64, domains []string) error {
 	// Wait for queue capacity
@@ -231,11 +257,11 @@ func (f *Feeder) insertBatch(ctx context.Context, fileID int, lineStart, lineEnd
 			return fmt.Errorf("get pending count: %w", err)
 		}
 
-		if pending < f.Config.MaxPendingBatches {
+		if pending < f.Config.MaxPendingBatches && !f.paused() {
 			break
 		}
 
-		// Queue is full, wait
+		// Queue is full or feeding is paused, wait
 		time.Sleep(f.Config.PollInterval)
 	}
 
@@ -2,22 +2,66 @@
 package handlers
 
 import (
+	"compress/gzip"
 	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
 	"net/http"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
 	"strings"
 	"time"
 
 	"github.com/go-chi/chi/v5"
+	"golang.org/x/net/publicsuffix"
 
 	"github.com/locplace/scanner/internal/coordinator/db"
 	"github.com/locplace/scanner/internal/coordinator/feeder"
+	"github.com/locplace/scanner/internal/coordinator/metrics"
+	"github.com/locplace/scanner/internal/coordinator/middleware"
+	"github.com/locplace/scanner/internal/coordinator/tldfilter"
 	"github.com/locplace/scanner/pkg/api"
+	"github.com/locplace/scanner/pkg/loc"
 )
 
 // AdminHandlers contains handlers for admin endpoints.
 type AdminHandlers struct {
 	DB               *db.DB
 	HeartbeatTimeout time.Duration
+	Maintenance      *middleware.Maintenance
+	StatsCache       *StatsCache // Purged after bulk changes; nil if stats aren't cached
+	// StaleAfter is the is_stale threshold, as in PublicHandlers.
+	StaleAfter time.Duration
+	// BaseURL is the public base URL for Link headers, as in PublicHandlers.
+	BaseURL string
+	// TLDFilter and MaxHorizPrecM are the submission filters, as in
+	// ScannerHandlers; Reparse applies them to records it recovers.
+	TLDFilter     *tldfilter.Filter
+	MaxHorizPrecM float64
+}
+
+// PurgeCache handles POST /api/admin/cache/purge.
+// Drops cached public stats so they reflect known big changes immediately
+// instead of after STATS_CACHE_TTL.
+func (h *AdminHandlers) PurgeCache(w http.ResponseWriter, r *http.Request) {
+	resp := api.CachePurgeResponse{Purged: []string{}}
+	if h.StatsCache.Purge() {
+		resp.Purged = append(resp.Purged, "stats")
+	}
+	metrics.CachePurgesTotal.WithLabelValues("manual").Inc()
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// purgeStats drops cached stats after an admin action that changes them in
+// bulk.
+func (h *AdminHandlers) purgeStats() {
+	if h.StatsCache.Purge() {
+		metrics.CachePurgesTotal.WithLabelValues("auto").Inc()
+	}
 }
 
 // RegisterClient handles POST /api/admin/clients.
@@ -33,12 +77,17 @@ func (h *AdminHandlers) RegisterClient(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	id, token, err := h.DB.CreateClient(r.Context(), req.Name)
+	id, token, err := h.DB.CreateClient(r.Context(), req.Name, strings.TrimSpace(req.CertIdentity))
+	if errors.Is(err, db.ErrCertIdentityTaken) {
+		writeError(w, err.Error(), http.StatusConflict)
+		return
+	}
 	if err != nil {
 		writeError(w, "failed to create client", http.StatusInternalServerError)
 		return
 	}
 
+	h.audit(r, db.AuditClientCreate, id, map[string]string{"name": req.Name, "cert_identity": strings.TrimSpace(req.CertIdentity)})
 	writeJSON(w, http.StatusCreated, api.RegisterClientResponse{
 		ID:    id,
 		Name:  req.Name,
@@ -61,14 +110,22 @@ func (h *AdminHandlers) ListClients(w http.ResponseWriter, r *http.Request) {
 
 	for _, c := range clients {
 		isAlive := c.LastHeartbeat != nil && now.Sub(*c.LastHeartbeat) < h.HeartbeatTimeout
-		resp.Clients = append(resp.Clients, api.ClientInfo{
+		info := api.ClientInfo{
 			ID:            c.ID,
 			Name:          c.Name,
 			CreatedAt:     c.CreatedAt,
 			LastHeartbeat: c.LastHeartbeat,
 			ActiveBatches: c.ActiveBatches,
 			IsAlive:       isAlive,
-		})
+			Capabilities:  c.Capabilities,
+		}
+		if c.Version != nil {
+			info.Version = *c.Version
+		}
+		if c.CertIdentity != nil {
+			info.CertIdentity = *c.CertIdentity
+		}
+		resp.Clients = append(resp.Clients, info)
 	}
 
 	writeJSON(w, http.StatusOK, resp)
@@ -88,9 +145,328 @@ func (h *AdminHandlers) DeleteClient(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	h.audit(r, db.AuditClientDelete, id, nil)
 	w.WriteHeader(http.StatusNoContent)
 }
 
+// PruneClients handles POST /api/admin/clients/prune.
+// Deletes clients that have not sent a heartbeat within older_than. The
+// threshold may not be shorter than the heartbeat timeout, so live clients
+// are never pruned.
+func (h *AdminHandlers) PruneClients(w http.ResponseWriter, r *http.Request) {
+	var req api.PruneClientsRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, "invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	olderThan, err := parsePruneThreshold(req.OlderThan, h.HeartbeatTimeout)
+	if err != nil {
+		writeError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	ids, err := h.DB.PruneClients(r.Context(), olderThan, req.DryRun)
+	if err != nil {
+		writeError(w, "failed to prune clients", http.StatusInternalServerError)
+		return
+	}
+	if !req.DryRun && len(ids) > 0 {
+		h.audit(r, db.AuditClientsPrune, "", map[string]any{"older_than": olderThan.String(), "client_ids": ids})
+	}
+
+	writeJSON(w, http.StatusOK, api.PruneClientsResponse{
+		ClientIDs: ids,
+		DryRun:    req.DryRun,
+	})
+}
+
+// GetMaintenance handles GET /api/admin/maintenance.
+func (h *AdminHandlers) GetMaintenance(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, api.MaintenanceMode{Enabled: h.Maintenance.Enabled()})
+}
+
+// Reparse handles POST /api/admin/reparse.
+// Re-runs the current LOC parser over the raw strings of stored records and
+// of unparsed answers, so parser fixes apply without a rescan. Stored
+// records whose parsed fields differ are updated; unparsed answers that now
+// parse are stored as records, unless the TLD allowlist or precision floor
+// rejects them as it would a submission. Stored records the parser now
+// rejects are counted but left alone. Work is done in chunks of
+// reparseChunkSize: each is read, then written in its own transaction, so a
+// large table neither builds one huge update nor loses everything if the
+// request is cut short, and only one connection is used at a time.
+func (h *AdminHandlers) Reparse(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	var resp api.ReparseResponse
+
+	// Each page is read in full before its updates are written, so no
+	// cursor is held open while the rows it reads are rewritten
+	var lastFQDN, lastRaw string
+	for {
+		page, err := h.DB.ListLOCRecordsAfter(ctx, lastFQDN, lastRaw, reparseChunkSize)
+		if err != nil {
+			writeError(w, "failed to read records", http.StatusInternalServerError)
+			return
+		}
+		var changed []api.LOCRecord
+		for _, stored := range page {
+			resp.Checked++
+			rec, err := reparseRecord(stored.FQDN, stored.RawRecord)
+			switch {
+			case err != nil:
+				resp.Failing++
+			case !sameParsedFields(*rec, stored.Record()):
+				changed = append(changed, *rec)
+			}
+		}
+		n, err := h.DB.UpdateParsedFields(ctx, changed)
+		if err != nil {
+			writeError(w, "failed to update records", http.StatusInternalServerError)
+			return
+		}
+		resp.Changed += n
+		extendWriteDeadline(w)
+		if len(page) < reparseChunkSize {
+			break
+		}
+		lastFQDN, lastRaw = page[len(page)-1].FQDN, page[len(page)-1].RawRecord
+	}
+
+	// Fixed and rejected answers leave unparsed_records, so only the ones
+	// still failing move the offset
+	for offset := 0; ; {
+		page, _, err := h.DB.ListUnparsedRecords(ctx, reparseChunkSize, offset)
+		if err != nil {
+			writeError(w, "failed to read unparsed records", http.StatusInternalServerError)
+			return
+		}
+		var recs []api.LOCRecord
+		for _, u := range page {
+			rec, err := reparseRecord(u.FQDN, u.RawRecord)
+			if err != nil {
+				resp.StillFailing++
+				offset++
+				continue
+			}
+			rec.ObservedAt = &u.LastSeenAt
+			recs = append(recs, *rec)
+		}
+		kept, _ := h.filterFixed(slices.Clone(recs))
+		rejected := droppedAnswers(recs, kept)
+		fixed := make([]db.BatchRecord, len(kept))
+		for i, rec := range kept {
+			fixed[i] = db.BatchRecord{RootDomain: rootDomainOf(rec.FQDN), Record: rec}
+		}
+		if err := h.DB.StoreFixedUnparsed(ctx, fixed, rejected); err != nil {
+			writeError(w, "failed to store fixed records", http.StatusInternalServerError)
+			return
+		}
+		resp.Fixed += len(fixed)
+		resp.Rejected += len(rejected)
+		extendWriteDeadline(w)
+		if len(page) < reparseChunkSize {
+			break
+		}
+	}
+	h.purgeStats()
+	h.audit(r, db.AuditRecordsReparse, "", resp)
+
+	log.Printf("Reparse: %d records checked, %d changed, %d failing; %d unparsed fixed, %d rejected, %d still failing",
+		resp.Checked, resp.Changed, resp.Failing, resp.Fixed, resp.Rejected, resp.StillFailing)
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// reparseChunkSize is how many records Reparse reads and writes at a time.
+const reparseChunkSize = 1000
+
+// droppedAnswers returns the records of all that are not in kept, matching
+// them by name and raw record.
+func droppedAnswers(all, kept []api.LOCRecord) []api.LOCRecord {
+	type answer struct{ fqdn, raw string }
+	keep := make(map[answer]bool, len(kept))
+	for _, rec := range kept {
+		keep[answer{rec.FQDN, rec.RawRecord}] = true
+	}
+	var dropped []api.LOCRecord
+	for _, rec := range all {
+		if !keep[answer{rec.FQDN, rec.RawRecord}] {
+			dropped = append(dropped, rec)
+		}
+	}
+	return dropped
+}
+
+// filterFixed applies the submission filters, the TLD allowlist and the
+// precision floor, to unparsed answers that now parse. It returns the
+// records to store and the rejected ones.
+func (h *AdminHandlers) filterFixed(recs []api.LOCRecord) ([]api.LOCRecord, []api.RejectedRecord) {
+	filters := &ScannerHandlers{TLDFilter: h.TLDFilter, MaxHorizPrecM: h.MaxHorizPrecM}
+	req := api.SubmitBatchRequest{LOCRecords: recs}
+	rejected := filters.filterTLDs(&req)
+	kept, imprecise := filters.filterImprecise(req.LOCRecords)
+	return kept, append(rejected, imprecise...)
+}
+
+// reparseRecord parses raw with the current parser, as scanners do, and
+// validates the result as submissions are.
+func reparseRecord(fqdn, raw string) (*api.LOCRecord, error) {
+	rec, err := loc.ParseLenient(fqdn, raw)
+	if err != nil {
+		return nil, err
+	}
+	if err := rec.Validate(); err != nil {
+		return nil, err
+	}
+	rec.RawRecord = raw // Keep the stored key, even if the parser trims it
+	return rec, nil
+}
+
+// sameParsedFields reports whether a and b have the same parsed values.
+func sameParsedFields(a, b api.LOCRecord) bool {
+	return a.Latitude == b.Latitude && a.Longitude == b.Longitude && a.AltitudeM == b.AltitudeM &&
+		a.SizeM == b.SizeM && a.HorizPrecM == b.HorizPrecM && a.VertPrecM == b.VertPrecM
+}
+
+// UpdateRecord handles PATCH /api/admin/records/{fqdn}.
+// Corrects the root domain a name's records are attributed to, e.g. after a
+// public suffix list fix, and logs the change in the audit log. Coordinates
+// and precision can't be edited; they only change by scanning.
+func (h *AdminHandlers) UpdateRecord(w http.ResponseWriter, r *http.Request) {
+	fqdn := strings.ToLower(strings.TrimSuffix(chi.URLParam(r, "fqdn"), "."))
+
+	var fields map[string]json.RawMessage
+	if err := json.NewDecoder(r.Body).Decode(&fields); err != nil {
+		writeError(w, "invalid request body", http.StatusBadRequest)
+		return
+	}
+	rootDomain, err := parseRecordUpdate(fqdn, fields)
+	if err != nil {
+		writeError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	previous, err := h.DB.UpdateRootDomain(r.Context(), fqdn, rootDomain, middleware.GetAdminKeyID(r.Context()))
+	if err != nil {
+		writeError(w, "failed to update record", http.StatusInternalServerError)
+		return
+	}
+	records, err := h.DB.GetLOCRecordsByFQDN(r.Context(), fqdn)
+	if err != nil {
+		writeError(w, "failed to get record", http.StatusInternalServerError)
+		return
+	}
+	if len(records) == 0 {
+		writeError(w, "record not found", http.StatusNotFound)
+		return
+	}
+	if len(previous) > 0 {
+		log.Printf("Record %s: root domain changed from %s to %s", fqdn, strings.Join(previous, ", "), rootDomain)
+		h.purgeStats()
+	}
+	setFreshness(records, h.StaleAfter)
+	writeJSON(w, http.StatusOK, api.RecordDetailResponse{FQDN: fqdn, Records: records})
+}
+
+// parseRecordUpdate validates a record update for fqdn and returns the new
+// root domain. It must be fqdn or one of its parents, and not a public
+// suffix. Any other field is rejected.
+func parseRecordUpdate(fqdn string, fields map[string]json.RawMessage) (string, error) {
+	var others []string
+	for name := range fields {
+		if name != "root_domain" {
+			others = append(others, name)
+		}
+	}
+	if len(others) > 0 {
+		slices.Sort(others)
+		return "", fmt.Errorf("only root_domain can be updated; LOC fields come from scanning (got %s)", strings.Join(others, ", "))
+	}
+	raw, ok := fields["root_domain"]
+	if !ok {
+		return "", errors.New("root_domain is required")
+	}
+	var req api.UpdateRecordRequest
+	if err := json.Unmarshal(raw, &req.RootDomain); err != nil {
+		return "", errors.New("root_domain must be a string")
+	}
+
+	rootDomain := strings.ToLower(strings.TrimSuffix(req.RootDomain, "."))
+	if rootDomain == "" {
+		return "", errors.New("root_domain is required")
+	}
+	if fqdn != rootDomain && !strings.HasSuffix(fqdn, "."+rootDomain) {
+		return "", fmt.Errorf("root_domain must be %s or one of its parent domains", fqdn)
+	}
+	if suffix, _ := publicsuffix.PublicSuffix(rootDomain); suffix == rootDomain {
+		return "", fmt.Errorf("root_domain %s is a public suffix", rootDomain)
+	}
+	return rootDomain, nil
+}
+
+// GetSchemaVersion handles GET /api/admin/schema.
+// Reports the applied migration version next to the one this build expects.
+func (h *AdminHandlers) GetSchemaVersion(w http.ResponseWriter, r *http.Request) {
+	version, dirty, err := h.DB.SchemaVersion(r.Context())
+	if err != nil {
+		writeError(w, "failed to get schema version", http.StatusInternalServerError)
+		return
+	}
+	latest, err := db.LatestMigration()
+	if err != nil {
+		writeError(w, "failed to read migrations", http.StatusInternalServerError)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, api.SchemaVersion{
+		Version:  version,
+		Dirty:    dirty,
+		Latest:   latest,
+		UpToDate: version == latest && !dirty,
+	})
+}
+
+// SetMaintenance handles PUT /api/admin/maintenance.
+// Turns read-only maintenance mode on or off.
+func (h *AdminHandlers) SetMaintenance(w http.ResponseWriter, r *http.Request) {
+	var req api.MaintenanceMode
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, "invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	h.Maintenance.Set(req.Enabled)
+	SetMaintenanceMetric(req.Enabled)
+	log.Printf("Maintenance mode enabled=%t", req.Enabled)
+	h.audit(r, db.AuditMaintenanceSet, "", req)
+
+	writeJSON(w, http.StatusOK, req)
+}
+
+// SetMaintenanceMetric reports the maintenance mode to Prometheus.
+func SetMaintenanceMetric(enabled bool) {
+	v := 0.0
+	if enabled {
+		v = 1
+	}
+	metrics.MaintenanceMode.Set(v)
+}
+
+// parsePruneThreshold parses older_than and checks it against the heartbeat timeout.
+func parsePruneThreshold(s string, heartbeatTimeout time.Duration) (time.Duration, error) {
+	if s == "" {
+		return 0, errors.New("older_than is required")
+	}
+	d, err := time.ParseDuration(s)
+	if err != nil {
+		return 0, errors.New("older_than must be a duration such as 720h")
+	}
+	if d < heartbeatTimeout {
+		return 0, fmt.Errorf("older_than must be at least the heartbeat timeout (%s)", heartbeatTimeout)
+	}
+	return d, nil
+}
+
 // DiscoverFiles handles POST /api/admin/discover-files.
 // Fetches the domain file list from GitHub and updates the database.
 func (h *AdminHandlers) DiscoverFiles(w http.ResponseWriter, r *http.Request) {
@@ -100,6 +476,8 @@ func (h *AdminHandlers) DiscoverFiles(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	h.purgeStats()
+	h.audit(r, db.AuditFilesDiscover, "", map[string]int{"files_discovered": count})
 	writeJSON(w, http.StatusOK, api.DiscoverFilesResponse{
 		FilesDiscovered: count,
 	})
@@ -121,16 +499,192 @@ func (h *AdminHandlers) ResetScan(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	h.purgeStats()
+	h.audit(r, db.AuditScanReset, "", map[string]int{"files_reset": fileStats.Total})
 	writeJSON(w, http.StatusOK, api.ResetScanResponse{
 		FilesReset: fileStats.Total,
 	})
 }
 
+// RescanFile handles POST /api/admin/files/{id}/rescan.
+// Resets a single domain file to pending so the feeder re-queues its domains,
+// e.g. after the upstream file was refreshed.
+func (h *AdminHandlers) RescanFile(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		writeError(w, "invalid file id", http.StatusBadRequest)
+		return
+	}
+
+	domains, found, err := h.DB.ResetFile(r.Context(), id)
+	if errors.Is(err, db.ErrFileProcessing) {
+		writeError(w, "file is still being processed", http.StatusConflict)
+		return
+	}
+	if err != nil {
+		writeError(w, "failed to reset file", http.StatusInternalServerError)
+		return
+	}
+	if !found {
+		writeError(w, "file not found", http.StatusNotFound)
+		return
+	}
+
+	metrics.FileRescansTotal.Inc()
+	h.purgeStats()
+	h.audit(r, db.AuditFileRescan, strconv.Itoa(id), map[string]int64{"domains_reset": domains})
+	writeJSON(w, http.StatusOK, api.RescanFileResponse{
+		FileID:       id,
+		DomainsReset: domains,
+	})
+}
+
+// ExportSQLite handles POST /api/admin/export/sqlite.
+// Builds a SQLite database of all records (a loc_records table, indexed on
+// root_domain and coordinates) in a temporary file and sends it once it is
+// complete, so a failed export is an error response rather than a truncated
+// file.
+func (h *AdminHandlers) ExportSQLite(w http.ResponseWriter, r *http.Request) {
+	dir, err := os.MkdirTemp("", "locplace-export-")
+	if err != nil {
+		writeError(w, "failed to create export", http.StatusInternalServerError)
+		return
+	}
+	defer os.RemoveAll(dir) //nolint:errcheck
+	path := filepath.Join(dir, "locplace.db")
+
+	extendWriteDeadline(w)
+	export, err := newSQLiteExport(r.Context(), path)
+	if err != nil {
+		log.Printf("SQLite export: %v", err)
+		writeError(w, "failed to create export", http.StatusInternalServerError)
+		return
+	}
+	count := 0
+	err = h.DB.StreamLOCRecords(r.Context(), db.RecordFilter{}, func(rec api.PublicLOCRecord) error {
+		count++
+		if count%1000 == 0 {
+			extendWriteDeadline(w)
+		}
+		return export.add(r.Context(), rec)
+	})
+	if err == nil {
+		err = export.finish(r.Context())
+	} else {
+		export.close()
+	}
+	if err != nil {
+		log.Printf("SQLite export aborted after %d records: %v", count, err)
+		writeError(w, "failed to export records", http.StatusInternalServerError)
+		return
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		writeError(w, "failed to read export", http.StatusInternalServerError)
+		return
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		writeError(w, "failed to read export", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/vnd.sqlite3")
+	w.Header().Set("Content-Disposition", `attachment; filename="locplace.db"`)
+	w.Header().Set("Content-Length", strconv.FormatInt(info.Size(), 10))
+	w.WriteHeader(http.StatusOK)
+	extendWriteDeadline(w)
+	if _, err := io.Copy(w, f); err != nil {
+		log.Printf("SQLite export: sending %d records failed: %v", count, err)
+	}
+}
+
+// ListScanErrors handles GET /api/admin/scan-errors.
+// Lists FQDNs whose lookups failed, optionally filtered by ?class=, with
+// counts per error class.
+func (h *AdminHandlers) ListScanErrors(w http.ResponseWriter, r *http.Request) {
+	class := r.URL.Query().Get("class")
+	if class != "" && !slices.Contains(api.ScanErrorClasses, class) {
+		writeError(w, "class must be one of "+strings.Join(api.ScanErrorClasses, ", "), http.StatusBadRequest)
+		return
+	}
+
+	limit := parseIntParam(r, "limit", 100)
+	offset := parseIntParam(r, "offset", 0)
+	if limit > 1000 {
+		limit = 1000
+	}
+
+	errs, total, err := h.DB.ListScanErrors(r.Context(), class, limit, offset)
+	if err != nil {
+		writeError(w, "failed to list scan errors", http.StatusInternalServerError)
+		return
+	}
+	byClass, err := h.DB.CountScanErrorsByClass(r.Context())
+	if err != nil {
+		writeError(w, "failed to count scan errors", http.StatusInternalServerError)
+		return
+	}
+
+	if errs == nil {
+		errs = []api.ScanErrorInfo{}
+	}
+
+	pagination := api.NewPagination(total, limit, offset, len(errs))
+	setPaginationLinks(w, r, h.baseURL(r), pagination)
+	writeJSON(w, http.StatusOK, api.ListScanErrorsResponse{
+		Errors:     errs,
+		ByClass:    byClass,
+		Pagination: pagination,
+	})
+}
+
+// ListUnparsedRecords handles GET /api/admin/unparsed-records.
+// Lists LOC answers that failed to parse, with the raw string and error.
+func (h *AdminHandlers) ListUnparsedRecords(w http.ResponseWriter, r *http.Request) {
+	limit := parseIntParam(r, "limit", 100)
+	offset := parseIntParam(r, "offset", 0)
+	if limit > 1000 {
+		limit = 1000
+	}
+
+	records, total, err := h.DB.ListUnparsedRecords(r.Context(), limit, offset)
+	if err != nil {
+		writeError(w, "failed to list unparsed records", http.StatusInternalServerError)
+		return
+	}
+
+	if records == nil {
+		records = []api.UnparsedRecord{}
+	}
+
+	pagination := api.NewPagination(total, limit, offset, len(records))
+	setPaginationLinks(w, r, h.baseURL(r), pagination)
+	writeJSON(w, http.StatusOK, api.ListUnparsedRecordsResponse{
+		Records:    records,
+		Pagination: pagination,
+	})
+}
+
 // ManualScan handles POST /api/admin/manual-scan.
 // Queues a list of domains for scanning as a single batch.
+// The body may be gzip-compressed (Content-Encoding: gzip).
 func (h *AdminHandlers) ManualScan(w http.ResponseWriter, r *http.Request) {
+	body, err := decodedBody(w, r, maxImportBodyBytes)
+	if err != nil {
+		writeError(w, "invalid gzip body", http.StatusBadRequest)
+		return
+	}
+	defer body.Close()
+
 	var req api.ManualScanRequest
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+	if err := json.NewDecoder(body).Decode(&req); err != nil {
+		var maxErr *http.MaxBytesError
+		if errors.As(err, &maxErr) {
+			writeError(w, "request body too large", http.StatusRequestEntityTooLarge)
+			return
+		}
 		writeError(w, "invalid request body", http.StatusBadRequest)
 		return
 	}
@@ -161,6 +715,8 @@ func (h *AdminHandlers) ManualScan(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	h.purgeStats()
+	h.audit(r, db.AuditScanManual, "", map[string]int{"domains_queued": len(cleanDomains)})
 	writeJSON(w, http.StatusOK, api.ManualScanResponse{
 		DomainsQueued: len(cleanDomains),
 	})
@@ -168,6 +724,34 @@ func (h *AdminHandlers) ManualScan(w http.ResponseWriter, r *http.Request) {
 
 // Helper functions
 
+// maxImportBodyBytes caps the (decompressed) size of domain import bodies.
+const maxImportBodyBytes = 256 << 20
+
+// decodedBody returns the request body, transparently decompressing it when
+// Content-Encoding is gzip. The decompressed stream is capped at maxBytes to
+// guard against decompression bombs; reading past it fails with *http.MaxBytesError.
+// Closing it closes the gzip reader and the request body.
+func decodedBody(w http.ResponseWriter, r *http.Request, maxBytes int64) (io.ReadCloser, error) {
+	if !strings.EqualFold(r.Header.Get("Content-Encoding"), "gzip") {
+		return http.MaxBytesReader(w, r.Body, maxBytes), nil
+	}
+	gz, err := gzip.NewReader(r.Body)
+	if err != nil {
+		return nil, err
+	}
+	return gzipBody{ReadCloser: http.MaxBytesReader(w, gz, maxBytes), body: r.Body}, nil
+}
+
+// gzipBody is a capped gzip stream that also closes the compressed body.
+type gzipBody struct {
+	io.ReadCloser // Closes the gzip reader
+	body          io.Closer
+}
+
+func (b gzipBody) Close() error {
+	return errors.Join(b.ReadCloser.Close(), b.body.Close())
+}
+
 func writeJSON(w http.ResponseWriter, status int, v any) {
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(status)
@@ -177,3 +761,14 @@ func writeJSON(w http.ResponseWriter, status int, v any) {
 func writeError(w http.ResponseWriter, message string, status int) {
 	writeJSON(w, status, api.ErrorResponse{Error: message})
 }
+
+// NotFound writes a JSON 404. It handles unknown /api/ paths so they don't
+// fall through to the frontend's index.html.
+func NotFound(w http.ResponseWriter, r *http.Request) {
+	writeError(w, "not found", http.StatusNotFound)
+}
+
+// MethodNotAllowed writes a JSON 405 for known API paths.
+func MethodNotAllowed(w http.ResponseWriter, r *http.Request) {
+	writeError(w, "method not allowed", http.StatusMethodNotAllowed)
+}
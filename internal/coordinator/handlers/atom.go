@@ -0,0 +1,71 @@
+package handlers
+
+import (
+	"encoding/xml"
+	"fmt"
+	"time"
+
+	"github.com/locplace/scanner/pkg/api"
+)
+
+// atomFeed is a minimal Atom (RFC 4287) feed document.
+type atomFeed struct {
+	XMLName xml.Name    `xml:"http://www.w3.org/2005/Atom feed"`
+	ID      string      `xml:"id"`
+	Title   string      `xml:"title"`
+	Updated string      `xml:"updated"`
+	Author  atomAuthor  `xml:"author"`
+	Links   []atomLink  `xml:"link"`
+	Entries []atomEntry `xml:"entry"`
+}
+
+type atomAuthor struct {
+	Name string `xml:"name"`
+}
+
+type atomLink struct {
+	Rel  string `xml:"rel,attr,omitempty"`
+	Type string `xml:"type,attr,omitempty"`
+	Href string `xml:"href,attr"`
+}
+
+type atomEntry struct {
+	ID      string     `xml:"id"`
+	Title   string     `xml:"title"`
+	Updated string     `xml:"updated"`
+	Links   []atomLink `xml:"link"`
+	Summary string     `xml:"summary"`
+}
+
+// buildAtomFeed renders records (newest first) as an Atom feed. baseURL is the
+// scheme and host the feed is served from, used for the feed and entry IDs.
+func buildAtomFeed(baseURL, host string, records []api.PublicLOCRecord) atomFeed {
+	feed := atomFeed{
+		ID:      baseURL + "/api/public/records/feed.atom",
+		Title:   "Newly discovered DNS LOC records",
+		Updated: time.Unix(0, 0).UTC().Format(time.RFC3339),
+		Author:  atomAuthor{Name: "locplace"},
+		Links: []atomLink{
+			{Rel: "self", Type: "application/atom+xml", Href: baseURL + "/api/public/records/feed.atom"},
+			{Rel: "alternate", Type: "text/html", Href: baseURL + "/"},
+		},
+		Entries: make([]atomEntry, 0, len(records)),
+	}
+	if len(records) > 0 {
+		feed.Updated = records[0].FirstSeenAt.UTC().Format(time.RFC3339)
+	}
+
+	for _, rec := range records {
+		mapURL := fmt.Sprintf("https://www.openstreetmap.org/?mlat=%.6f&mlon=%.6f#map=14/%.6f/%.6f",
+			rec.Latitude, rec.Longitude, rec.Latitude, rec.Longitude)
+		feed.Entries = append(feed.Entries, atomEntry{
+			// tag: URIs (RFC 4151) stay stable as long as the record is not re-discovered
+			ID:      fmt.Sprintf("tag:%s,%s:%s", host, rec.FirstSeenAt.UTC().Format("2006-01-02"), rec.FQDN),
+			Title:   rec.FQDN,
+			Updated: rec.FirstSeenAt.UTC().Format(time.RFC3339),
+			Links:   []atomLink{{Rel: "alternate", Type: "text/html", Href: mapURL}},
+			Summary: fmt.Sprintf("%.6f, %.6f (altitude %.2fm): %s", rec.Latitude, rec.Longitude, rec.AltitudeM, rec.RawRecord),
+		})
+	}
+	return feed
+}
@@ -0,0 +1,65 @@
+package handlers
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/locplace/scanner/internal/coordinator/db"
+	"github.com/locplace/scanner/internal/coordinator/middleware"
+	"github.com/locplace/scanner/pkg/api"
+)
+
+// audit records an admin action made by r in the audit log. The action has
+// already taken effect, so the entry is best-effort: a failure is logged
+// rather than returned, and the write outlives a client that disconnects
+// meanwhile. Record updates are the exception; UpdateRootDomain writes their
+// entry in the same transaction as the change.
+func (h *AdminHandlers) audit(r *http.Request, action, target string, details any) {
+	err := h.DB.InsertAuditEntry(context.WithoutCancel(r.Context()), db.AuditEntry{
+		Action:  action,
+		Target:  target,
+		Details: details,
+		Actor:   middleware.GetAdminKeyID(r.Context()),
+	})
+	if err != nil {
+		log.Printf("Failed to write audit entry %s %s: %v", action, target, err)
+	}
+}
+
+// ListAudit handles GET /api/admin/audit.
+// Lists administrative changes oldest first. since (RFC 3339) only returns
+// entries made after it; limit and offset page through the rest.
+func (h *AdminHandlers) ListAudit(w http.ResponseWriter, r *http.Request) {
+	var since time.Time
+	if s := r.URL.Query().Get("since"); s != "" {
+		var err error
+		if since, err = time.Parse(time.RFC3339Nano, s); err != nil {
+			writeError(w, "since must be an RFC 3339 timestamp", http.StatusBadRequest)
+			return
+		}
+	}
+
+	limit := parseIntParam(r, "limit", 100)
+	offset := parseIntParam(r, "offset", 0)
+	if limit > 1000 {
+		limit = 1000
+	}
+
+	entries, total, err := h.DB.ListAuditEntries(r.Context(), since, limit, offset)
+	if err != nil {
+		writeError(w, "failed to list audit log", http.StatusInternalServerError)
+		return
+	}
+	if entries == nil {
+		entries = []api.AuditEntry{}
+	}
+
+	pagination := api.NewPagination(total, limit, offset, len(entries))
+	setPaginationLinks(w, r, h.baseURL(r), pagination)
+	writeJSON(w, http.StatusOK, api.ListAuditResponse{
+		Entries:    entries,
+		Pagination: pagination,
+	})
+}
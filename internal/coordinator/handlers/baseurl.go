@@ -0,0 +1,71 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// ParseBaseURL validates a configured public base URL such as
+// https://loc.place or https://example.org/locplace and returns it without
+// a trailing slash, ready to have paths appended.
+func ParseBaseURL(s string) (string, error) {
+	u, err := url.Parse(s)
+	if err != nil {
+		return "", err
+	}
+	if (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return "", fmt.Errorf("must be an absolute http or https URL")
+	}
+	if u.RawQuery != "" || u.Fragment != "" || u.User != nil {
+		return "", fmt.Errorf("must not have a query, fragment or user info")
+	}
+	return strings.TrimSuffix(u.String(), "/"), nil
+}
+
+// baseURL returns the scheme, host and path prefix for absolute links in
+// feeds, sitemaps, robots.txt and Link headers: the configured BaseURL, or
+// else the address the request was made to.
+func (h *PublicHandlers) baseURL(r *http.Request) string {
+	return resolveBaseURL(h.BaseURL, r)
+}
+
+// baseURL is PublicHandlers.baseURL for the admin API's Link headers.
+func (h *AdminHandlers) baseURL(r *http.Request) string {
+	return resolveBaseURL(h.BaseURL, r)
+}
+
+// resolveBaseURL returns configured if set, else requestBaseURL(r).
+func resolveBaseURL(configured string, r *http.Request) string {
+	if configured != "" {
+		return configured
+	}
+	return requestBaseURL(r)
+}
+
+// requestBaseURL returns the scheme and host the request was made to.
+// Behind a trusted proxy, middleware.ForwardedHost has already applied the
+// forwarded host and scheme. Otherwise X-Forwarded-Proto: https is still
+// honored from any peer, as it was before TRUSTED_PROXIES covered it, so
+// TLS-terminating proxies keep getting https links; it can only upgrade
+// the scheme, never change the host.
+func requestBaseURL(r *http.Request) string {
+	scheme := r.URL.Scheme
+	if scheme == "" {
+		scheme = "http"
+		if r.TLS != nil || strings.EqualFold(strings.TrimSpace(r.Header.Get("X-Forwarded-Proto")), "https") {
+			scheme = "https"
+		}
+	}
+	return scheme + "://" + r.Host
+}
+
+// hostOf returns the host of a base URL, for tag: URIs.
+func hostOf(baseURL string) string {
+	u, err := url.Parse(baseURL)
+	if err != nil {
+		return ""
+	}
+	return u.Host
+}
@@ -0,0 +1,126 @@
+package handlers
+
+import (
+	"encoding/base64"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/locplace/scanner/internal/coordinator/db"
+	"github.com/locplace/scanner/pkg/api"
+)
+
+// Number of changes GetChanges returns per page by default and at most.
+const (
+	defaultChangesLimit = 1000
+	maxChangesLimit     = 10000
+)
+
+// GetChanges handles GET /api/public/changes.
+// Returns the records added, updated and removed after a position in the
+// feed, so a mirror can stay in sync without re-downloading everything.
+// The first call starts at a since timestamp; later calls pass the
+// response's next_cursor as cursor. Pages hold up to limit changes.
+func (h *PublicHandlers) GetChanges(w http.ResponseWriter, r *http.Request) {
+	after, err := parseChangesPosition(r.URL.Query().Get("since"), r.URL.Query().Get("cursor"))
+	if err != nil {
+		writeError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	limit, err := parseLimit(r.URL.Query().Get("limit"), defaultChangesLimit, maxChangesLimit)
+	if err != nil {
+		writeError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if h.ChangesRetention > 0 && after.ChangedAt.Before(time.Now().Add(-h.ChangesRetention)) {
+		// Removals from before then may already be pruned
+		writeError(w, "position is older than the changes retention; download records.jsonl again and continue from its time", http.StatusGone)
+		return
+	}
+
+	changes, err := h.DB.ListChanges(r.Context(), after, limit)
+	if err != nil {
+		writeError(w, "failed to get changes", http.StatusInternalServerError)
+		return
+	}
+
+	setFreshness(changes.Records, h.StaleAfter)
+	extendWriteDeadline(w)
+	writeJSON(w, http.StatusOK, classifyChanges(after, changes))
+}
+
+// parseChangesPosition returns the feed position to read after: the
+// cursor if given, otherwise the since timestamp.
+func parseChangesPosition(since, cursor string) (db.ChangeCursor, error) {
+	switch {
+	case cursor != "" && since != "":
+		return db.ChangeCursor{}, errors.New("pass either since or cursor, not both")
+	case cursor != "":
+		c, err := decodeChangeCursor(cursor)
+		if err != nil {
+			return db.ChangeCursor{}, err
+		}
+		return c, nil
+	case since != "":
+		t, err := time.Parse(time.RFC3339Nano, since)
+		if err != nil {
+			return db.ChangeCursor{}, errors.New("since must be an RFC 3339 timestamp")
+		}
+		return db.ChangeCursor{ChangedAt: t}, nil
+	default:
+		return db.ChangeCursor{}, errors.New("since or cursor is required")
+	}
+}
+
+// encodeChangeCursor returns the opaque cursor for a feed position.
+func encodeChangeCursor(c db.ChangeCursor) string {
+	key := c.ChangedAt.UTC().Format(time.RFC3339Nano) + " " + strconv.FormatInt(c.ChangeID, 10)
+	return base64.RawURLEncoding.EncodeToString([]byte(key))
+}
+
+// decodeChangeCursor parses a cursor produced by encodeChangeCursor.
+func decodeChangeCursor(s string) (db.ChangeCursor, error) {
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return db.ChangeCursor{}, errInvalidCursor
+	}
+	ts, id, ok := strings.Cut(string(b), " ")
+	if !ok {
+		return db.ChangeCursor{}, errInvalidCursor
+	}
+	t, err := time.Parse(time.RFC3339Nano, ts)
+	if err != nil {
+		return db.ChangeCursor{}, errInvalidCursor
+	}
+	changeID, err := strconv.ParseInt(id, 10, 64)
+	if err != nil {
+		return db.ChangeCursor{}, errInvalidCursor
+	}
+	return db.ChangeCursor{ChangedAt: t, ChangeID: changeID}, nil
+}
+
+// classifyChanges splits the records of a page into those first seen after
+// its starting position and those seen again or edited.
+func classifyChanges(after db.ChangeCursor, changes db.Changes) api.ChangesResponse {
+	resp := api.ChangesResponse{
+		Since:      after.ChangedAt,
+		Added:      []api.PublicLOCRecord{},
+		Updated:    []api.PublicLOCRecord{},
+		Removed:    changes.Removed,
+		NextCursor: encodeChangeCursor(changes.Next),
+		HasMore:    changes.More,
+	}
+	if resp.Removed == nil {
+		resp.Removed = []api.RemovedRecord{}
+	}
+	for _, rec := range changes.Records {
+		if rec.FirstSeenAt.After(after.ChangedAt) {
+			resp.Added = append(resp.Added, rec)
+		} else {
+			resp.Updated = append(resp.Updated, rec)
+		}
+	}
+	return resp
+}
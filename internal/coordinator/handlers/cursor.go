@@ -0,0 +1,64 @@
+package handlers
+
+import (
+	"encoding/base64"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/locplace/scanner/internal/coordinator/db"
+	"github.com/locplace/scanner/pkg/api"
+)
+
+// cursorEnd is the before value that selects the last page.
+const cursorEnd = "end"
+
+var errInvalidCursor = errors.New("invalid cursor")
+
+// encodeCursor returns the opaque keyset cursor for a record.
+func encodeCursor(r api.PublicLOCRecord) string {
+	key := r.LastSeenAt.UTC().Format(time.RFC3339Nano) + " " + r.FQDN + "\n" + r.RawRecord
+	return base64.RawURLEncoding.EncodeToString([]byte(key))
+}
+
+// decodeCursor parses a cursor produced by encodeCursor. Cursors from before
+// records were keyed by raw record lack it and decode with an empty one.
+func decodeCursor(s string) (*db.RecordCursor, error) {
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, errInvalidCursor
+	}
+	ts, rest, ok := strings.Cut(string(b), " ")
+	fqdn, raw, _ := strings.Cut(rest, "\n")
+	if !ok || fqdn == "" {
+		return nil, errInvalidCursor
+	}
+	t, err := time.Parse(time.RFC3339Nano, ts)
+	if err != nil {
+		return nil, errInvalidCursor
+	}
+	return &db.RecordCursor{LastSeenAt: t, FQDN: fqdn, RawRecord: raw}, nil
+}
+
+// cursorPagination builds the envelope for a keyset page. backward and
+// cursor are the request's scan direction and position; more is what
+// db.ListLOCRecordsPage reported.
+func cursorPagination(records []api.PublicLOCRecord, total, limit int, cursor *db.RecordCursor, backward, more bool) api.Pagination {
+	p := api.Pagination{Total: total, Limit: limit}
+	if len(records) == 0 {
+		return p
+	}
+	// Moving away from a cursor means there are records behind it
+	hasNext, hasPrev := more, cursor != nil
+	if backward {
+		hasNext, hasPrev = cursor != nil, more
+	}
+	if hasNext {
+		p.HasMore = true
+		p.NextCursor = encodeCursor(records[len(records)-1])
+	}
+	if hasPrev {
+		p.PrevCursor = encodeCursor(records[0])
+	}
+	return p
+}
@@ -0,0 +1,50 @@
+package handlers
+
+import (
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+)
+
+// maxCoordDecimals caps coordinate rounding. Ten decimals of a degree is
+// about 10µm, well below any LOC record's precision.
+const maxCoordDecimals = 10
+
+// ExportDecimals sets the default number of decimals coordinates are rounded
+// to per export format. Negative values keep full precision.
+type ExportDecimals struct {
+	GeoJSON int // GET /api/public/records.geojson
+	JSONL   int // GET /api/public/records.jsonl
+}
+
+// DefaultExportDecimals returns the export rounding used when none is
+// configured: full precision everywhere.
+func DefaultExportDecimals() ExportDecimals {
+	return ExportDecimals{GeoJSON: -1, JSONL: -1}
+}
+
+// roundCoord rounds v to the given number of decimals. Negative decimals
+// return v unchanged.
+func roundCoord(v float64, decimals int) float64 {
+	if decimals < 0 {
+		return v
+	}
+	p := math.Pow10(min(decimals, maxCoordDecimals))
+	return math.Round(v*p) / p
+}
+
+// parseDecimals reads the decimals parameter, falling back to def. Values
+// are clamped to [0, maxCoordDecimals]; a negative def with no parameter
+// keeps full precision.
+func parseDecimals(r *http.Request, def int) (int, error) {
+	s := r.URL.Query().Get("decimals")
+	if s == "" {
+		return min(def, maxCoordDecimals), nil
+	}
+	v, err := strconv.Atoi(s)
+	if err != nil {
+		return 0, fmt.Errorf("decimals must be an integer")
+	}
+	return max(0, min(v, maxCoordDecimals)), nil
+}
@@ -0,0 +1,318 @@
+package handlers
+
+import (
+	"fmt"
+	"math"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/locplace/scanner/internal/coordinator/db"
+)
+
+// parseRecordFilter reads the record filter query parameters shared by the
+// records list and export endpoints:
+//
+//	domain           exact root domain
+//	fqdn_pattern     glob over the FQDN (* and ?), e.g. *.edu
+//	bbox             min_lon,min_lat,max_lon,max_lat (min_lon > max_lon crosses the antimeridian)
+//	min_altitude_m   minimum altitude in meters
+//	max_altitude_m   maximum altitude in meters
+//	max_horiz_prec_m maximum horizontal precision in meters
+//	max_vert_prec_m  maximum vertical precision in meters
+//	min_size_m       minimum size (diameter of the entity) in meters
+//	max_size_m       maximum size in meters
+//	updated_since    RFC 3339 timestamp; only records seen after it, oldest first
+//	first_seen_since RFC 3339 timestamp or YYYY-MM-DD; records first seen at or after it
+//	first_seen_until RFC 3339 timestamp or YYYY-MM-DD; records first seen before it (a date includes that whole day)
+//	max_age_seconds  only records seen within this many seconds
+//	tz               IANA time zone for date-only first_seen_* values (default UTC)
+//	hemisphere       N, S, E or W; repeat or comma-separate to combine, e.g. S,W
+//	exclude_zero_altitude  true drops records whose altitude is probably unset (0m, default vertical precision)
+//	exclude_implausible    true drops records with plausibility warnings (see api.LOCRecord.PlausibilityWarnings)
+//	sample_fraction  keep roughly this share of the matches, 0.000001 to 1
+//	sample_seed      integer seed for sample_fraction (default 0); the same seed selects the same records
+func parseRecordFilter(r *http.Request) (db.RecordFilter, error) {
+	q := r.URL.Query()
+	filter := db.RecordFilter{
+		Domain: q.Get("domain"),
+	}
+
+	if s := q.Get("fqdn_pattern"); s != "" {
+		pattern, err := parseFQDNPattern(s)
+		if err != nil {
+			return filter, err
+		}
+		filter.FQDNPattern = pattern
+	}
+
+	if s := q.Get("bbox"); s != "" {
+		bbox, err := parseBBox(s)
+		if err != nil {
+			return filter, err
+		}
+		filter.BBox = bbox
+	}
+
+	var err error
+	if filter.MinAltitudeM, err = parseFloatParam(r, "min_altitude_m"); err != nil {
+		return filter, err
+	}
+	if filter.MaxAltitudeM, err = parseFloatParam(r, "max_altitude_m"); err != nil {
+		return filter, err
+	}
+	if filter.MinAltitudeM != nil && filter.MaxAltitudeM != nil && *filter.MinAltitudeM > *filter.MaxAltitudeM {
+		return filter, fmt.Errorf("min_altitude_m must not exceed max_altitude_m")
+	}
+	if filter.MaxHorizPrecM, err = parseFloatParam(r, "max_horiz_prec_m"); err != nil {
+		return filter, err
+	}
+	if filter.MaxHorizPrecM != nil && *filter.MaxHorizPrecM < 0 {
+		return filter, fmt.Errorf("max_horiz_prec_m must not be negative")
+	}
+	if filter.MaxVertPrecM, err = parseFloatParam(r, "max_vert_prec_m"); err != nil {
+		return filter, err
+	}
+	if filter.MaxVertPrecM != nil && *filter.MaxVertPrecM < 0 {
+		return filter, fmt.Errorf("max_vert_prec_m must not be negative")
+	}
+	if filter.MinSizeM, err = parseFloatParam(r, "min_size_m"); err != nil {
+		return filter, err
+	}
+	if filter.MinSizeM != nil && *filter.MinSizeM < 0 {
+		return filter, fmt.Errorf("min_size_m must not be negative")
+	}
+	if filter.MaxSizeM, err = parseFloatParam(r, "max_size_m"); err != nil {
+		return filter, err
+	}
+	if filter.MaxSizeM != nil && *filter.MaxSizeM < 0 {
+		return filter, fmt.Errorf("max_size_m must not be negative")
+	}
+	if filter.MinSizeM != nil && filter.MaxSizeM != nil && *filter.MinSizeM > *filter.MaxSizeM {
+		return filter, fmt.Errorf("min_size_m must not exceed max_size_m")
+	}
+
+	if s := q.Get("exclude_zero_altitude"); s != "" {
+		v, err := strconv.ParseBool(s)
+		if err != nil {
+			return filter, fmt.Errorf("exclude_zero_altitude must be true or false")
+		}
+		filter.ExcludeZeroAltitude = v
+	}
+
+	if s := q.Get("exclude_implausible"); s != "" {
+		v, err := strconv.ParseBool(s)
+		if err != nil {
+			return filter, fmt.Errorf("exclude_implausible must be true or false")
+		}
+		filter.ExcludeImplausible = v
+	}
+
+	if err := parseHemispheres(q["hemisphere"], &filter); err != nil {
+		return filter, err
+	}
+
+	if s := q.Get("updated_since"); s != "" {
+		t, err := time.Parse(time.RFC3339Nano, s)
+		if err != nil {
+			return filter, fmt.Errorf("updated_since must be an RFC 3339 timestamp")
+		}
+		filter.UpdatedSince = &t
+	}
+
+	if s := q.Get("max_age_seconds"); s != "" {
+		v, err := strconv.ParseInt(s, 10, 64)
+		if err != nil || v < 0 || v > maxAgeSeconds {
+			return filter, fmt.Errorf("max_age_seconds must be an integer between 0 and %d", maxAgeSeconds)
+		}
+		t := time.Now().Add(-time.Duration(v) * time.Second)
+		filter.LastSeenSince = &t
+	}
+
+	loc := time.UTC
+	if s := q.Get("tz"); s != "" {
+		var err error
+		if loc, err = time.LoadLocation(s); err != nil {
+			return filter, fmt.Errorf("tz must be an IANA time zone name, e.g. Europe/Amsterdam")
+		}
+	}
+	if s := q.Get("first_seen_since"); s != "" {
+		t, err := parseTimeBoundary(s, loc, false)
+		if err != nil {
+			return filter, fmt.Errorf("first_seen_since %w", err)
+		}
+		filter.FirstSeenSince = &t
+	}
+	if s := q.Get("first_seen_until"); s != "" {
+		t, err := parseTimeBoundary(s, loc, true)
+		if err != nil {
+			return filter, fmt.Errorf("first_seen_until %w", err)
+		}
+		filter.FirstSeenUntil = &t
+	}
+	if filter.FirstSeenSince != nil && filter.FirstSeenUntil != nil && !filter.FirstSeenSince.Before(*filter.FirstSeenUntil) {
+		return filter, fmt.Errorf("first_seen_since must be before first_seen_until")
+	}
+
+	if err := parseSample(q, &filter); err != nil {
+		return filter, err
+	}
+
+	return filter, nil
+}
+
+// parseTimeBoundary parses an RFC 3339 timestamp, or a YYYY-MM-DD date in
+// loc. A date stands for the start of that local day, or with endOfDay the
+// start of the next one, so an exclusive upper bound covers the whole day.
+// Days are counted on the calendar, so DST days of 23 or 25 hours come out
+// right.
+func parseTimeBoundary(s string, loc *time.Location, endOfDay bool) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339Nano, s); err == nil {
+		return t, nil
+	}
+	// Parsed as a plain calendar date; startOfDay applies loc
+	d, err := time.Parse(time.DateOnly, s)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("must be an RFC 3339 timestamp or a YYYY-MM-DD date")
+	}
+	if endOfDay {
+		d = d.AddDate(0, 0, 1)
+	}
+	return startOfDay(d.Year(), d.Month(), d.Day(), loc).UTC(), nil
+}
+
+// startOfDay returns the first instant of a calendar day in loc. Where a
+// DST change skips midnight, time.Date may resolve it to the previous
+// evening, so the day then starts at the end of that zone period.
+func startOfDay(year int, month time.Month, day int, loc *time.Location) time.Time {
+	t := time.Date(year, month, day, 0, 0, 0, 0, loc)
+	if t.Day() != day {
+		_, end := t.ZoneBounds()
+		return end
+	}
+	return t
+}
+
+// minSampleFraction is the smallest sample_fraction the hash resolution
+// in db.RecordFilter can represent.
+const minSampleFraction = 0.000001
+
+// parseSample reads sample_fraction and sample_seed. A seed without a
+// fraction is rejected rather than silently ignored.
+func parseSample(q url.Values, filter *db.RecordFilter) error {
+	if s := q.Get("sample_fraction"); s != "" {
+		f, err := strconv.ParseFloat(s, 64)
+		if err != nil || math.IsNaN(f) || f < minSampleFraction || f > 1 {
+			return fmt.Errorf("sample_fraction must be a number between %g and 1", minSampleFraction)
+		}
+		filter.SampleFraction = f
+	}
+	if s := q.Get("sample_seed"); s != "" {
+		if filter.SampleFraction == 0 {
+			return fmt.Errorf("sample_seed requires sample_fraction")
+		}
+		seed, err := strconv.ParseInt(s, 10, 64)
+		if err != nil {
+			return fmt.Errorf("sample_seed must be an integer")
+		}
+		filter.SampleSeed = seed
+	}
+	return nil
+}
+
+// parseHemispheres sets the latitude/longitude hemispheres from hemisphere
+// values. At most one of N/S and one of E/W may be given.
+func parseHemispheres(values []string, filter *db.RecordFilter) error {
+	for _, v := range values {
+		for _, h := range strings.Split(v, ",") {
+			h = strings.ToUpper(strings.TrimSpace(h))
+			var target *string
+			switch h {
+			case "N", "S":
+				target = &filter.LatHemisphere
+			case "E", "W":
+				target = &filter.LonHemisphere
+			default:
+				return fmt.Errorf("hemisphere must be N, S, E or W")
+			}
+			if *target != "" && *target != h {
+				return fmt.Errorf("hemisphere %s conflicts with %s", h, *target)
+			}
+			*target = h
+		}
+	}
+	return nil
+}
+
+// maxAgeSeconds caps max_age_seconds at about a century, well within
+// time.Duration.
+const maxAgeSeconds = 100 * 365 * 24 * 3600
+
+// maxPatternWildcards caps the number of * in an fqdn_pattern. LIKE matching
+// cost grows with the number of % segments, so a handful is plenty.
+const maxPatternWildcards = 4
+
+// parseFQDNPattern validates and normalizes an fqdn_pattern glob. Only
+// hostname characters plus the * and ? wildcards are accepted.
+func parseFQDNPattern(s string) (string, error) {
+	s = strings.ToLower(strings.TrimSuffix(s, "."))
+	if len(s) > 253 {
+		return "", fmt.Errorf("fqdn_pattern is too long")
+	}
+	for strings.Contains(s, "**") {
+		s = strings.ReplaceAll(s, "**", "*")
+	}
+	for _, c := range s {
+		if !(c >= 'a' && c <= 'z' || c >= '0' && c <= '9' || c == '-' || c == '.' || c == '_' || c == '*' || c == '?') {
+			return "", fmt.Errorf("fqdn_pattern may only contain hostname characters, * and ?")
+		}
+	}
+	if strings.Count(s, "*") > maxPatternWildcards {
+		return "", fmt.Errorf("fqdn_pattern may contain at most %d * wildcards", maxPatternWildcards)
+	}
+	return s, nil
+}
+
+// parseBBox parses "min_lon,min_lat,max_lon,max_lat".
+func parseBBox(s string) (*db.BBox, error) {
+	parts := strings.Split(s, ",")
+	if len(parts) != 4 {
+		return nil, fmt.Errorf("bbox must be min_lon,min_lat,max_lon,max_lat")
+	}
+
+	var v [4]float64
+	for i, p := range parts {
+		f, err := strconv.ParseFloat(strings.TrimSpace(p), 64)
+		if err != nil || math.IsNaN(f) || math.IsInf(f, 0) {
+			return nil, fmt.Errorf("bbox contains an invalid number: %q", p)
+		}
+		v[i] = f
+	}
+
+	bbox := &db.BBox{MinLon: v[0], MinLat: v[1], MaxLon: v[2], MaxLat: v[3]}
+	if bbox.MinLon < -180 || bbox.MinLon > 180 || bbox.MaxLon < -180 || bbox.MaxLon > 180 {
+		return nil, fmt.Errorf("bbox longitude must be between -180 and 180")
+	}
+	if bbox.MinLat < -90 || bbox.MinLat > 90 || bbox.MaxLat < -90 || bbox.MaxLat > 90 {
+		return nil, fmt.Errorf("bbox latitude must be between -90 and 90")
+	}
+	if bbox.MinLat > bbox.MaxLat {
+		return nil, fmt.Errorf("bbox min_lat must not exceed max_lat")
+	}
+	return bbox, nil
+}
+
+// parseFloatParam returns nil if the parameter is absent.
+func parseFloatParam(r *http.Request, name string) (*float64, error) {
+	s := r.URL.Query().Get(name)
+	if s == "" {
+		return nil, nil
+	}
+	v, err := strconv.ParseFloat(s, 64)
+	if err != nil || math.IsNaN(v) || math.IsInf(v, 0) {
+		return nil, fmt.Errorf("%s must be a number", name)
+	}
+	return &v, nil
+}
@@ -0,0 +1,29 @@
+package handlers
+
+import (
+	"time"
+
+	"github.com/locplace/scanner/pkg/api"
+)
+
+// DefaultStaleAfter is how long after it was last seen a record is marked
+// stale when no threshold is configured.
+const DefaultStaleAfter = 30 * 24 * time.Hour
+
+// markFreshness sets the AgeSeconds and IsStale fields of rec as of now.
+// Records are stale once they haven't been seen for longer than staleAfter;
+// zero never marks them stale. They are computed when serving rather than
+// stored, as they change with time.
+func markFreshness(rec *api.PublicLOCRecord, now time.Time, staleAfter time.Duration) {
+	age := max(now.Sub(rec.LastSeenAt), 0)
+	rec.AgeSeconds = int64(age / time.Second)
+	rec.IsStale = staleAfter > 0 && age > staleAfter
+}
+
+// setFreshness marks the freshness of records as of now.
+func setFreshness(records []api.PublicLOCRecord, staleAfter time.Duration) {
+	now := time.Now()
+	for i := range records {
+		markFreshness(&records[i], now, staleAfter)
+	}
+}
@@ -1,12 +1,27 @@
 package handlers
 
 import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"database/sql"
+	"encoding/base64"
 	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"io"
+	"math"
 	"net/http"
 	"net/http/httptest"
+	"net/url"
+	"path/filepath"
+	"slices"
 	"strings"
 	"testing"
+	"time"
 
+	"github.com/locplace/scanner/internal/coordinator/db"
+	"github.com/locplace/scanner/internal/coordinator/tldfilter"
 	"github.com/locplace/scanner/pkg/api"
 )
 
@@ -38,7 +53,7 @@ func TestWriteJSON(t *testing.T) {
 			data: api.SubmitBatchResponse{
 				Accepted: 5,
 			},
-			wantBody:   `{"accepted":5}`,
+			wantBody:   `{"accepted":5,"parse":{"parsed":0,"failed":0}}`,
 			wantStatus: http.StatusOK,
 		},
 		{
@@ -477,3 +492,1464 @@ func TestLOCRecord_Parsing(t *testing.T) {
 		})
 	}
 }
+
+func TestParseRecordFilter(t *testing.T) {
+	tests := []struct {
+		name    string
+		query   string
+		wantErr bool
+		check   func(t *testing.T, f db.RecordFilter)
+	}{
+		{
+			name:  "no parameters",
+			query: "",
+			check: func(t *testing.T, f db.RecordFilter) {
+				if f.Domain != "" || f.BBox != nil || f.MinAltitudeM != nil || f.MaxAltitudeM != nil || f.MaxHorizPrecM != nil {
+					t.Errorf("expected empty filter, got %+v", f)
+				}
+			},
+		},
+		{
+			name:  "domain and bbox",
+			query: "domain=nikhef.nl&bbox=4,52,5,53",
+			check: func(t *testing.T, f db.RecordFilter) {
+				if f.Domain != "nikhef.nl" {
+					t.Errorf("Domain = %q, want nikhef.nl", f.Domain)
+				}
+				want := db.BBox{MinLon: 4, MinLat: 52, MaxLon: 5, MaxLat: 53}
+				if f.BBox == nil || *f.BBox != want {
+					t.Errorf("BBox = %+v, want %+v", f.BBox, want)
+				}
+			},
+		},
+		{
+			name:  "max age",
+			query: "max_age_seconds=3600",
+			check: func(t *testing.T, f db.RecordFilter) {
+				want := time.Now().Add(-time.Hour)
+				if f.LastSeenSince == nil || f.LastSeenSince.Sub(want).Abs() > time.Minute {
+					t.Errorf("LastSeenSince = %v, want about %v", f.LastSeenSince, want)
+				}
+			},
+		},
+		{name: "negative max age", query: "max_age_seconds=-1", wantErr: true},
+		{name: "fractional max age", query: "max_age_seconds=1.5", wantErr: true},
+		{
+			name:  "first seen week in a time zone",
+			query: "first_seen_since=2024-06-03&first_seen_until=2024-06-09&tz=America/New_York",
+			check: func(t *testing.T, f db.RecordFilter) {
+				since := time.Date(2024, 6, 3, 4, 0, 0, 0, time.UTC)
+				until := time.Date(2024, 6, 10, 4, 0, 0, 0, time.UTC)
+				if f.FirstSeenSince == nil || !f.FirstSeenSince.Equal(since) || f.FirstSeenUntil == nil || !f.FirstSeenUntil.Equal(until) {
+					t.Errorf("first seen = %v - %v, want %v - %v", f.FirstSeenSince, f.FirstSeenUntil, since, until)
+				}
+			},
+		},
+		{
+			name:    "unknown time zone",
+			query:   "first_seen_since=2024-06-03&tz=Mars/Olympus_Mons",
+			wantErr: true,
+		},
+		{
+			name:    "empty first seen range",
+			query:   "first_seen_since=2024-06-03&first_seen_until=2024-06-02",
+			wantErr: true,
+		},
+		{
+			name:  "altitude and precision",
+			query: "min_altitude_m=-10&max_altitude_m=500&max_horiz_prec_m=100&max_vert_prec_m=10",
+			check: func(t *testing.T, f db.RecordFilter) {
+				if f.MinAltitudeM == nil || *f.MinAltitudeM != -10 {
+					t.Errorf("MinAltitudeM = %v, want -10", f.MinAltitudeM)
+				}
+				if f.MaxAltitudeM == nil || *f.MaxAltitudeM != 500 {
+					t.Errorf("MaxAltitudeM = %v, want 500", f.MaxAltitudeM)
+				}
+				if f.MaxHorizPrecM == nil || *f.MaxHorizPrecM != 100 {
+					t.Errorf("MaxHorizPrecM = %v, want 100", f.MaxHorizPrecM)
+				}
+				if f.MaxVertPrecM == nil || *f.MaxVertPrecM != 10 {
+					t.Errorf("MaxVertPrecM = %v, want 10", f.MaxVertPrecM)
+				}
+			},
+		},
+		{
+			name:  "bbox crossing antimeridian",
+			query: "bbox=170,-50,-170,-30",
+			check: func(t *testing.T, f db.RecordFilter) {
+				if f.BBox == nil || f.BBox.MinLon != 170 || f.BBox.MaxLon != -170 {
+					t.Errorf("BBox = %+v, want antimeridian box", f.BBox)
+				}
+			},
+		},
+		{
+			name:  "fqdn pattern normalized",
+			query: "fqdn_pattern=WWW.**.Example.EDU.",
+			check: func(t *testing.T, f db.RecordFilter) {
+				if f.FQDNPattern != "www.*.example.edu" {
+					t.Errorf("FQDNPattern = %q, want www.*.example.edu", f.FQDNPattern)
+				}
+			},
+		},
+		{name: "fqdn pattern bad character", query: "fqdn_pattern=%25.edu", wantErr: true},
+		{name: "fqdn pattern too many wildcards", query: "fqdn_pattern=*a*b*c*d*e", wantErr: true},
+		{
+			name:  "updated since",
+			query: "updated_since=2024-06-01T12:00:00.5Z",
+			check: func(t *testing.T, f db.RecordFilter) {
+				want := time.Date(2024, 6, 1, 12, 0, 0, 5e8, time.UTC)
+				if f.UpdatedSince == nil || !f.UpdatedSince.Equal(want) {
+					t.Errorf("UpdatedSince = %v, want %v", f.UpdatedSince, want)
+				}
+			},
+		},
+		{name: "updated since not a timestamp", query: "updated_since=yesterday", wantErr: true},
+		{
+			name:  "hemispheres combined",
+			query: "hemisphere=s&hemisphere=W",
+			check: func(t *testing.T, f db.RecordFilter) {
+				if f.LatHemisphere != "S" || f.LonHemisphere != "W" {
+					t.Errorf("hemispheres = %q/%q, want S/W", f.LatHemisphere, f.LonHemisphere)
+				}
+			},
+		},
+		{
+			name:  "hemispheres comma separated",
+			query: "hemisphere=N,E",
+			check: func(t *testing.T, f db.RecordFilter) {
+				if f.LatHemisphere != "N" || f.LonHemisphere != "E" {
+					t.Errorf("hemispheres = %q/%q, want N/E", f.LatHemisphere, f.LonHemisphere)
+				}
+			},
+		},
+		{name: "hemisphere conflict", query: "hemisphere=N,S", wantErr: true},
+		{
+			name:  "sample with seed",
+			query: "sample_fraction=0.25&sample_seed=-7",
+			check: func(t *testing.T, f db.RecordFilter) {
+				if f.SampleFraction != 0.25 || f.SampleSeed != -7 {
+					t.Errorf("sample = %v/%d, want 0.25/-7", f.SampleFraction, f.SampleSeed)
+				}
+			},
+		},
+		{
+			name:  "size range",
+			query: "min_size_m=100&max_size_m=5000",
+			check: func(t *testing.T, f db.RecordFilter) {
+				if f.MinSizeM == nil || *f.MinSizeM != 100 || f.MaxSizeM == nil || *f.MaxSizeM != 5000 {
+					t.Errorf("size range = %v-%v, want 100-5000", f.MinSizeM, f.MaxSizeM)
+				}
+			},
+		},
+		{name: "negative min size", query: "min_size_m=-1", wantErr: true},
+		{name: "size range inverted", query: "min_size_m=500&max_size_m=100", wantErr: true},
+		{name: "sample fraction zero", query: "sample_fraction=0", wantErr: true},
+		{name: "sample fraction above one", query: "sample_fraction=1.5", wantErr: true},
+		{name: "sample seed without fraction", query: "sample_seed=1", wantErr: true},
+		{name: "sample seed not an integer", query: "sample_fraction=0.5&sample_seed=x", wantErr: true},
+		{
+			name:  "exclude zero altitude",
+			query: "exclude_zero_altitude=true",
+			check: func(t *testing.T, f db.RecordFilter) {
+				if !f.ExcludeZeroAltitude {
+					t.Error("ExcludeZeroAltitude = false, want true")
+				}
+			},
+		},
+		{name: "exclude zero altitude invalid", query: "exclude_zero_altitude=maybe", wantErr: true},
+		{name: "hemisphere invalid", query: "hemisphere=X", wantErr: true},
+		{name: "bbox wrong arity", query: "bbox=1,2,3", wantErr: true},
+		{name: "bbox not a number", query: "bbox=a,2,3,4", wantErr: true},
+		{name: "bbox latitude out of range", query: "bbox=0,-91,1,1", wantErr: true},
+		{name: "bbox inverted latitude", query: "bbox=0,10,1,5", wantErr: true},
+		{name: "altitude not a number", query: "min_altitude_m=high", wantErr: true},
+		{name: "altitude NaN", query: "max_altitude_m=NaN", wantErr: true},
+		{name: "altitude min above max", query: "min_altitude_m=10&max_altitude_m=5", wantErr: true},
+		{name: "negative precision", query: "max_horiz_prec_m=-1", wantErr: true},
+		{name: "negative vertical precision", query: "max_vert_prec_m=-1", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/api/public/records?"+tt.query, nil)
+			got, err := parseRecordFilter(req)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseRecordFilter() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.check != nil {
+				tt.check(t, got)
+			}
+		})
+	}
+}
+
+func TestClassifyChanges(t *testing.T) {
+	since := time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)
+	next := db.ChangeCursor{ChangedAt: since.Add(3 * time.Hour), ChangeID: 42}
+	records := []api.PublicLOCRecord{
+		{FQDN: "old.example", FirstSeenAt: since.Add(-time.Hour), LastSeenAt: since.Add(time.Hour)},
+		{FQDN: "boundary.example", FirstSeenAt: since, LastSeenAt: since.Add(2 * time.Hour)},
+		{FQDN: "new.example", FirstSeenAt: since.Add(3 * time.Hour), LastSeenAt: since.Add(3 * time.Hour)},
+	}
+
+	resp := classifyChanges(db.ChangeCursor{ChangedAt: since}, db.Changes{Records: records, Next: next, More: true})
+	if len(resp.Added) != 1 || resp.Added[0].FQDN != "new.example" {
+		t.Errorf("added = %+v, want new.example", resp.Added)
+	}
+	if len(resp.Updated) != 2 || resp.Updated[0].FQDN != "old.example" || resp.Updated[1].FQDN != "boundary.example" {
+		t.Errorf("updated = %+v, want old.example and boundary.example", resp.Updated)
+	}
+	if resp.Removed == nil {
+		t.Error("removed = nil, want empty list")
+	}
+	if got, err := decodeChangeCursor(resp.NextCursor); err != nil || !got.ChangedAt.Equal(next.ChangedAt) || got.ChangeID != 42 || !resp.HasMore {
+		t.Errorf("next_cursor = %+v (%v), has_more = %v; want %+v, true", got, err, resp.HasMore, next)
+	}
+}
+
+func TestChangeCursorRoundTrip(t *testing.T) {
+	c := db.ChangeCursor{ChangedAt: time.Date(2024, 6, 1, 12, 0, 0, 123456000, time.FixedZone("CEST", 2*3600)), ChangeID: 9001}
+	got, err := decodeChangeCursor(encodeChangeCursor(c))
+	if err != nil || !got.ChangedAt.Equal(c.ChangedAt) || got.ChangeID != c.ChangeID {
+		t.Errorf("decodeChangeCursor() = %+v, %v; want %+v", got, err, c)
+	}
+	for _, s := range []string{"", "!!!", encodeCursor(api.PublicLOCRecord{FQDN: "x.example.com"})} {
+		if _, err := decodeChangeCursor(s); err == nil {
+			t.Errorf("decodeChangeCursor(%q) should fail", s)
+		}
+	}
+}
+
+func TestGetChanges_InvalidPosition(t *testing.T) {
+	h := &PublicHandlers{ChangesRetention: 24 * time.Hour}
+	recent := time.Now().Add(-time.Hour).UTC().Format(time.RFC3339)
+	for _, q := range []string{"", "?since=yesterday", "?cursor=!!!", "?since=" + recent + "&cursor=x", "?since=" + recent + "&limit=0"} {
+		rec := httptest.NewRecorder()
+		h.GetChanges(rec, httptest.NewRequest(http.MethodGet, "/api/public/changes"+q, nil))
+		if rec.Code != http.StatusBadRequest {
+			t.Errorf("%q: status = %d, want 400", q, rec.Code)
+		}
+	}
+
+	// Removals from before the retention may be gone, so the mirror must resync
+	rec := httptest.NewRecorder()
+	h.GetChanges(rec, httptest.NewRequest(http.MethodGet, "/api/public/changes?since=1970-01-01T00:00:00Z", nil))
+	if rec.Code != http.StatusGone {
+		t.Errorf("since before retention: status = %d, want 410", rec.Code)
+	}
+}
+
+func TestBuildAtomFeed(t *testing.T) {
+	seen := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	records := []api.PublicLOCRecord{
+		{FQDN: "nikhef.nl", Latitude: 52.356, Longitude: 4.951, FirstSeenAt: seen},
+		{FQDN: "caida.org", Latitude: 32.88, Longitude: -117.24, FirstSeenAt: seen.Add(-time.Hour)},
+	}
+
+	feed := buildAtomFeed("https://loc.example", "loc.example", records)
+
+	if feed.Updated != "2024-03-01T12:00:00Z" {
+		t.Errorf("Updated = %q, want newest first_seen_at", feed.Updated)
+	}
+	if len(feed.Entries) != 2 {
+		t.Fatalf("got %d entries, want 2", len(feed.Entries))
+	}
+	e := feed.Entries[0]
+	if e.Title != "nikhef.nl" {
+		t.Errorf("Title = %q, want nikhef.nl", e.Title)
+	}
+	if e.ID != "tag:loc.example,2024-03-01:nikhef.nl" {
+		t.Errorf("ID = %q", e.ID)
+	}
+	if len(e.Links) != 1 || !strings.Contains(e.Links[0].Href, "mlat=52.356000") {
+		t.Errorf("Links = %+v, want map link", e.Links)
+	}
+
+	if _, err := xml.Marshal(feed); err != nil {
+		t.Errorf("xml.Marshal() error: %v", err)
+	}
+}
+
+func TestListRecords_InvalidCountOnly(t *testing.T) {
+	h := &PublicHandlers{}
+	rec := httptest.NewRecorder()
+	h.ListRecords(rec, httptest.NewRequest(http.MethodGet, "/api/public/records?count_only=maybe", nil))
+	if rec.Code != http.StatusBadRequest || !strings.Contains(rec.Body.String(), "count_only") {
+		t.Errorf("count_only=maybe: %d %s, want 400", rec.Code, rec.Body.String())
+	}
+}
+
+func TestGetRecordsGeoJSON_InvalidEmpty(t *testing.T) {
+	h := &PublicHandlers{}
+	rec := httptest.NewRecorder()
+	h.GetRecordsGeoJSON(rec, httptest.NewRequest(http.MethodGet, "/api/public/records.geojson?empty=404", nil))
+	if rec.Code != http.StatusBadRequest || !strings.Contains(rec.Body.String(), "empty must be 200 or 204") {
+		t.Errorf("empty=404: %d %s, want 400", rec.Code, rec.Body.String())
+	}
+}
+
+func TestParseRecord(t *testing.T) {
+	h := &PublicHandlers{}
+
+	tests := []struct {
+		name       string
+		body       string
+		wantStatus int
+		wantLat    float64
+		wantRaw    string
+	}{
+		{
+			name:       "valid record",
+			body:       `{"fqdn":"nikhef.nl","raw":"52 22 23.000 N 4 53 32.000 E -2.00m 1m 10000m 10m"}`,
+			wantStatus: http.StatusOK,
+			wantLat:    52.373056,
+		},
+		{
+			name:       "decimal fields",
+			body:       `{"fqdn":"nikhef.nl","decimal":{"latitude":52.373056,"longitude":4.892222,"altitude_m":-2}}`,
+			wantStatus: http.StatusOK,
+			wantLat:    52.373056,
+			wantRaw:    "52 22 23.002 N 4 53 31.999 E -2.00m 1m 10000m 10m",
+		},
+		{name: "decimal out of range", body: `{"decimal":{"latitude":95,"longitude":0}}`, wantStatus: http.StatusBadRequest},
+		{name: "decimal without longitude", body: `{"decimal":{"latitude":52}}`, wantStatus: http.StatusBadRequest},
+		{name: "raw and decimal", body: `{"raw":"52 N 4 E 0m","decimal":{"latitude":52,"longitude":4}}`, wantStatus: http.StatusBadRequest},
+		{name: "unparseable", body: `{"raw":"not a loc record"}`, wantStatus: http.StatusBadRequest},
+		{name: "missing raw", body: `{"fqdn":"nikhef.nl"}`, wantStatus: http.StatusBadRequest},
+		{name: "invalid json", body: `{`, wantStatus: http.StatusBadRequest},
+		{name: "body too large", body: `{"raw":"` + strings.Repeat("1", maxParseBodyBytes) + `"}`, wantStatus: http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/api/public/parse", strings.NewReader(tt.body))
+			rr := httptest.NewRecorder()
+			h.ParseRecord(rr, req)
+
+			if rr.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d (body %s)", rr.Code, tt.wantStatus, rr.Body.String())
+			}
+			if tt.wantStatus != http.StatusOK {
+				return
+			}
+			var rec api.LOCRecord
+			if err := json.NewDecoder(rr.Body).Decode(&rec); err != nil {
+				t.Fatalf("decode response: %v", err)
+			}
+			if rec.FQDN != "nikhef.nl" || rec.Latitude < tt.wantLat-0.0001 || rec.Latitude > tt.wantLat+0.0001 {
+				t.Errorf("unexpected record: %+v", rec)
+			}
+			if tt.wantRaw != "" && rec.RawRecord != tt.wantRaw {
+				t.Errorf("raw_record = %q, want %q", rec.RawRecord, tt.wantRaw)
+			}
+		})
+	}
+}
+
+func TestDecodedBody(t *testing.T) {
+	var compressed bytes.Buffer
+	gz := gzip.NewWriter(&compressed)
+	_, _ = gz.Write([]byte(`{"domains":["nikhef.nl"]}`))
+	_ = gz.Close()
+
+	tests := []struct {
+		name     string
+		body     []byte
+		encoding string
+		maxBytes int64
+		want     string
+		wantErr  bool
+		tooLarge bool // The error must be *http.MaxBytesError
+	}{
+		{name: "plain", body: []byte("hello"), maxBytes: 100, want: "hello"},
+		{name: "plain over cap", body: []byte("hello"), maxBytes: 3, wantErr: true, tooLarge: true},
+		{name: "gzip", body: compressed.Bytes(), encoding: "gzip", maxBytes: 100, want: `{"domains":["nikhef.nl"]}`},
+		{name: "gzip over cap", body: compressed.Bytes(), encoding: "gzip", maxBytes: 10, wantErr: true, tooLarge: true},
+		{name: "not gzip", body: []byte("hello"), encoding: "gzip", maxBytes: 100, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/api/admin/manual-scan", bytes.NewReader(tt.body))
+			if tt.encoding != "" {
+				req.Header.Set("Content-Encoding", tt.encoding)
+			}
+			body, err := decodedBody(httptest.NewRecorder(), req, tt.maxBytes)
+			var got []byte
+			if err == nil {
+				got, err = io.ReadAll(body)
+				if cerr := body.Close(); cerr != nil {
+					t.Errorf("Close() error: %v", cerr)
+				}
+			}
+			if tt.wantErr {
+				var maxErr *http.MaxBytesError
+				if err == nil {
+					t.Errorf("expected error, got body %q", got)
+				} else if tt.tooLarge && !errors.As(err, &maxErr) {
+					t.Errorf("error = %v, want *http.MaxBytesError", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if string(got) != tt.want {
+				t.Errorf("body = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestIdempotencyCache(t *testing.T) {
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	c := NewIdempotencyCache(time.Minute)
+	c.now = func() time.Time { return now }
+
+	if resp, inFlight := c.Begin("k"); resp != nil || inFlight {
+		t.Fatalf("first Begin = %v, %v; want nil, false", resp, inFlight)
+	}
+	if _, inFlight := c.Begin("k"); !inFlight {
+		t.Error("second Begin before Finish should report in flight")
+	}
+
+	c.Finish("k", api.SubmitBatchResponse{Accepted: 3})
+	if resp, _ := c.Begin("k"); resp == nil || resp.Accepted != 3 {
+		t.Errorf("Begin after Finish = %v, want stored response", resp)
+	}
+
+	c.Begin("aborted")
+	c.Abort("aborted")
+	if resp, inFlight := c.Begin("aborted"); resp != nil || inFlight {
+		t.Error("aborted key should be claimable again")
+	}
+
+	now = now.Add(2 * time.Minute)
+	if resp, inFlight := c.Begin("k"); resp != nil || inFlight {
+		t.Error("expired key should be claimable again")
+	}
+}
+
+func TestSetCacheControl(t *testing.T) {
+	tests := []struct {
+		ttl  time.Duration
+		want string
+	}{
+		{0, ""},
+		{5 * time.Minute, "public, max-age=300"},
+		{time.Hour, "public, max-age=3600"},
+	}
+
+	for _, tt := range tests {
+		rr := httptest.NewRecorder()
+		setCacheControl(rr, tt.ttl)
+		if got := rr.Header().Get("Cache-Control"); got != tt.want {
+			t.Errorf("setCacheControl(%v) = %q, want %q", tt.ttl, got, tt.want)
+		}
+	}
+}
+
+func TestParseStats(t *testing.T) {
+	req := api.SubmitBatchRequest{
+		LOCRecords: []api.LOCRecord{
+			{FQDN: "ok.example.com", Latitude: 52.37, Longitude: 4.89},
+			{FQDN: "bad.example.com", RawRecord: "91 N 0 E 0m", Latitude: 91, Longitude: 0},
+			{FQDN: "nan.example.com", Latitude: math.NaN(), Longitude: 0},
+		},
+		ParseErrors: []api.ParseError{
+			{FQDN: "garbage.example.com", RawRecord: "nonsense", Message: "invalid LOC record format"},
+		},
+	}
+
+	valid, stats := parseStats(req, time.Now())
+	if len(valid) != 1 || valid[0].FQDN != "ok.example.com" {
+		t.Errorf("valid = %+v, want only ok.example.com", valid)
+	}
+	if stats.Parsed != 1 || stats.Failed != 3 {
+		t.Errorf("stats = %d parsed / %d failed, want 1 / 3", stats.Parsed, stats.Failed)
+	}
+	if len(stats.Errors) != 3 || stats.Errors[0].FQDN != "garbage.example.com" || stats.Errors[1].FQDN != "bad.example.com" || stats.Errors[2].FQDN != "nan.example.com" {
+		t.Errorf("errors = %+v, want scanner error then rejected records", stats.Errors)
+	}
+}
+
+func TestPartialLookups(t *testing.T) {
+	// One good and one bad answer for mixed: its stored rows and unparsed
+	// entry must be kept, while clean is fully settled
+	req := api.SubmitBatchRequest{
+		LOCRecords: []api.LOCRecord{
+			{FQDN: "mixed.example.com", RawRecord: "52 22 23 N 4 53 32 E 0m", Latitude: 52.37, Longitude: 4.89},
+			{FQDN: "clean.example.com", RawRecord: "52 22 23 N 4 53 32 E 0m", Latitude: 52.37, Longitude: 4.89},
+			{FQDN: "coarse.example.com", Latitude: 52.37, Longitude: 4.89, HorizPrecM: 50000},
+		},
+		ParseErrors: []api.ParseError{
+			{FQDN: "mixed.example.com", RawRecord: "52 N 4 E garbage", Message: "invalid altitude"},
+		},
+	}
+	h := &ScannerHandlers{MaxHorizPrecM: 10000}
+	valid, stats := parseStats(req, time.Now())
+	_, imprecise := h.filterImprecise(valid)
+
+	got := partialLookups(stats.Errors, imprecise)
+	if want := []string{"coarse.example.com", "mixed.example.com"}; !slices.Equal(got, want) {
+		t.Errorf("partialLookups() = %v, want %v", got, want)
+	}
+}
+
+func TestFilterTLDs(t *testing.T) {
+	filter, err := tldfilter.Parse("nl", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	h := &ScannerHandlers{TLDFilter: filter}
+	req := api.SubmitBatchRequest{
+		LOCRecords:  []api.LOCRecord{{FQDN: "a.example.nl"}, {FQDN: "b.example.com"}},
+		ScanErrors:  []api.ScanError{{FQDN: "c.example.com"}, {FQDN: "d.example.nl"}},
+		ParseErrors: []api.ParseError{{FQDN: "e.example.com"}},
+		DomainFacts: []api.DomainFacts{{FQDN: "f.example.com"}},
+	}
+
+	rejected := h.filterTLDs(&req)
+	if len(rejected) != 1 || rejected[0].FQDN != "b.example.com" || rejected[0].Reason != "TLD .com is not in the allowlist" {
+		t.Errorf("rejected = %+v", rejected)
+	}
+	if len(req.LOCRecords) != 1 || req.LOCRecords[0].FQDN != "a.example.nl" {
+		t.Errorf("records = %+v, want only a.example.nl", req.LOCRecords)
+	}
+	if len(req.ScanErrors) != 1 || len(req.ParseErrors) != 0 || len(req.DomainFacts) != 0 {
+		t.Errorf("side data not filtered: %+v", req)
+	}
+
+	// No filter leaves the request alone
+	req = api.SubmitBatchRequest{LOCRecords: []api.LOCRecord{{FQDN: "b.example.com"}}}
+	if rejected := (&ScannerHandlers{}).filterTLDs(&req); rejected != nil || len(req.LOCRecords) != 1 {
+		t.Errorf("filterTLDs without filter = %+v, %+v", rejected, req.LOCRecords)
+	}
+}
+
+func TestReparseRecord(t *testing.T) {
+	raw := "  52 22 23.000 N 4 53 32.000 E -2.00m 1m 10000m 10m "
+	rec, err := reparseRecord("nikhef.nl", raw)
+	if err != nil {
+		t.Fatalf("reparseRecord() error: %v", err)
+	}
+	if rec.RawRecord != raw {
+		t.Errorf("RawRecord = %q, want the stored string %q", rec.RawRecord, raw)
+	}
+
+	stored := *rec
+	if !sameParsedFields(*rec, stored) {
+		t.Error("identical records should have the same parsed fields")
+	}
+	stored.Latitude += 1e-9
+	if sameParsedFields(*rec, stored) {
+		t.Error("a changed latitude should be detected")
+	}
+
+	for _, bad := range []string{"not a loc record", "91 0 0.000 N 4 53 32.000 E 0m"} {
+		if _, err := reparseRecord("bad.example.com", bad); err == nil {
+			t.Errorf("reparseRecord(%q) should fail", bad)
+		}
+	}
+}
+
+func TestAdminFilterFixed(t *testing.T) {
+	filter, err := tldfilter.Parse("nl", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	h := &AdminHandlers{TLDFilter: filter, MaxHorizPrecM: 1000}
+	kept, rejected := h.filterFixed([]api.LOCRecord{
+		{FQDN: "ok.example.nl", HorizPrecM: 10},
+		{FQDN: "other.example.com", HorizPrecM: 10},
+		{FQDN: "coarse.example.nl", HorizPrecM: 50000},
+	})
+	if len(kept) != 1 || kept[0].FQDN != "ok.example.nl" {
+		t.Errorf("kept = %+v, want only ok.example.nl", kept)
+	}
+	if len(rejected) != 2 || rejected[0].FQDN != "other.example.com" || rejected[1].FQDN != "coarse.example.nl" {
+		t.Errorf("rejected = %+v, want the .com name and the coarse one", rejected)
+	}
+
+	// Without filters everything is kept
+	kept, rejected = (&AdminHandlers{}).filterFixed([]api.LOCRecord{{FQDN: "a.example.com", HorizPrecM: 1e7}})
+	if len(kept) != 1 || len(rejected) != 0 {
+		t.Errorf("unfiltered: kept %d, rejected %d; want 1, 0", len(kept), len(rejected))
+	}
+}
+
+func TestDroppedAnswers(t *testing.T) {
+	all := []api.LOCRecord{
+		{FQDN: "a.example.nl", RawRecord: "1"},
+		{FQDN: "a.example.nl", RawRecord: "2"},
+		{FQDN: "b.example.nl", RawRecord: "1"},
+	}
+	got := droppedAnswers(all, []api.LOCRecord{all[0], all[2]})
+	if len(got) != 1 || got[0].FQDN != "a.example.nl" || got[0].RawRecord != "2" {
+		t.Errorf("droppedAnswers() = %+v, want only the second answer of a.example.nl", got)
+	}
+	if got := droppedAnswers(all, all); len(got) != 0 {
+		t.Errorf("droppedAnswers(all, all) = %+v, want none", got)
+	}
+}
+
+func TestFilterImprecise(t *testing.T) {
+	records := []api.LOCRecord{
+		{FQDN: "precise.example.com", HorizPrecM: 10},
+		{FQDN: "limit.example.com", HorizPrecM: 1000},
+		{FQDN: "earth.example.com", HorizPrecM: 90000000},
+	}
+
+	kept, rejected := (&ScannerHandlers{}).filterImprecise(slices.Clone(records))
+	if len(kept) != 3 || rejected != nil {
+		t.Errorf("without a limit: kept %d, rejected %+v", len(kept), rejected)
+	}
+
+	kept, rejected = (&ScannerHandlers{MaxHorizPrecM: 1000}).filterImprecise(slices.Clone(records))
+	if len(kept) != 2 || kept[0].FQDN != "precise.example.com" || kept[1].FQDN != "limit.example.com" {
+		t.Errorf("kept = %+v, want the records within 1000m", kept)
+	}
+	if len(rejected) != 1 || rejected[0].FQDN != "earth.example.com" || rejected[0].Reason != "horizontal precision 90000000m is coarser than the 1000m limit" {
+		t.Errorf("rejected = %+v", rejected)
+	}
+}
+
+func TestParseStats_ObservedAt(t *testing.T) {
+	now := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+	at := func(d time.Duration) *time.Time {
+		t := now.Add(d)
+		return &t
+	}
+	req := api.SubmitBatchRequest{
+		LOCRecords: []api.LOCRecord{
+			{FQDN: "past.example.com", ObservedAt: at(-time.Hour)},
+			{FQDN: "skewed.example.com", ObservedAt: at(time.Minute)},
+			{FQDN: "future.example.com", ObservedAt: at(time.Hour)},
+			{FQDN: "unset.example.com"},
+		},
+	}
+
+	valid, stats := parseStats(req, now)
+	if len(valid) != 3 || stats.Failed != 1 || stats.Errors[0].FQDN != "future.example.com" {
+		t.Fatalf("valid = %+v, errors = %+v; want only future.example.com rejected", valid, stats.Errors)
+	}
+	if !valid[0].ObservedAt.Equal(now.Add(-time.Hour)) {
+		t.Errorf("past observed_at = %v, want unchanged", valid[0].ObservedAt)
+	}
+	if !valid[1].ObservedAt.Equal(now) {
+		t.Errorf("skewed observed_at = %v, want clamped to %v", valid[1].ObservedAt, now)
+	}
+	if valid[2].ObservedAt != nil {
+		t.Errorf("unset observed_at = %v, want nil (the database uses NOW())", valid[2].ObservedAt)
+	}
+}
+
+func TestScannerAPIVersion(t *testing.T) {
+	tests := []struct {
+		name       string
+		header     string
+		minVersion int
+		want       int
+		wantErr    bool
+	}{
+		{name: "absent is version 1", want: 1},
+		{name: "current", header: "2", want: 2},
+		{name: "previous", header: "1", want: 1},
+		{name: "too old", header: "1", minVersion: 2, wantErr: true},
+		{name: "absent below minimum", minVersion: 2, wantErr: true},
+		{name: "too new", header: "3", wantErr: true},
+		{name: "not a number", header: "two", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodPost, "/api/scanner/results", nil)
+			if tt.header != "" {
+				r.Header.Set(api.ScannerAPIVersionHeader, tt.header)
+			}
+			h := &ScannerHandlers{MinAPIVersion: tt.minVersion}
+			got, err := h.apiVersion(r)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("apiVersion() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("apiVersion() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDecodeSubmitBatch(t *testing.T) {
+	body := `{"batch_id": 7, "domains_checked": 3, "loc_records": [{"fqdn": "a.example.com"}],
+		"parse_errors": [{"fqdn": "b.example.com"}], "keep_best_precision": true}`
+
+	// Version 1 predates parse_errors and keep_best_precision, so they are ignored
+	v1, err := decodeSubmitBatch(strings.NewReader(body), 1)
+	if err != nil {
+		t.Fatalf("decodeSubmitBatch(v1) error: %v", err)
+	}
+	if v1.BatchID != 7 || v1.DomainsChecked != 3 || len(v1.LOCRecords) != 1 || v1.ParseErrors != nil || v1.KeepBestPrecision {
+		t.Errorf("decodeSubmitBatch(v1) = %+v", v1)
+	}
+
+	v2, err := decodeSubmitBatch(strings.NewReader(body), 2)
+	if err != nil {
+		t.Fatalf("decodeSubmitBatch(v2) error: %v", err)
+	}
+	if v2.BatchID != 7 || len(v2.ParseErrors) != 1 || !v2.KeepBestPrecision {
+		t.Errorf("decodeSubmitBatch(v2) = %+v", v2)
+	}
+}
+
+func TestValidDomain(t *testing.T) {
+	tests := []struct {
+		in   string
+		want bool
+	}{
+		{"example.com", true},
+		{"_loc.sub-domain.example.co.uk", true},
+		{"localhost", true},
+		{"", false},
+		{"example..com", false},
+		{".example.com", false},
+		{"Example.com", false}, // Callers lowercase first
+		{"exa mple.com", false},
+		{"%.com", false},
+		{strings.Repeat("a", 64) + ".com", false},
+		{strings.Repeat("a.", 127) + "com", false},
+	}
+	for _, tt := range tests {
+		if got := validDomain(tt.in); got != tt.want {
+			t.Errorf("validDomain(%q) = %v, want %v", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestCursorRoundTrip(t *testing.T) {
+	rec := api.PublicLOCRecord{
+		FQDN:       "a b.example.com", // Spaces can't occur, but must not confuse decoding
+		RawRecord:  "52 22 23.000 N 4 53 32.000 E -2.00m 1m 10000m 10m",
+		LastSeenAt: time.Date(2024, 6, 1, 12, 0, 0, 123456000, time.FixedZone("CEST", 2*3600)),
+	}
+	got, err := decodeCursor(encodeCursor(rec))
+	if err != nil {
+		t.Fatalf("decodeCursor() error: %v", err)
+	}
+	if !got.LastSeenAt.Equal(rec.LastSeenAt) || got.FQDN != rec.FQDN || got.RawRecord != rec.RawRecord {
+		t.Errorf("decodeCursor() = %+v, want %v %s %s", got, rec.LastSeenAt, rec.FQDN, rec.RawRecord)
+	}
+
+	// Cursors issued before records were keyed by raw record still decode
+	old := base64.RawURLEncoding.EncodeToString([]byte("2024-06-01T10:00:00Z x.example.com"))
+	if got, err := decodeCursor(old); err != nil || got.FQDN != "x.example.com" || got.RawRecord != "" {
+		t.Errorf("decodeCursor(old) = %+v, %v", got, err)
+	}
+
+	for _, s := range []string{"", "!!!", "bm90LWEtY3Vyc29y", cursorEnd} {
+		if _, err := decodeCursor(s); err == nil {
+			t.Errorf("decodeCursor(%q) should fail", s)
+		}
+	}
+}
+
+func TestCursorPagination(t *testing.T) {
+	t0 := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	records := []api.PublicLOCRecord{
+		{FQDN: "first.example.com", LastSeenAt: t0},
+		{FQDN: "last.example.com", LastSeenAt: t0},
+	}
+	cursor := &db.RecordCursor{LastSeenAt: t0, FQDN: "x.example.com"}
+
+	tests := []struct {
+		name               string
+		records            []api.PublicLOCRecord
+		cursor             *db.RecordCursor
+		backward, more     bool
+		wantNext, wantPrev bool
+	}{
+		{name: "forward from start with more", records: records, more: true, wantNext: true},
+		{name: "forward last page", records: records, cursor: cursor, wantPrev: true},
+		{name: "forward middle", records: records, cursor: cursor, more: true, wantNext: true, wantPrev: true},
+		{name: "last page via before=end", records: records, backward: true, more: true, wantPrev: true},
+		{name: "backward to first page", records: records, cursor: cursor, backward: true, wantNext: true},
+		{name: "empty page", cursor: cursor, more: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := cursorPagination(tt.records, 10, 2, tt.cursor, tt.backward, tt.more)
+			if (p.NextCursor != "") != tt.wantNext || p.HasMore != tt.wantNext {
+				t.Errorf("next cursor = %q, has_more = %v, want next: %v", p.NextCursor, p.HasMore, tt.wantNext)
+			}
+			if (p.PrevCursor != "") != tt.wantPrev {
+				t.Errorf("prev cursor = %q, want prev: %v", p.PrevCursor, tt.wantPrev)
+			}
+			if tt.wantNext {
+				if c, _ := decodeCursor(p.NextCursor); c == nil || c.FQDN != "last.example.com" {
+					t.Errorf("next cursor should point at the last record, got %+v", c)
+				}
+			}
+			if tt.wantPrev {
+				if c, _ := decodeCursor(p.PrevCursor); c == nil || c.FQDN != "first.example.com" {
+					t.Errorf("prev cursor should point at the first record, got %+v", c)
+				}
+			}
+		})
+	}
+}
+
+func TestParsePruneThreshold(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    time.Duration
+		wantErr bool
+	}{
+		{in: "720h", want: 720 * time.Hour},
+		{in: "2m", want: 2 * time.Minute},
+		{in: "", wantErr: true},
+		{in: "30d", wantErr: true},
+		{in: "1m", wantErr: true}, // Below heartbeat timeout
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			got, err := parsePruneThreshold(tt.in, 2*time.Minute)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parsePruneThreshold(%q) error = %v, wantErr %v", tt.in, err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("parsePruneThreshold(%q) = %v, want %v", tt.in, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestListAudit_InvalidSince(t *testing.T) {
+	h := &AdminHandlers{}
+	rec := httptest.NewRecorder()
+	h.ListAudit(rec, httptest.NewRequest(http.MethodGet, "/api/admin/audit?since=yesterday", nil))
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("status = %d, want 400", rec.Code)
+	}
+}
+
+func TestParseDensityGrid(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    float64
+		wantErr bool
+	}{
+		{in: "", want: 1},
+		{in: "0.5", want: 0.5},
+		{in: "0.01", want: 0.01},
+		{in: "90", want: 90},
+		{in: "0", wantErr: true},
+		{in: "-1", wantErr: true},
+		{in: "0.001", wantErr: true},
+		{in: "91", wantErr: true},
+		{in: "NaN", wantErr: true},
+		{in: "one", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			got, err := parseDensityGrid(tt.in)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseDensityGrid(%q) error = %v, wantErr %v", tt.in, err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("parseDensityGrid(%q) = %v, want %v", tt.in, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseLimit(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    int
+		wantErr bool
+	}{
+		{in: "", want: 100},
+		{in: "1", want: 1},
+		{in: "1000", want: 1000},
+		{in: "5000", want: 1000},
+		{in: "0", wantErr: true},
+		{in: "-3", wantErr: true},
+		{in: "ten", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			got, err := parseLimit(tt.in, defaultDensityLimit, maxDensityLimit)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseLimit(%q) error = %v, wantErr %v", tt.in, err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("parseLimit(%q) = %v, want %v", tt.in, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMarkFreshness(t *testing.T) {
+	now := time.Date(2024, 6, 10, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name       string
+		lastSeen   time.Time
+		staleAfter time.Duration
+		wantAge    int64
+		wantStale  bool
+	}{
+		{name: "fresh", lastSeen: now.Add(-90 * time.Minute), staleAfter: 24 * time.Hour, wantAge: 5400},
+		{name: "stale", lastSeen: now.Add(-48 * time.Hour), staleAfter: 24 * time.Hour, wantAge: 172800, wantStale: true},
+		{name: "at threshold", lastSeen: now.Add(-24 * time.Hour), staleAfter: 24 * time.Hour, wantAge: 86400},
+		{name: "never stale", lastSeen: now.Add(-48 * time.Hour), wantAge: 172800},
+		{name: "clock skew", lastSeen: now.Add(time.Minute), staleAfter: 24 * time.Hour, wantAge: 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := api.PublicLOCRecord{LastSeenAt: tt.lastSeen}
+			markFreshness(&rec, now, tt.staleAfter)
+			if rec.AgeSeconds != tt.wantAge || rec.IsStale != tt.wantStale {
+				t.Errorf("age %d stale %v, want %d %v", rec.AgeSeconds, rec.IsStale, tt.wantAge, tt.wantStale)
+			}
+		})
+	}
+}
+
+func TestParseRecordUpdate(t *testing.T) {
+	tests := []struct {
+		name    string
+		fqdn    string
+		body    string
+		want    string
+		wantErr bool
+	}{
+		{name: "parent", fqdn: "www.example.co.uk", body: `{"root_domain": "example.co.uk"}`, want: "example.co.uk"},
+		{name: "self", fqdn: "example.com", body: `{"root_domain": "Example.COM."}`, want: "example.com"},
+		{name: "public suffix", fqdn: "www.example.co.uk", body: `{"root_domain": "co.uk"}`, wantErr: true},
+		{name: "unrelated", fqdn: "www.example.com", body: `{"root_domain": "example.org"}`, wantErr: true},
+		{name: "label suffix", fqdn: "www.badexample.com", body: `{"root_domain": "example.com"}`, wantErr: true},
+		{name: "missing", fqdn: "example.com", body: `{}`, wantErr: true},
+		{name: "empty", fqdn: "example.com", body: `{"root_domain": ""}`, wantErr: true},
+		{name: "not a string", fqdn: "example.com", body: `{"root_domain": 1}`, wantErr: true},
+		{name: "coordinates", fqdn: "example.com", body: `{"root_domain": "example.com", "latitude": 1}`, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var fields map[string]json.RawMessage
+			if err := json.Unmarshal([]byte(tt.body), &fields); err != nil {
+				t.Fatal(err)
+			}
+			got, err := parseRecordUpdate(tt.fqdn, fields)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseRecordUpdate(%q, %s) error = %v, wantErr %v", tt.fqdn, tt.body, err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("parseRecordUpdate(%q, %s) = %q, want %q", tt.fqdn, tt.body, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLocationFeature(t *testing.T) {
+	seen := time.Date(2024, 6, 1, 12, 0, 0, 0, time.FixedZone("CEST", 2*3600))
+	loc := api.AggregatedLocation{
+		FQDNs:       []string{"a.example.com", "b.example.com"},
+		RootDomains: []string{"example.com"},
+		RawRecord:   "52 22 23.000 N 4 53 32.000 E -2.00m 1m 10000m 10m",
+		Latitude:    52.373,
+		Longitude:   4.892,
+		AltitudeM:   -2,
+		SizeM:       1,
+		Count:       2,
+		FirstSeenAt: seen,
+		LastSeenAt:  seen,
+	}
+
+	data, err := json.Marshal(locationFeature(loc, false))
+	if err != nil {
+		t.Fatalf("marshal: %v", err)
+	}
+	var got struct {
+		Geometry   api.GeoJSONPoint `json:"geometry"`
+		Properties map[string]any   `json:"properties"`
+	}
+	if err := json.Unmarshal(data, &got); err != nil {
+		t.Fatalf("unmarshal: %v", err)
+	}
+
+	if len(got.Geometry.Coordinates) != 2 || got.Geometry.Coordinates[0] != 4.892 {
+		t.Errorf("coordinates = %v, want [lon, lat]", got.Geometry.Coordinates)
+	}
+	wantKeys := []string{"fqdns", "root_domains", "raw_record", "altitude_m", "size_diameter_m",
+		"size_radius_m", "count", "first_seen", "last_seen"}
+	if len(got.Properties) != len(wantKeys) {
+		t.Errorf("got %d properties, want %d: %v", len(got.Properties), len(wantKeys), got.Properties)
+	}
+	for _, k := range wantKeys {
+		if _, ok := got.Properties[k]; !ok {
+			t.Errorf("missing property %q", k)
+		}
+	}
+	if got.Properties["first_seen"] != "2024-06-01T10:00:00Z" {
+		t.Errorf("first_seen = %v, want RFC 3339 UTC", got.Properties["first_seen"])
+	}
+	if got.Properties["size_radius_m"] != 0.5 {
+		t.Errorf("size_radius_m = %v, want 0.5", got.Properties["size_radius_m"])
+	}
+}
+
+func TestStatsCache(t *testing.T) {
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	c := NewStatsCache(30 * time.Second)
+	c.now = func() time.Time { return now }
+
+	loads := 0
+	load := func(context.Context) (api.StatsResponse, error) {
+		loads++
+		return api.StatsResponse{TotalLOCRecords: loads}, nil
+	}
+
+	for range 3 {
+		got, err := c.Get(context.Background(), load)
+		if err != nil || got.TotalLOCRecords != 1 {
+			t.Fatalf("Get() = %+v, %v; want first load", got, err)
+		}
+	}
+
+	now = now.Add(31 * time.Second)
+	if got, _ := c.Get(context.Background(), load); got.TotalLOCRecords != 2 {
+		t.Errorf("after expiry TotalLOCRecords = %d, want reload", got.TotalLOCRecords)
+	}
+
+	now = now.Add(31 * time.Second)
+	failing := func(context.Context) (api.StatsResponse, error) { return api.StatsResponse{}, errors.New("db down") }
+	if _, err := c.Get(context.Background(), failing); err == nil {
+		t.Error("expected load error")
+	}
+	if got, _ := c.Get(context.Background(), load); got.TotalLOCRecords != 3 {
+		t.Errorf("error was cached: TotalLOCRecords = %d, want 3", got.TotalLOCRecords)
+	}
+
+	if !c.Purge() {
+		t.Error("Purge() = false, want true for a configured cache")
+	}
+	if got, _ := c.Get(context.Background(), load); got.TotalLOCRecords != 4 {
+		t.Errorf("after purge TotalLOCRecords = %d, want reload", got.TotalLOCRecords)
+	}
+
+	var disabled *StatsCache
+	if disabled.Purge() {
+		t.Error("Purge() on a nil cache = true, want false")
+	}
+}
+
+func TestServeExport_Ranges(t *testing.T) {
+	data := []byte(`{"type":"FeatureCollection","features":[]}`)
+
+	rec := httptest.NewRecorder()
+	serveExport(rec, httptest.NewRequest(http.MethodGet, "/records.geojson", nil), "application/geo+json", data)
+	if rec.Code != http.StatusOK || rec.Body.String() != string(data) {
+		t.Fatalf("full request: status %d body %q", rec.Code, rec.Body.String())
+	}
+	if rec.Header().Get("Accept-Ranges") != "bytes" {
+		t.Errorf("Accept-Ranges = %q, want bytes", rec.Header().Get("Accept-Ranges"))
+	}
+	etag := rec.Header().Get("ETag")
+	if etag == "" {
+		t.Fatal("missing ETag")
+	}
+
+	// Resume from byte 10 while the export is unchanged
+	req := httptest.NewRequest(http.MethodGet, "/records.geojson", nil)
+	req.Header.Set("Range", "bytes=10-")
+	req.Header.Set("If-Range", etag)
+	rec = httptest.NewRecorder()
+	serveExport(rec, req, "application/geo+json", data)
+	if rec.Code != http.StatusPartialContent || rec.Body.String() != string(data[10:]) {
+		t.Errorf("ranged request: status %d body %q", rec.Code, rec.Body.String())
+	}
+
+	// The export changed since the first download: send it whole
+	req.Header.Set("If-Range", `"stale"`)
+	rec = httptest.NewRecorder()
+	serveExport(rec, req, "application/geo+json", data)
+	if rec.Code != http.StatusOK || rec.Body.String() != string(data) {
+		t.Errorf("stale If-Range: status %d, want full 200", rec.Code)
+	}
+}
+
+func TestSQLiteExport(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "locplace.db")
+	e, err := newSQLiteExport(ctx, path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	seen := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+	err = e.add(ctx, api.PublicLOCRecord{
+		FQDN:        "o'brien.example.com",
+		RootDomain:  "example.com",
+		RawRecord:   "52 22 23.000 N 4 53 32.000 E -2.00m 1m 10000m 10m",
+		Latitude:    52.37305555555556,
+		Longitude:   4.892222222222222,
+		AltitudeM:   -2,
+		SizeM:       1,
+		HorizPrecM:  10000,
+		VertPrecM:   10,
+		FirstSeenAt: seen,
+		LastSeenAt:  seen,
+		CNAMEChain:  []string{"loc.example.net"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := e.finish(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	sdb, err := sql.Open("sqlite", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sdb.Close()
+
+	var fqdn, lastSeen, chain string
+	var lat float64
+	err = sdb.QueryRow(`SELECT fqdn, latitude, last_seen_at, cname_chain FROM loc_records`).Scan(&fqdn, &lat, &lastSeen, &chain)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fqdn != "o'brien.example.com" || lat != 52.37305555555556 || lastSeen != "2024-06-01T12:00:00Z" || chain != `["loc.example.net"]` {
+		t.Errorf("row = %q, %v, %q, %q", fqdn, lat, lastSeen, chain)
+	}
+
+	rows, err := sdb.Query(`SELECT name FROM sqlite_master WHERE type = 'index' AND tbl_name = 'loc_records' AND sql IS NOT NULL ORDER BY name`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rows.Close()
+	var indexes []string
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			t.Fatal(err)
+		}
+		indexes = append(indexes, name)
+	}
+	if want := []string{"idx_loc_records_coords", "idx_loc_records_root_domain"}; !slices.Equal(indexes, want) {
+		t.Errorf("indexes = %v, want %v", indexes, want)
+	}
+}
+
+func TestParseCRS(t *testing.T) {
+	tests := []struct {
+		in       string
+		mercator bool
+		wantErr  bool
+	}{
+		{in: "", mercator: false},
+		{in: "4326", mercator: false},
+		{in: "epsg:4326", mercator: false},
+		{in: "3857", mercator: true},
+		{in: "EPSG:3857", mercator: true},
+		{in: "900913", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			got, err := parseCRS(tt.in)
+			if (err != nil) != tt.wantErr || got != tt.mercator {
+				t.Errorf("parseCRS(%q) = %v, %v; want %v, error %v", tt.in, got, err, tt.mercator, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestRoundCoord(t *testing.T) {
+	tests := []struct {
+		v        float64
+		decimals int
+		want     float64
+	}{
+		{52.3731234, -1, 52.3731234},
+		{52.3731234, 0, 52},
+		{52.3731234, 3, 52.373},
+		{-4.8956, 2, -4.9},
+		{1.23456789012345, 20, 1.2345678901},
+	}
+	for _, tt := range tests {
+		if got := roundCoord(tt.v, tt.decimals); got != tt.want {
+			t.Errorf("roundCoord(%v, %d) = %v, want %v", tt.v, tt.decimals, got, tt.want)
+		}
+	}
+}
+
+func TestParseDecimals(t *testing.T) {
+	tests := []struct {
+		query   string
+		def     int
+		want    int
+		wantErr bool
+	}{
+		{query: "", def: -1, want: -1},
+		{query: "", def: 4, want: 4},
+		{query: "", def: 99, want: maxCoordDecimals},
+		{query: "decimals=3", def: -1, want: 3},
+		{query: "decimals=-2", def: 6, want: 0},
+		{query: "decimals=50", def: -1, want: maxCoordDecimals},
+		{query: "decimals=abc", def: -1, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "/?"+tt.query, nil)
+			got, err := parseDecimals(r, tt.def)
+			if (err != nil) != tt.wantErr || (!tt.wantErr && got != tt.want) {
+				t.Errorf("parseDecimals(%q, %d) = %d, %v; want %d, error %v", tt.query, tt.def, got, err, tt.want, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestBuildSitemap(t *testing.T) {
+	set := buildSitemap("https://loc.place", []string{"nikhef.nl", "a&b.example"}, 1)
+	data, err := xml.Marshal(set)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `<urlset xmlns="http://www.sitemaps.org/schemas/sitemap/0.9">` +
+		`<url><loc>https://loc.place/</loc></url>` +
+		`<url><loc>https://loc.place/?q=nikhef.nl</loc></url>` +
+		`<url><loc>https://loc.place/?q=a%26b.example</loc></url></urlset>`
+	if string(data) != want {
+		t.Errorf("sitemap =\n%s\nwant\n%s", data, want)
+	}
+
+	if got := buildSitemap("https://loc.place", []string{"x.nl"}, 2).URLs; len(got) != 1 {
+		t.Errorf("page 2 has %d URLs, want only the domain", len(got))
+	}
+
+	index := buildSitemapIndex("https://loc.place", 2)
+	if len(index.Sitemaps) != 2 || index.Sitemaps[1].Loc != "https://loc.place/sitemap-2.xml" {
+		t.Errorf("sitemap index = %+v", index.Sitemaps)
+	}
+}
+
+func TestGetRobotsTxt(t *testing.T) {
+	tests := []struct {
+		name       string
+		configured string
+		baseURL    string
+		want       string
+	}{
+		{"generated", "", "", "Sitemap: https://loc.place/sitemap.xml\n"},
+		{"generated with base url", "", "https://example.org/locplace", "Sitemap: https://example.org/locplace/sitemap.xml\n"},
+		{"configured", "User-agent: *\nDisallow: /\n", "", "User-agent: *\nDisallow: /\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := &PublicHandlers{RobotsTxt: tt.configured, BaseURL: tt.baseURL}
+			req := httptest.NewRequest(http.MethodGet, "https://loc.place/robots.txt", nil)
+			rr := httptest.NewRecorder()
+			h.GetRobotsTxt(rr, req)
+			if !strings.HasSuffix(rr.Body.String(), tt.want) {
+				t.Errorf("robots.txt = %q, want suffix %q", rr.Body.String(), tt.want)
+			}
+		})
+	}
+}
+
+func TestParseBaseURL(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    string
+		wantErr bool
+	}{
+		{in: "https://loc.place/", want: "https://loc.place"},
+		{in: "http://example.org:8080/locplace", want: "http://example.org:8080/locplace"},
+		{in: "loc.place", wantErr: true},
+		{in: "ftp://loc.place", wantErr: true},
+		{in: "https://loc.place/?x=1", wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := ParseBaseURL(tt.in)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("ParseBaseURL(%q) = %q, %v; want %q, error %v", tt.in, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
+
+func TestRequestBaseURL(t *testing.T) {
+	tests := []struct {
+		name   string
+		target string
+		scheme string // As applied by middleware.ForwardedHost
+		proto  string
+		want   string
+	}{
+		{name: "plain", target: "http://loc.place/", want: "http://loc.place"},
+		{name: "tls", target: "https://loc.place/", want: "https://loc.place"},
+		{name: "untrusted proxy https", target: "http://loc.place/", proto: "https", want: "https://loc.place"},
+		{name: "untrusted proxy http", target: "https://loc.place/", proto: "http", want: "https://loc.place"},
+		{name: "trusted proxy scheme wins", target: "http://loc.place/", scheme: "http", proto: "https", want: "http://loc.place"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, tt.target, nil)
+			req.URL.Scheme = tt.scheme
+			if tt.proto != "" {
+				req.Header.Set("X-Forwarded-Proto", tt.proto)
+			}
+			if got := requestBaseURL(req); got != tt.want {
+				t.Errorf("requestBaseURL() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseTerrainParam(t *testing.T) {
+	tests := []struct {
+		query      string
+		want       bool
+		wantStatus int // 0 = no error
+	}{
+		{query: "", want: false},
+		{query: "terrain=false", want: false},
+		{query: "terrain=maybe", wantStatus: http.StatusBadRequest},
+		{query: "terrain=true", want: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			if tt.want && !api.TerrainAvailable() {
+				tt.want, tt.wantStatus = false, http.StatusNotImplemented
+			}
+			req := httptest.NewRequest(http.MethodGet, "/api/public/records/nikhef.nl?"+tt.query, nil)
+			got, err := parseTerrainParam(req)
+			if tt.wantStatus != 0 {
+				rec := httptest.NewRecorder()
+				if err == nil {
+					t.Fatal("expected error")
+				}
+				writeTerrainParamError(rec, err)
+				if rec.Code != tt.wantStatus {
+					t.Errorf("status = %d, want %d", rec.Code, tt.wantStatus)
+				}
+				return
+			}
+			if err != nil || got != tt.want {
+				t.Errorf("parseTerrainParam() = %v, %v; want %v", got, err, tt.want)
+			}
+		})
+	}
+}
+
+func TestPaginationLinks(t *testing.T) {
+	next := 200
+	tests := []struct {
+		name string
+		base string
+		url  string
+		p    api.Pagination
+		want []string
+	}{
+		{
+			name: "middle offset page",
+			base: "https://example.org/locplace",
+			url:  "/api/public/records?domain=nikhef.nl&offset=100&limit=100",
+			p:    api.Pagination{Total: 450, Limit: 100, Offset: 100, HasMore: true, NextOffset: &next},
+			want: []string{
+				`<https://example.org/locplace/api/public/records?domain=nikhef.nl&limit=100&offset=0>; rel="first"`,
+				`<https://example.org/locplace/api/public/records?domain=nikhef.nl&limit=100&offset=0>; rel="prev"`,
+				`<https://example.org/locplace/api/public/records?domain=nikhef.nl&limit=100&offset=200>; rel="next"`,
+				`<https://example.org/locplace/api/public/records?domain=nikhef.nl&limit=100&offset=400>; rel="last"`,
+			},
+		},
+		{
+			name: "only page",
+			url:  "/api/admin/scan-errors",
+			p:    api.Pagination{Total: 3, Limit: 100},
+			want: []string{
+				`</api/admin/scan-errors?limit=100&offset=0>; rel="first"`,
+				`</api/admin/scan-errors?limit=100&offset=0>; rel="last"`,
+			},
+		},
+		{
+			name: "empty",
+			url:  "/api/admin/scan-errors",
+			p:    api.Pagination{Limit: 100},
+			want: []string{`</api/admin/scan-errors?limit=100&offset=0>; rel="first"`},
+		},
+		{
+			name: "cursor page",
+			url:  "/api/public/records?after=abc&limit=10",
+			p:    api.Pagination{Total: 50, Limit: 10, HasMore: true, NextCursor: "def", PrevCursor: "xyz"},
+			want: []string{
+				`</api/public/records?limit=10>; rel="first"`,
+				`</api/public/records?before=xyz&limit=10>; rel="prev"`,
+				`</api/public/records?after=def&limit=10>; rel="next"`,
+				`</api/public/records?before=end&limit=10>; rel="last"`,
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			u, err := url.Parse(tt.url)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got := paginationLinks(tt.base, u, tt.p)
+			if strings.Join(got, "\n") != strings.Join(tt.want, "\n") {
+				t.Errorf("paginationLinks() =\n%s\nwant\n%s", strings.Join(got, "\n"), strings.Join(tt.want, "\n"))
+			}
+		})
+	}
+}
+
+func TestParseTimeBoundary(t *testing.T) {
+	amsterdam, err := time.LoadLocation("Europe/Amsterdam")
+	if err != nil {
+		t.Skipf("time zone data unavailable: %v", err)
+	}
+	saoPaulo, err := time.LoadLocation("America/Sao_Paulo")
+	if err != nil {
+		t.Skipf("time zone data unavailable: %v", err)
+	}
+
+	tests := []struct {
+		name     string
+		in       string
+		loc      *time.Location
+		endOfDay bool
+		want     time.Time
+		wantErr  bool
+	}{
+		{name: "date in UTC", in: "2024-06-01", loc: time.UTC, want: time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)},
+		{name: "end of day in UTC", in: "2024-06-01", loc: time.UTC, endOfDay: true, want: time.Date(2024, 6, 2, 0, 0, 0, 0, time.UTC)},
+		{name: "timestamp ignores tz", in: "2024-06-01T12:00:00+02:00", loc: amsterdam, want: time.Date(2024, 6, 1, 10, 0, 0, 0, time.UTC)},
+		{name: "summer date", in: "2024-06-01", loc: amsterdam, want: time.Date(2024, 5, 31, 22, 0, 0, 0, time.UTC)},
+		// Clocks go forward on 2024-03-31, so that day is 23 hours long
+		{name: "spring forward start", in: "2024-03-31", loc: amsterdam, want: time.Date(2024, 3, 30, 23, 0, 0, 0, time.UTC)},
+		{name: "spring forward end", in: "2024-03-31", loc: amsterdam, endOfDay: true, want: time.Date(2024, 3, 31, 22, 0, 0, 0, time.UTC)},
+		// Clocks go back on 2024-10-27, so that day is 25 hours long
+		{name: "fall back end", in: "2024-10-27", loc: amsterdam, endOfDay: true, want: time.Date(2024, 10, 27, 23, 0, 0, 0, time.UTC)},
+		// Midnight did not exist on 2018-11-04 in Sao Paulo; the day began at 01:00 -02
+		{name: "missing midnight", in: "2018-11-04", loc: saoPaulo, want: time.Date(2018, 11, 4, 3, 0, 0, 0, time.UTC)},
+		{name: "year end", in: "2024-12-31", loc: time.UTC, endOfDay: true, want: time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)},
+		{name: "invalid date", in: "2024-02-30", loc: time.UTC, wantErr: true},
+		{name: "garbage", in: "last week", loc: time.UTC, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseTimeBoundary(tt.in, tt.loc, tt.endOfDay)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseTimeBoundary(%q) error = %v, wantErr %v", tt.in, err, tt.wantErr)
+			}
+			if !tt.wantErr && !got.Equal(tt.want) {
+				t.Errorf("parseTimeBoundary(%q) = %v, want %v", tt.in, got, tt.want)
+			}
+		})
+	}
+}
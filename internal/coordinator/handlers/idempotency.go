@@ -0,0 +1,69 @@
+package handlers
+
+import (
+	"sync"
+	"time"
+
+	"github.com/locplace/scanner/pkg/api"
+)
+
+// IdempotencyCache remembers the responses to recent result submissions by
+// Idempotency-Key, so a scanner retrying after a network error gets the
+// original response instead of having the batch processed twice.
+type IdempotencyCache struct {
+	ttl time.Duration
+	now func() time.Time
+
+	mu      sync.Mutex
+	entries map[string]*idempotencyEntry
+}
+
+type idempotencyEntry struct {
+	resp    *api.SubmitBatchResponse // nil while the first request is in flight
+	expires time.Time
+}
+
+// NewIdempotencyCache creates a cache that keeps keys for ttl.
+func NewIdempotencyCache(ttl time.Duration) *IdempotencyCache {
+	return &IdempotencyCache{
+		ttl:     ttl,
+		now:     time.Now,
+		entries: make(map[string]*idempotencyEntry),
+	}
+}
+
+// Begin claims key for processing. If the key was already completed, the
+// stored response is returned. If another request with the key is still in
+// flight, inFlight is true. Otherwise both are zero and the caller must call
+// Finish or Abort.
+func (c *IdempotencyCache) Begin(key string) (resp *api.SubmitBatchResponse, inFlight bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := c.now()
+	for k, e := range c.entries {
+		if now.After(e.expires) {
+			delete(c.entries, k)
+		}
+	}
+
+	if e, ok := c.entries[key]; ok {
+		return e.resp, e.resp == nil
+	}
+	c.entries[key] = &idempotencyEntry{expires: now.Add(c.ttl)}
+	return nil, false
+}
+
+// Finish stores the response for a key claimed with Begin.
+func (c *IdempotencyCache) Finish(key string, resp api.SubmitBatchResponse) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.entries[key] = &idempotencyEntry{resp: &resp, expires: c.now().Add(c.ttl)}
+}
+
+// Abort releases a key claimed with Begin so the request can be retried.
+func (c *IdempotencyCache) Abort(key string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.entries, key)
+}
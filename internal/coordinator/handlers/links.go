@@ -0,0 +1,67 @@
+package handlers
+
+import (
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/locplace/scanner/pkg/api"
+)
+
+// setPaginationLinks sets an RFC 8288 Link header with first, prev, next
+// and last page URLs, so generic clients can page without reading the
+// envelope. Requests paging by after/before get cursor links; others get
+// offset links. URLs are absolute, on baseURL (see resolveBaseURL), and keep
+// the request's other parameters.
+func setPaginationLinks(w http.ResponseWriter, r *http.Request, baseURL string, p api.Pagination) {
+	if links := paginationLinks(baseURL, r.URL, p); len(links) > 0 {
+		w.Header().Set("Link", strings.Join(links, ", "))
+	}
+}
+
+// paginationLinks returns the Link header values for page p of u, with
+// baseURL in front of its path.
+func paginationLinks(baseURL string, u *url.URL, p api.Pagination) []string {
+	q := u.Query()
+	link := func(rel string, set map[string]string) string {
+		v := url.Values{}
+		for k, vals := range q {
+			if k != "offset" && k != "after" && k != "before" {
+				v[k] = vals
+			}
+		}
+		v.Set("limit", strconv.Itoa(p.Limit))
+		for k, val := range set {
+			v.Set(k, val)
+		}
+		return "<" + baseURL + u.Path + "?" + v.Encode() + `>; rel="` + rel + `"`
+	}
+
+	var links []string
+	if q.Get("after") != "" || q.Get("before") != "" {
+		links = append(links, link("first", nil))
+		if p.PrevCursor != "" {
+			links = append(links, link("prev", map[string]string{"before": p.PrevCursor}))
+		}
+		if p.NextCursor != "" {
+			links = append(links, link("next", map[string]string{"after": p.NextCursor}))
+		}
+		return append(links, link("last", map[string]string{"before": cursorEnd}))
+	}
+
+	offsetLink := func(rel string, offset int) string {
+		return link(rel, map[string]string{"offset": strconv.Itoa(offset)})
+	}
+	links = append(links, offsetLink("first", 0))
+	if p.Offset > 0 {
+		links = append(links, offsetLink("prev", max(0, p.Offset-p.Limit)))
+	}
+	if p.NextOffset != nil {
+		links = append(links, offsetLink("next", *p.NextOffset))
+	}
+	if p.Limit > 0 && p.Total > 0 {
+		links = append(links, offsetLink("last", (p.Total-1)/p.Limit*p.Limit))
+	}
+	return links
+}
@@ -1,32 +1,172 @@
 package handlers
 
 import (
+	"bytes"
+	"cmp"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"log"
+	"math"
 	"net/http"
+	"slices"
 	"strconv"
+	"strings"
 	"time"
 
+	"github.com/go-chi/chi/v5"
+
 	"github.com/locplace/scanner/internal/coordinator/db"
 	"github.com/locplace/scanner/pkg/api"
+	"github.com/locplace/scanner/pkg/loc"
 )
 
 // PublicHandlers contains handlers for public endpoints.
 type PublicHandlers struct {
 	DB               *db.DB
 	HeartbeatTimeout time.Duration
+	FeedSize         int // Number of entries in the Atom feed (0 = 50)
+	CacheTTLs        CacheTTLs
+	Decimals         ExportDecimals // Default coordinate rounding per export (see ?decimals=)
+	StatsCache       *StatsCache    // Optional: caches GET /api/public/stats
+	RobotsTxt        string         // Served as /robots.txt ("" = generated, pointing to the sitemap)
+	BaseURL          string         // Public base URL for absolute links ("" = derived from the request)
+	// StaleAfter is how long after last_seen_at records are marked
+	// is_stale (0 = never).
+	StaleAfter time.Duration
+	// ChangesRetention is how long removals are kept for the changes feed;
+	// older positions get 410 Gone (0 = forever).
+	ChangesRetention time.Duration
+}
+
+// CacheTTLs sets the Cache-Control max-age per response format.
+// Zero means no Cache-Control header is sent.
+type CacheTTLs struct {
+	Records time.Duration // GET /api/public/records
+	GeoJSON time.Duration // GET /api/public/records.geojson
+	JSONL   time.Duration // GET /api/public/records.jsonl
+	Feed    time.Duration // GET /api/public/records/feed.atom
+}
+
+// DefaultCacheTTLs returns the cache TTLs used when none are configured.
+func DefaultCacheTTLs() CacheTTLs {
+	return CacheTTLs{
+		GeoJSON: 5 * time.Minute,
+		Feed:    15 * time.Minute,
+	}
+}
+
+// setCacheControl sets a public max-age for ttl, if positive.
+func setCacheControl(w http.ResponseWriter, ttl time.Duration) {
+	if ttl > 0 {
+		w.Header().Set("Cache-Control", "public, max-age="+strconv.Itoa(int(ttl.Seconds())))
+	}
 }
 
 // ListRecords handles GET /api/public/records.
+// Pages by offset, or by keyset cursor with after/before: after=<cursor>
+// pages forward, before=<cursor> pages backward and before=end returns the
+// last page. Cursors come from next_cursor/prev_cursor of earlier responses.
+// count_only=true returns just the number of matching records.
 func (h *PublicHandlers) ListRecords(w http.ResponseWriter, r *http.Request) {
 	limit := parseIntParam(r, "limit", 100)
 	offset := parseIntParam(r, "offset", 0)
-	domain := r.URL.Query().Get("domain")
 
 	if limit > 1000 {
 		limit = 1000
 	}
 
-	records, total, err := h.DB.ListLOCRecords(r.Context(), limit, offset, domain)
+	filter, err := parseRecordFilter(r)
+	if err != nil {
+		writeError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if s := r.URL.Query().Get("count_only"); s != "" {
+		countOnly, err := strconv.ParseBool(s)
+		if err != nil {
+			writeError(w, "count_only must be true or false", http.StatusBadRequest)
+			return
+		}
+		if countOnly {
+			h.countRecords(w, r, filter)
+			return
+		}
+	}
+
+	after, before := r.URL.Query().Get("after"), r.URL.Query().Get("before")
+	if after != "" || before != "" {
+		if after != "" && before != "" {
+			writeError(w, "after and before are mutually exclusive", http.StatusBadRequest)
+			return
+		}
+		if offset != 0 {
+			writeError(w, "offset cannot be combined with after or before", http.StatusBadRequest)
+			return
+		}
+		h.listRecordsByCursor(w, r, limit, filter, after, before)
+		return
+	}
+
+	records, total, err := h.DB.ListLOCRecords(r.Context(), limit, offset, filter)
+	if err != nil {
+		writeError(w, "failed to list records", http.StatusInternalServerError)
+		return
+	}
+
+	if records == nil {
+		records = []api.PublicLOCRecord{}
+	}
+
+	setFreshness(records, h.StaleAfter)
+	pagination := api.NewPagination(total, limit, offset, len(records))
+	if len(records) > 0 {
+		// Let offset clients switch to cursors from any page
+		if pagination.HasMore {
+			pagination.NextCursor = encodeCursor(records[len(records)-1])
+		}
+		if offset > 0 {
+			pagination.PrevCursor = encodeCursor(records[0])
+		}
+	}
+
+	setCacheControl(w, h.CacheTTLs.Records)
+	setPaginationLinks(w, r, h.baseURL(r), pagination)
+	writeJSON(w, http.StatusOK, api.ListRecordsResponse{
+		Records:    records,
+		Pagination: pagination,
+	})
+}
+
+// countRecords serves ListRecords with count_only=true, counting the
+// matching records without reading them.
+func (h *PublicHandlers) countRecords(w http.ResponseWriter, r *http.Request, filter db.RecordFilter) {
+	total, err := h.DB.CountMatchingLOCRecords(r.Context(), filter)
+	if err != nil {
+		writeError(w, "failed to count records", http.StatusInternalServerError)
+		return
+	}
+
+	setCacheControl(w, h.CacheTTLs.Records)
+	writeJSON(w, http.StatusOK, api.CountRecordsResponse{Total: total})
+}
+
+// listRecordsByCursor serves a keyset page of ListRecords.
+func (h *PublicHandlers) listRecordsByCursor(w http.ResponseWriter, r *http.Request, limit int, filter db.RecordFilter, after, before string) {
+	backward := before != ""
+	var cursor *db.RecordCursor
+	if s := after + before; s != cursorEnd || !backward {
+		var err error
+		if cursor, err = decodeCursor(s); err != nil {
+			writeError(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+
+	records, total, more, err := h.DB.ListLOCRecordsPage(r.Context(), limit, filter, cursor, backward)
 	if err != nil {
 		writeError(w, "failed to list records", http.StatusInternalServerError)
 		return
@@ -36,41 +176,434 @@ func (h *PublicHandlers) ListRecords(w http.ResponseWriter, r *http.Request) {
 		records = []api.PublicLOCRecord{}
 	}
 
+	setFreshness(records, h.StaleAfter)
+	pagination := cursorPagination(records, total, limit, cursor, backward, more)
+	setCacheControl(w, h.CacheTTLs.Records)
+	setPaginationLinks(w, r, h.baseURL(r), pagination)
 	writeJSON(w, http.StatusOK, api.ListRecordsResponse{
-		Records: records,
-		Total:   total,
-		Limit:   limit,
-		Offset:  offset,
+		Records:    records,
+		Pagination: pagination,
+	})
+}
+
+// ListRecordsJSONL handles GET /api/public/records.jsonl.
+// Streams every record matching the ListRecords filters as JSON Lines
+// (one PublicLOCRecord per line), without pagination. decimals=N rounds
+// latitude and longitude to N decimals.
+func (h *PublicHandlers) ListRecordsJSONL(w http.ResponseWriter, r *http.Request) {
+	filter, err := parseRecordFilter(r)
+	if err != nil {
+		writeError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	decimals, err := parseDecimals(r, h.Decimals.JSONL)
+	if err != nil {
+		writeError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/x-ndjson")
+	w.Header().Set("Accept-Ranges", "none") // Streamed, so the length is unknown up front
+	setCacheControl(w, h.CacheTTLs.JSONL)
+	w.WriteHeader(http.StatusOK)
+
+	extendWriteDeadline(w)
+	flusher, _ := w.(http.Flusher) //nolint:errcheck // Flushing is optional
+	enc := json.NewEncoder(w)
+	count := 0
+	now := time.Now()
+	err = h.DB.StreamLOCRecords(r.Context(), filter, func(rec api.PublicLOCRecord) error {
+		rec.Latitude = roundCoord(rec.Latitude, decimals)
+		rec.Longitude = roundCoord(rec.Longitude, decimals)
+		markFreshness(&rec, now, h.StaleAfter)
+		if err := enc.Encode(rec); err != nil {
+			return err
+		}
+		count++
+		if count%1000 == 0 {
+			extendWriteDeadline(w)
+			if flusher != nil {
+				flusher.Flush()
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		// Headers are already sent; all we can do is stop the stream
+		log.Printf("JSONL export aborted after %d records: %v", count, err)
+	}
+}
+
+// ListRootDomains handles GET /api/public/root-domains.
+// Root domains are only recorded once a LOC record is found, so every domain
+// listed has LOC data; with_loc=false is rejected rather than silently ignored.
+func (h *PublicHandlers) ListRootDomains(w http.ResponseWriter, r *http.Request) {
+	if v := r.URL.Query().Get("with_loc"); v != "" && v != "true" {
+		writeError(w, "only with_loc=true is supported", http.StatusBadRequest)
+		return
+	}
+
+	limit := parseIntParam(r, "limit", 100)
+	offset := parseIntParam(r, "offset", 0)
+
+	if limit > 1000 {
+		limit = 1000
+	}
+
+	domains, total, err := h.DB.ListRootDomainsWithLOC(r.Context(), limit, offset)
+	if err != nil {
+		writeError(w, "failed to list root domains", http.StatusInternalServerError)
+		return
+	}
+
+	if domains == nil {
+		domains = []api.RootDomainCount{}
+	}
+
+	pagination := api.NewPagination(total, limit, offset, len(domains))
+	setPaginationLinks(w, r, h.baseURL(r), pagination)
+	writeJSON(w, http.StatusOK, api.ListRootDomainsResponse{
+		RootDomains: domains,
+		Pagination:  pagination,
+	})
+}
+
+// GetRecordsFeed handles GET /api/public/records/feed.atom.
+// Returns the most recently discovered records as an Atom feed.
+func (h *PublicHandlers) GetRecordsFeed(w http.ResponseWriter, r *http.Request) {
+	size := h.FeedSize
+	if size <= 0 {
+		size = 50
+	}
+
+	records, err := h.DB.ListRecentLOCRecords(r.Context(), size)
+	if err != nil {
+		writeError(w, "failed to get records", http.StatusInternalServerError)
+		return
+	}
+
+	baseURL := h.baseURL(r)
+	feed := buildAtomFeed(baseURL, hostOf(baseURL), records)
+
+	data, err := xml.MarshalIndent(feed, "", "  ")
+	if err != nil {
+		writeError(w, "failed to encode feed", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/atom+xml; charset=utf-8")
+	setCacheControl(w, h.CacheTTLs.Feed)
+	w.WriteHeader(http.StatusOK)
+	_, _ = w.Write([]byte(xml.Header))
+	_, _ = w.Write(data)
+}
+
+// maxRecentRecords caps the limit of GetRecentRecords.
+const maxRecentRecords = 500
+
+// GetRecentRecords handles GET /api/public/records/recent.
+// Returns the records seen most recently, newest first, to surface active
+// and changing records; the Atom feed covers newly discovered ones.
+func (h *PublicHandlers) GetRecentRecords(w http.ResponseWriter, r *http.Request) {
+	limit := min(parseIntParam(r, "limit", 50), maxRecentRecords)
+
+	records, err := h.DB.ListRecentlySeenLOCRecords(r.Context(), limit)
+	if err != nil {
+		writeError(w, "failed to get records", http.StatusInternalServerError)
+		return
+	}
+	if records == nil {
+		records = []api.PublicLOCRecord{}
+	}
+
+	setFreshness(records, h.StaleAfter)
+	setCacheControl(w, h.CacheTTLs.Records)
+	writeJSON(w, http.StatusOK, api.RecentRecordsResponse{Records: records})
+}
+
+// maxParseBodyBytes caps the POST /api/public/parse body. LOC presentation
+// strings are short; anything larger is not a LOC record.
+const maxParseBodyBytes = 4096
+
+// ParseRecord handles POST /api/public/parse.
+// Parses a LOC presentation string with the scanner's lenient parser, or
+// builds one from decimal fields, and returns the record without storing
+// anything. warnings=true adds the record's plausibility warnings and
+// terrain=true its altitude anomaly.
+func (h *PublicHandlers) ParseRecord(w http.ResponseWriter, r *http.Request) {
+	var req api.ParseRecordRequest
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxParseBodyBytes)).Decode(&req); err != nil {
+		writeError(w, "invalid request body", http.StatusBadRequest)
+		return
+	}
+	hasRaw := strings.TrimSpace(req.Raw) != ""
+	if hasRaw == (req.Decimal != nil) {
+		writeError(w, "exactly one of raw or decimal is required", http.StatusBadRequest)
+		return
+	}
+
+	withWarnings := false
+	if s := r.URL.Query().Get("warnings"); s != "" {
+		v, err := strconv.ParseBool(s)
+		if err != nil {
+			writeError(w, "warnings must be true or false", http.StatusBadRequest)
+			return
+		}
+		withWarnings = v
+	}
+	withTerrain, err := parseTerrainParam(r)
+	if err != nil {
+		writeTerrainParamError(w, err)
+		return
+	}
+
+	var rec api.LOCRecord
+	if hasRaw {
+		parsed, err := loc.ParseLenient(req.FQDN, req.Raw)
+		if err != nil {
+			writeError(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		rec = *parsed
+	} else {
+		var err error
+		if rec, err = req.Decimal.Record(req.FQDN); err != nil {
+			writeError(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+
+	resp := api.ParseRecordResponse{LOCRecord: rec}
+	if withWarnings {
+		resp.Warnings = rec.PlausibilityWarnings()
+	}
+	if withTerrain {
+		resp.AltitudeAnomaly = altitudeAnomaly(rec)
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// GetRecord handles GET /api/public/records/{fqdn}.
+// Returns all LOC records published at the name; terrain=true adds their
+// altitude anomalies.
+func (h *PublicHandlers) GetRecord(w http.ResponseWriter, r *http.Request) {
+	fqdn := strings.ToLower(strings.TrimSuffix(chi.URLParam(r, "fqdn"), "."))
+	withTerrain, err := parseTerrainParam(r)
+	if err != nil {
+		writeTerrainParamError(w, err)
+		return
+	}
+
+	records, err := h.DB.GetLOCRecordsByFQDN(r.Context(), fqdn)
+	if err != nil {
+		writeError(w, "failed to get record", http.StatusInternalServerError)
+		return
+	}
+	if len(records) == 0 {
+		writeError(w, "record not found", http.StatusNotFound)
+		return
+	}
+
+	setFreshness(records, h.StaleAfter)
+	if withTerrain {
+		for i := range records {
+			records[i].AltitudeAnomaly = altitudeAnomaly(records[i].Record())
+		}
+	}
+	writeJSON(w, http.StatusOK, api.RecordDetailResponse{FQDN: fqdn, Records: records})
+}
+
+// VerifyRecordLocation handles GET /api/public/records/{fqdn}/verify.
+// Compares a record's published coordinates with a claimed location (lat/lon
+// query params, e.g. from WHOIS or IP geolocation). The threshold is taken
+// from the km param, defaulting to the record's horizontal precision. Of a
+// name with several records, the one nearest the claimed location is used.
+func (h *PublicHandlers) VerifyRecordLocation(w http.ResponseWriter, r *http.Request) {
+	fqdn := strings.ToLower(strings.TrimSuffix(chi.URLParam(r, "fqdn"), "."))
+
+	lat, err := parseFloatParam(r, "lat")
+	if err != nil || lat == nil || *lat < -90 || *lat > 90 {
+		writeError(w, "lat is required and must be between -90 and 90", http.StatusBadRequest)
+		return
+	}
+	lon, err := parseFloatParam(r, "lon")
+	if err != nil || lon == nil || *lon < -180 || *lon > 180 {
+		writeError(w, "lon is required and must be between -180 and 180", http.StatusBadRequest)
+		return
+	}
+	km, err := parseFloatParam(r, "km")
+	if err != nil || (km != nil && *km < 0) {
+		writeError(w, "km must be a non-negative number", http.StatusBadRequest)
+		return
+	}
+
+	stored, err := h.DB.GetLOCRecordsByFQDN(r.Context(), fqdn)
+	if err != nil {
+		writeError(w, "failed to get record", http.StatusInternalServerError)
+		return
+	}
+	if len(stored) == 0 {
+		writeError(w, "record not found", http.StatusNotFound)
+		return
+	}
+
+	nearest := slices.MinFunc(stored, func(a, b api.PublicLOCRecord) int {
+		return cmp.Compare(a.Record().DistanceKm(*lat, *lon), b.Record().DistanceKm(*lat, *lon))
+	})
+	rec := nearest.Record()
+	threshold := rec.HorizPrecM / 1000
+	if km != nil {
+		threshold = *km
+	}
+
+	writeJSON(w, http.StatusOK, api.VerifyLocationResponse{
+		FQDN:             rec.FQDN,
+		Latitude:         rec.Latitude,
+		Longitude:        rec.Longitude,
+		ClaimedLatitude:  *lat,
+		ClaimedLongitude: *lon,
+		DistanceKm:       rec.DistanceKm(*lat, *lon),
+		ThresholdKm:      threshold,
+		Within:           rec.WithinKm(*lat, *lon, threshold),
+	})
+}
+
+// maxSimilarToleranceM bounds the search radius of the similar endpoint.
+const maxSimilarToleranceM = 100_000
+
+// GetSimilarRecords handles GET /api/public/records/{fqdn}/similar.
+// Lists other records published within tolerance_m meters of the record's
+// coordinates, nearest first, to spot shared infrastructure. tolerance_m
+// defaults to the record's horizontal precision; 0 finds exact matches. Of a
+// name with several records, the most precise one is used.
+func (h *PublicHandlers) GetSimilarRecords(w http.ResponseWriter, r *http.Request) {
+	fqdn := strings.ToLower(strings.TrimSuffix(chi.URLParam(r, "fqdn"), "."))
+
+	tolerance, err := parseFloatParam(r, "tolerance_m")
+	if err != nil || (tolerance != nil && (*tolerance < 0 || *tolerance > maxSimilarToleranceM)) {
+		writeError(w, fmt.Sprintf("tolerance_m must be between 0 and %d", maxSimilarToleranceM), http.StatusBadRequest)
+		return
+	}
+	limit := parseIntParam(r, "limit", 100)
+	if limit > 1000 {
+		limit = 1000
+	}
+
+	records, err := h.DB.GetLOCRecordsByFQDN(r.Context(), fqdn)
+	if err != nil {
+		writeError(w, "failed to get record", http.StatusInternalServerError)
+		return
+	}
+	if len(records) == 0 {
+		writeError(w, "record not found", http.StatusNotFound)
+		return
+	}
+	stored := records[0]
+
+	toleranceM := math.Min(stored.HorizPrecM, maxSimilarToleranceM)
+	if tolerance != nil {
+		toleranceM = *tolerance
+	}
+
+	similar, err := h.DB.FindSimilarLOCRecords(r.Context(), stored.FQDN, stored.Latitude, stored.Longitude, toleranceM, limit)
+	if err != nil {
+		writeError(w, "failed to find similar records", http.StatusInternalServerError)
+		return
+	}
+	if similar == nil {
+		similar = []api.SimilarRecord{}
+	}
+	now := time.Now()
+	for i := range similar {
+		markFreshness(&similar[i].PublicLOCRecord, now, h.StaleAfter)
+	}
+
+	writeJSON(w, http.StatusOK, api.SimilarRecordsResponse{
+		FQDN:       stored.FQDN,
+		Latitude:   stored.Latitude,
+		Longitude:  stored.Longitude,
+		ToleranceM: toleranceM,
+		Records:    similar,
 	})
 }
 
 // GetRecordsGeoJSON handles GET /api/public/records.geojson.
 // Returns LOC records aggregated by location as a GeoJSON FeatureCollection.
 // Multiple FQDNs at the same coordinates are combined into a single feature.
+// Accepts the same filter parameters as ListRecords, plus dimensions=2 (default,
+// [lon, lat]) or dimensions=3 ([lon, lat, altitude_m]), and crs=4326 (default,
+// WGS84 degrees) or crs=3857 (Web Mercator meters). decimals=N rounds the
+// coordinates to N decimals, in the units of the chosen crs.
+//
+// X-Record-Count tells a valid empty result apart from one not yet loaded;
+// with empty=204, no matching records yield 204 No Content instead of an
+// empty FeatureCollection.
 func (h *PublicHandlers) GetRecordsGeoJSON(w http.ResponseWriter, r *http.Request) {
-	locations, err := h.DB.GetAggregatedLocationsForGeoJSON(r.Context())
+	filter, err := parseRecordFilter(r)
+	if err != nil {
+		writeError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	withAltitude := false
+	switch r.URL.Query().Get("dimensions") {
+	case "", "2":
+	case "3":
+		withAltitude = true
+	default:
+		writeError(w, "dimensions must be 2 or 3", http.StatusBadRequest)
+		return
+	}
+
+	mercator, err := parseCRS(r.URL.Query().Get("crs"))
+	if err != nil {
+		writeError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	decimals, err := parseDecimals(r, h.Decimals.GeoJSON)
+	if err != nil {
+		writeError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	noContentIfEmpty := false
+	switch r.URL.Query().Get("empty") {
+	case "", "200":
+	case "204":
+		noContentIfEmpty = true
+	default:
+		writeError(w, "empty must be 200 or 204", http.StatusBadRequest)
+		return
+	}
+
+	locations, err := h.DB.GetAggregatedLocationsForGeoJSON(r.Context(), filter)
 	if err != nil {
 		writeError(w, "failed to get records", http.StatusInternalServerError)
 		return
 	}
 
+	count := 0
+	for _, loc := range locations {
+		count += loc.Count
+	}
+	w.Header().Set(recordCountHeader, strconv.Itoa(count))
+	if count == 0 && noContentIfEmpty {
+		setCacheControl(w, h.CacheTTLs.GeoJSON)
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+
 	features := make([]api.GeoJSONFeature, 0, len(locations))
 	for _, loc := range locations {
-		feature := api.GeoJSONFeature{
-			Type: "Feature",
-			Geometry: api.GeoJSONPoint{
-				Type:        "Point",
-				Coordinates: []float64{loc.Longitude, loc.Latitude},
-			},
-			Properties: map[string]any{
-				"fqdns":        loc.FQDNs,
-				"root_domains": loc.RootDomains,
-				"raw_record":   loc.RawRecord,
-				"altitude_m":   loc.AltitudeM,
-				"count":        loc.Count,
-				"first_seen":   loc.FirstSeenAt,
-				"last_seen":    loc.LastSeenAt,
-			},
+		feature := locationFeature(loc, withAltitude)
+		if mercator {
+			coords := feature.Geometry.Coordinates
+			coords[0], coords[1] = api.WebMercator(loc.Latitude, loc.Longitude)
+		}
+		for i, v := range feature.Geometry.Coordinates {
+			feature.Geometry.Coordinates[i] = roundCoord(v, decimals)
 		}
 		features = append(features, feature)
 	}
@@ -79,6 +612,12 @@ func (h *PublicHandlers) GetRecordsGeoJSON(w http.ResponseWriter, r *http.Reques
 		Type:     "FeatureCollection",
 		Features: features,
 	}
+	if mercator {
+		fc.CRS = &api.GeoJSONCRS{
+			Type:       "name",
+			Properties: map[string]string{"name": "urn:ogc:def:crs:EPSG::3857"},
+		}
+	}
 
 	data, err := json.Marshal(fc)
 	if err != nil {
@@ -86,33 +625,265 @@ func (h *PublicHandlers) GetRecordsGeoJSON(w http.ResponseWriter, r *http.Reques
 		return
 	}
 
-	w.Header().Set("Content-Type", "application/geo+json")
-	w.Header().Set("Cache-Control", "public, max-age=300")
-	w.WriteHeader(http.StatusOK)
-	_, _ = w.Write(data)
+	setCacheControl(w, h.CacheTTLs.GeoJSON)
+	serveExport(w, r, "application/geo+json", data)
+}
+
+// recordCountHeader carries the number of records in an export.
+const recordCountHeader = "X-Record-Count"
+
+// parseCRS parses the crs parameter, reporting whether Web Mercator
+// (EPSG:3857) output was requested.
+func parseCRS(s string) (mercator bool, err error) {
+	switch strings.ToUpper(s) {
+	case "", "4326", "EPSG:4326":
+		return false, nil
+	case "3857", "EPSG:3857":
+		return true, nil
+	default:
+		return false, fmt.Errorf("crs must be 4326 or 3857")
+	}
+}
+
+// serveExport writes a fully materialized export with an ETag and support for
+// Range/If-Range requests, so interrupted downloads can be resumed.
+func serveExport(w http.ResponseWriter, r *http.Request, contentType string, data []byte) {
+	sum := sha256.Sum256(data)
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("ETag", `"`+hex.EncodeToString(sum[:16])+`"`)
+	http.ServeContent(w, r, "", time.Time{}, bytes.NewReader(data))
+}
+
+// locationFeature converts an aggregated location to a GeoJSON Point feature.
+func locationFeature(loc api.AggregatedLocation, withAltitude bool) api.GeoJSONFeature {
+	coords := []float64{loc.Longitude, loc.Latitude}
+	if withAltitude {
+		coords = append(coords, loc.AltitudeM)
+	}
+	return api.GeoJSONFeature{
+		Type: "Feature",
+		Geometry: api.GeoJSONPoint{
+			Type:        "Point",
+			Coordinates: coords,
+		},
+		Properties: api.LOCFeatureProperties{
+			FQDNs:         loc.FQDNs,
+			RootDomains:   loc.RootDomains,
+			RawRecord:     loc.RawRecord,
+			AltitudeM:     loc.AltitudeM,
+			SizeDiameterM: loc.SizeM,
+			SizeRadiusM:   loc.SizeM / 2,
+			Count:         loc.Count,
+			FirstSeen:     loc.FirstSeenAt.UTC(),
+			LastSeen:      loc.LastSeenAt.UTC(),
+		},
+	}
+}
+
+// GetDomainStatus handles GET /api/public/domains/{domain}/status.
+// Tells apart domains with a LOC record, domains still queued for scanning,
+// domains whose lookup failed, and the rest.
+func (h *PublicHandlers) GetDomainStatus(w http.ResponseWriter, r *http.Request) {
+	domain := strings.ToLower(strings.TrimSuffix(chi.URLParam(r, "domain"), "."))
+	if !validDomain(domain) {
+		writeError(w, "invalid domain", http.StatusBadRequest)
+		return
+	}
+
+	status, err := h.DB.GetDomainStatus(r.Context(), domain)
+	if err != nil {
+		log.Printf("Failed to get status for %s: %v", domain, err)
+		writeError(w, "failed to get domain status", http.StatusInternalServerError)
+		return
+	}
+
+	setCacheControl(w, h.CacheTTLs.Records)
+	writeJSON(w, http.StatusOK, status)
+}
+
+// validDomain reports whether s is a plausible lowercase hostname.
+func validDomain(s string) bool {
+	if s == "" || len(s) > 253 {
+		return false
+	}
+	for _, label := range strings.Split(s, ".") {
+		if label == "" || len(label) > 63 {
+			return false
+		}
+		for _, c := range label {
+			if !(c >= 'a' && c <= 'z' || c >= '0' && c <= '9' || c == '-' || c == '_') {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// GetBounds handles GET /api/public/bounds.
+// Returns the extent of the records matching the same filters as ListRecords,
+// for fitting a map viewport to the data.
+func (h *PublicHandlers) GetBounds(w http.ResponseWriter, r *http.Request) {
+	filter, err := parseRecordFilter(r)
+	if err != nil {
+		writeError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	bounds, err := h.DB.GetRecordBounds(r.Context(), filter)
+	if err != nil {
+		writeError(w, "failed to get bounds", http.StatusInternalServerError)
+		return
+	}
+
+	setCacheControl(w, h.CacheTTLs.GeoJSON)
+	writeJSON(w, http.StatusOK, bounds)
 }
 
 // GetStats handles GET /api/public/stats.
+// Responses are served from StatsCache when one is configured. The totals
+// cover every record, so the record filters are deliberately not accepted.
 func (h *PublicHandlers) GetStats(w http.ResponseWriter, r *http.Request) {
-	ctx := r.Context()
+	var stats api.StatsResponse
+	var err error
+	if h.StatsCache != nil {
+		stats, err = h.StatsCache.Get(r.Context(), h.loadStats)
+	} else {
+		stats, err = h.loadStats(r.Context())
+	}
+	if err != nil {
+		log.Printf("Failed to get stats: %v", err)
+		writeError(w, "failed to get stats", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Cache-Control", "public, max-age=60")
+	writeJSON(w, http.StatusOK, stats)
+}
+
+// GetStatsSummary handles GET /api/public/stats/summary.
+// Returns only the record count and active scanners, from a single query,
+// for clients that poll frequently (status badges and the like).
+func (h *PublicHandlers) GetStatsSummary(w http.ResponseWriter, r *http.Request) {
+	summary, err := h.DB.GetStatsSummary(r.Context(), h.HeartbeatTimeout)
+	if err != nil {
+		writeError(w, "failed to get stats", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Cache-Control", "public, max-age=15")
+	writeJSON(w, http.StatusOK, summary)
+}
+
+// GetRecordsPerDomain handles GET /api/public/stats/records-per-domain.
+// Accepts the record filters, e.g. to look at a region with bbox.
+func (h *PublicHandlers) GetRecordsPerDomain(w http.ResponseWriter, r *http.Request) {
+	filter, err := parseRecordFilter(r)
+	if err != nil {
+		writeError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	dist, err := h.DB.GetRecordsPerDomain(r.Context(), filter)
+	if err != nil {
+		log.Printf("Failed to get records per domain: %v", err)
+		writeError(w, "failed to get stats", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Cache-Control", "public, max-age=60")
+	writeJSON(w, http.StatusOK, dist)
+}
 
+// Density grid sizes in degrees: the finest is about a kilometer, the
+// coarsest an octant of the globe.
+const (
+	defaultDensityGrid = 1.0
+	minDensityGrid     = 0.01
+	maxDensityGrid     = 90.0
+)
+
+// Number of cells GetDensity returns by default and at most.
+const (
+	defaultDensityLimit = 100
+	maxDensityLimit     = 1000
+)
+
+// GetDensity handles GET /api/public/stats/density.
+// Counts records per grid cell of grid degrees (default 1) and returns the
+// limit densest cells (default 100), for a heatmap without fetching every
+// record. Accepts the record filters.
+func (h *PublicHandlers) GetDensity(w http.ResponseWriter, r *http.Request) {
+	filter, err := parseRecordFilter(r)
+	if err != nil {
+		writeError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	grid, err := parseDensityGrid(r.URL.Query().Get("grid"))
+	if err != nil {
+		writeError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	limit, err := parseLimit(r.URL.Query().Get("limit"), defaultDensityLimit, maxDensityLimit)
+	if err != nil {
+		writeError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	cells, err := h.DB.GetDensity(r.Context(), grid, limit, filter)
+	if err != nil {
+		log.Printf("Failed to get density: %v", err)
+		writeError(w, "failed to get stats", http.StatusInternalServerError)
+		return
+	}
+	if cells == nil {
+		cells = []api.DensityCell{}
+	}
+
+	w.Header().Set("Cache-Control", "public, max-age=60")
+	writeJSON(w, http.StatusOK, api.DensityResponse{Grid: grid, Cells: cells})
+}
+
+// parseDensityGrid parses the grid cell size in degrees.
+func parseDensityGrid(s string) (float64, error) {
+	if s == "" {
+		return defaultDensityGrid, nil
+	}
+	grid, err := strconv.ParseFloat(s, 64)
+	if err != nil || !(grid >= minDensityGrid && grid <= maxDensityGrid) {
+		return 0, fmt.Errorf("grid must be between %g and %g degrees", minDensityGrid, maxDensityGrid)
+	}
+	return grid, nil
+}
+
+// parseLimit parses a limit parameter, defaulting to def when empty. Limits
+// above maxLimit are capped rather than rejected.
+func parseLimit(s string, def, maxLimit int) (int, error) {
+	if s == "" {
+		return def, nil
+	}
+	limit, err := strconv.Atoi(s)
+	if err != nil || limit < 1 {
+		return 0, fmt.Errorf("limit must be a positive integer")
+	}
+	return min(limit, maxLimit), nil
+}
+
+// loadStats runs the queries behind GetStats.
+func (h *PublicHandlers) loadStats(ctx context.Context) (api.StatsResponse, error) {
 	// LOC record stats
 	locCount, err := h.DB.CountLOCRecords(ctx)
 	if err != nil {
-		writeError(w, "failed to get LOC record count", http.StatusInternalServerError)
-		return
+		return api.StatsResponse{}, fmt.Errorf("failed to get LOC record count: %w", err)
 	}
 
 	uniqueWithLOC, err := h.DB.CountUniqueRootDomainsWithLOC(ctx)
 	if err != nil {
-		writeError(w, "failed to get unique domains with LOC", http.StatusInternalServerError)
-		return
+		return api.StatsResponse{}, fmt.Errorf("failed to get unique domains with LOC: %w", err)
 	}
 
 	uniqueLocations, err := h.DB.CountUniqueLocations(ctx)
 	if err != nil {
-		writeError(w, "failed to get unique locations", http.StatusInternalServerError)
-		return
+		return api.StatsResponse{}, fmt.Errorf("failed to get unique locations: %w", err)
 	}
 
 	// Scanner stats - count active sessions (individual scanner instances)
@@ -121,31 +892,27 @@ func (h *PublicHandlers) GetStats(w http.ResponseWriter, r *http.Request) {
 		// Fall back to counting active clients if sessions table doesn't exist yet
 		activeSessions, err = h.DB.CountActiveClients(ctx, h.HeartbeatTimeout)
 		if err != nil {
-			writeError(w, "failed to get active scanners", http.StatusInternalServerError)
-			return
+			return api.StatsResponse{}, fmt.Errorf("failed to get active scanners: %w", err)
 		}
 	}
 
 	// File stats
 	fileStats, err := h.DB.GetDomainFileStats(ctx)
 	if err != nil {
-		writeError(w, "failed to get file stats", http.StatusInternalServerError)
-		return
+		return api.StatsResponse{}, fmt.Errorf("failed to get file stats: %w", err)
 	}
 
 	// Batch stats
 	batchStats, err := h.DB.GetBatchStats(ctx)
 	if err != nil {
-		writeError(w, "failed to get batch stats", http.StatusInternalServerError)
-		return
+		return api.StatsResponse{}, fmt.Errorf("failed to get batch stats: %w", err)
 	}
 
 	// Current file progress
 	var currentFile *api.CurrentFileProgress
 	processingFile, err := h.DB.GetCurrentProcessingFile(ctx)
 	if err != nil {
-		writeError(w, "failed to get current file", http.StatusInternalServerError)
-		return
+		return api.StatsResponse{}, fmt.Errorf("failed to get current file: %w", err)
 	}
 	if processingFile != nil {
 		progressPct := 0.0
@@ -161,8 +928,7 @@ func (h *PublicHandlers) GetStats(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
-	w.Header().Set("Cache-Control", "public, max-age=60")
-	writeJSON(w, http.StatusOK, api.StatsResponse{
+	return api.StatsResponse{
 		TotalLOCRecords:          locCount,
 		UniqueRootDomainsWithLOC: uniqueWithLOC,
 		UniqueLocations:          uniqueLocations,
@@ -178,7 +944,7 @@ func (h *PublicHandlers) GetStats(w http.ResponseWriter, r *http.Request) {
 			InFlight: batchStats.InFlight,
 		},
 		CurrentFile: currentFile,
-	})
+	}, nil
 }
 
 func parseIntParam(r *http.Request, name string, defaultVal int) int {
@@ -1,9 +1,14 @@
 package handlers
 
 import (
+	"context"
 	"encoding/json"
+	"fmt"
+	"io"
 	"log"
 	"net/http"
+	"slices"
+	"strconv"
 	"strings"
 	"time"
 
@@ -12,12 +17,25 @@ import (
 	"github.com/locplace/scanner/internal/coordinator/db"
 	"github.com/locplace/scanner/internal/coordinator/metrics"
 	"github.com/locplace/scanner/internal/coordinator/middleware"
+	"github.com/locplace/scanner/internal/coordinator/tldfilter"
 	"github.com/locplace/scanner/pkg/api"
 )
 
 // ScannerHandlers contains handlers for scanner endpoints.
 type ScannerHandlers struct {
-	DB *db.DB
+	DB          *db.DB
+	Idempotency *IdempotencyCache // Optional: enables Idempotency-Key on SubmitResults
+	// MinAPIVersion is the oldest result payload version accepted (0 = 1).
+	MinAPIVersion int
+	// KeepUnparsed stores LOC answers that failed to parse for review via
+	// GET /api/admin/unparsed-records.
+	KeepUnparsed bool
+	// TLDFilter rejects results for names outside the configured TLDs
+	// (nil = accept all).
+	TLDFilter *tldfilter.Filter
+	// MaxHorizPrecM rejects records whose horizontal precision is coarser
+	// than this many meters (0 = accept all).
+	MaxHorizPrecM float64
 }
 
 // GetJobs handles POST /api/scanner/jobs.
@@ -44,6 +62,21 @@ func (h *ScannerHandlers) GetJobs(w http.ResponseWriter, r *http.Request) {
 	// Also update client's last_heartbeat for backwards compat
 	_ = h.DB.UpdateHeartbeat(r.Context(), client.ID, req.SessionID)
 
+	// A restarted scanner first gets back the batches still leased to its
+	// previous session, one per request, before claiming new ones.
+	if req.ResumeSessionID != "" && req.ResumeSessionID != req.SessionID {
+		batch, err := h.DB.ResumeSessionBatch(r.Context(), client.ID, req.ResumeSessionID, req.SessionID)
+		if err != nil {
+			log.Printf("Failed to resume batches of previous session %s: %v", req.ResumeSessionID, err)
+		} else if batch != nil {
+			log.Printf("Resumed batch %d of previous session %s for client %s", batch.ID, req.ResumeSessionID, client.ID)
+			resp := batchResponse(batch)
+			resp.Resumed = true
+			writeJSON(w, http.StatusOK, resp)
+			return
+		}
+	}
+
 	// Claim a batch (pass both client ID and session ID)
 	batch, err := h.DB.ClaimBatch(r.Context(), client.ID, req.SessionID)
 	if err != nil {
@@ -59,6 +92,11 @@ func (h *ScannerHandlers) GetJobs(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	writeJSON(w, http.StatusOK, batchResponse(batch))
+}
+
+// batchResponse lists the domains of a claimed batch.
+func batchResponse(batch *db.ScanBatch) api.GetBatchResponse {
 	// Parse domains from newline-separated string
 	domains := strings.Split(batch.Domains, "\n")
 	// Filter empty strings
@@ -69,11 +107,10 @@ func (h *ScannerHandlers) GetJobs(w http.ResponseWriter, r *http.Request) {
 			filtered = append(filtered, d)
 		}
 	}
-
-	writeJSON(w, http.StatusOK, api.GetBatchResponse{
+	return api.GetBatchResponse{
 		BatchID: batch.ID,
 		Domains: filtered,
-	})
+	}
 }
 
 // Heartbeat handles POST /api/scanner/heartbeat.
@@ -98,12 +135,16 @@ func (h *ScannerHandlers) Heartbeat(w http.ResponseWriter, r *http.Request) {
 
 	// Also update client heartbeat for backwards compat
 	_ = h.DB.UpdateHeartbeat(r.Context(), client.ID, req.SessionID)
+	if err := h.DB.UpdateClientVersion(r.Context(), client.ID, req.Version, req.Capabilities); err != nil {
+		log.Printf("Failed to store version for client %s: %v", client.ID, err)
+	}
 
 	writeJSON(w, http.StatusOK, api.HeartbeatResponse{OK: true})
 }
 
 // SubmitResults handles POST /api/scanner/results.
 // Stores LOC records and marks the batch as complete.
+// A retried request with the same Idempotency-Key gets the original response.
 func (h *ScannerHandlers) SubmitResults(w http.ResponseWriter, r *http.Request) {
 	client := middleware.GetClient(r.Context())
 	if client == nil {
@@ -111,8 +152,13 @@ func (h *ScannerHandlers) SubmitResults(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
-	var req api.SubmitBatchRequest
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+	version, err := h.apiVersion(r)
+	if err != nil {
+		writeError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	req, err := decodeSubmitBatch(r.Body, version)
+	if err != nil {
 		writeError(w, "invalid request body", http.StatusBadRequest)
 		return
 	}
@@ -122,38 +168,131 @@ func (h *ScannerHandlers) SubmitResults(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
-	// Store LOC records
-	accepted := 0
-	for _, loc := range req.LOCRecords {
-		// Validate coordinates before attempting insert
-		if loc.Latitude < -90 || loc.Latitude > 90 || loc.Longitude < -180 || loc.Longitude > 180 {
-			log.Printf("Rejected invalid coordinates for %s: lat=%f, lon=%f", loc.FQDN, loc.Latitude, loc.Longitude)
-			continue
+	var key string
+	if k := r.Header.Get("Idempotency-Key"); k != "" && h.Idempotency != nil {
+		// Scope keys per client so scanners can't collide
+		key = client.ID + ":" + k
+		cached, inFlight := h.Idempotency.Begin(key)
+		if inFlight {
+			writeError(w, "a request with this idempotency key is in progress", http.StatusConflict)
+			return
 		}
+		if cached != nil {
+			writeJSON(w, http.StatusOK, *cached)
+			return
+		}
+	}
 
-		// Extract root domain from FQDN
-		rootDomain, err := publicsuffix.EffectiveTLDPlusOne(loc.FQDN)
+	resp, err := h.storeResults(r.Context(), req)
+	if err != nil {
+		if key != "" {
+			h.Idempotency.Abort(key)
+		}
+		writeError(w, "failed to complete batch", http.StatusInternalServerError)
+		return
+	}
+	if key != "" {
+		h.Idempotency.Finish(key, resp)
+	}
+
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// apiVersion returns the result payload version from the
+// X-Scanner-API-Version header, or an error if it is unsupported.
+func (h *ScannerHandlers) apiVersion(r *http.Request) (int, error) {
+	minVersion := max(h.MinAPIVersion, 1)
+	version := 1
+	if s := r.Header.Get(api.ScannerAPIVersionHeader); s != "" {
+		v, err := strconv.Atoi(s)
 		if err != nil {
-			// If we can't parse it, use the FQDN as-is
-			rootDomain = loc.FQDN
+			return 0, fmt.Errorf("invalid %s header", api.ScannerAPIVersionHeader)
 		}
+		version = v
+	}
+	if version < minVersion || version > api.ScannerAPIVersion {
+		return 0, fmt.Errorf("unsupported scanner API version %d (supported: %d to %d), please upgrade the scanner",
+			version, minVersion, api.ScannerAPIVersion)
+	}
+	return version, nil
+}
 
-		if err := h.DB.UpsertLOCRecord(r.Context(), rootDomain, loc); err != nil {
-			log.Printf("Failed to insert LOC record for %s: %v", loc.FQDN, err)
-			continue
+// submitBatchRequestV1 is the version 1 result payload.
+type submitBatchRequestV1 struct {
+	BatchID        int64           `json:"batch_id"`
+	DomainsChecked int             `json:"domains_checked"`
+	LOCRecords     []api.LOCRecord `json:"loc_records"`
+}
+
+// decodeSubmitBatch decodes a result payload of the given version and
+// translates it to the current shape.
+func decodeSubmitBatch(body io.Reader, version int) (api.SubmitBatchRequest, error) {
+	switch version {
+	case 1:
+		var v1 submitBatchRequestV1
+		if err := json.NewDecoder(body).Decode(&v1); err != nil {
+			return api.SubmitBatchRequest{}, err
+		}
+		return api.SubmitBatchRequest{
+			BatchID:        v1.BatchID,
+			DomainsChecked: v1.DomainsChecked,
+			LOCRecords:     v1.LOCRecords,
+		}, nil
+	default:
+		var req api.SubmitBatchRequest
+		err := json.NewDecoder(body).Decode(&req)
+		return req, err
+	}
+}
+
+// storeResults stores the submitted LOC records and scan errors and marks
+// the batch as complete, all in one transaction.
+func (h *ScannerHandlers) storeResults(ctx context.Context, req api.SubmitBatchRequest) (api.SubmitBatchResponse, error) {
+	rejected := h.filterTLDs(&req)
+	rejectedTLD := len(rejected)
+	valid, parse := parseStats(req, time.Now())
+	valid, imprecise := h.filterImprecise(valid)
+	rejected = append(rejected, imprecise...)
+
+	records := make([]db.BatchRecord, len(valid))
+	for i, loc := range valid {
+		records[i] = db.BatchRecord{RootDomain: rootDomainOf(loc.FQDN), Record: loc}
+	}
+	for i := range req.ScanErrors {
+		if !slices.Contains(api.ScanErrorClasses, req.ScanErrors[i].Class) {
+			req.ScanErrors[i].Class = api.ScanErrorOther
 		}
-		accepted++
 	}
 
-	// Mark batch as complete
-	fileID, assignedAt, err := h.DB.CompleteBatch(r.Context(), req.BatchID)
+	results := db.BatchResults{
+		BatchID:           req.BatchID,
+		Records:           records,
+		KeepBestPrecision: req.KeepBestPrecision,
+		ReplaceIfNewer:    req.ReplaceIfNewer,
+		ScanErrors:        req.ScanErrors,
+		DomainFacts:       req.DomainFacts,
+		Partial:           partialLookups(parse.Errors, imprecise),
+	}
+	if h.KeepUnparsed {
+		results.Unparsed = parse.Errors
+	}
+	out, err := h.DB.StoreBatchResults(ctx, results)
 	if err != nil {
-		writeError(w, "failed to complete batch", http.StatusInternalServerError)
-		return
+		return api.SubmitBatchResponse{}, err
+	}
+	fileID, assignedAt, accepted := out.FileID, out.AssignedAt, out.Accepted
+
+	// Count only committed batches, so retries after a failure aren't double counted
+	metrics.LOCParseResultsTotal.WithLabelValues("parsed").Add(float64(parse.Parsed))
+	metrics.LOCParseResultsTotal.WithLabelValues("failed").Add(float64(parse.Failed))
+	metrics.RecordsRejectedTotal.WithLabelValues("tld").Add(float64(rejectedTLD))
+	metrics.RecordsRejectedTotal.WithLabelValues("precision").Add(float64(len(imprecise)))
+	for _, e := range req.ScanErrors {
+		metrics.ScanErrorsTotal.WithLabelValues(e.Class).Inc()
 	}
 
 	// Check if the file is now complete (all batches done)
-	completed, err := h.DB.CheckAndMarkFileComplete(r.Context(), fileID)
+	completed, err := h.DB.CheckAndMarkFileComplete(ctx, fileID)
 	if err != nil {
 		// Log but don't fail - the batch is already completed
 		// The file will be marked complete on next check
@@ -170,5 +309,105 @@ func (h *ScannerHandlers) SubmitResults(w http.ResponseWriter, r *http.Request)
 	metrics.DomainsCheckedTotal.Add(float64(req.DomainsChecked))
 	metrics.LOCDiscoveriesTotal.Add(float64(accepted))
 
-	writeJSON(w, http.StatusOK, api.SubmitBatchResponse{Accepted: accepted})
+	return api.SubmitBatchResponse{Accepted: accepted, Parse: parse, Rejected: rejected}, nil
+}
+
+// rootDomainOf returns the registrable domain of fqdn, or fqdn itself if
+// it has none (e.g. a bare public suffix).
+func rootDomainOf(fqdn string) string {
+	rootDomain, err := publicsuffix.EffectiveTLDPlusOne(fqdn)
+	if err != nil {
+		return fqdn
+	}
+	return rootDomain
+}
+
+// filterImprecise splits off the records whose horizontal precision is
+// coarser than h.MaxHorizPrecM.
+func (h *ScannerHandlers) filterImprecise(records []api.LOCRecord) ([]api.LOCRecord, []api.RejectedRecord) {
+	if h.MaxHorizPrecM <= 0 {
+		return records, nil
+	}
+	var rejected []api.RejectedRecord
+	kept := slices.DeleteFunc(records, func(loc api.LOCRecord) bool {
+		if loc.HorizPrecM <= h.MaxHorizPrecM {
+			return false
+		}
+		reason := fmt.Sprintf("horizontal precision %sm is coarser than the %sm limit",
+			strconv.FormatFloat(loc.HorizPrecM, 'f', -1, 64), strconv.FormatFloat(h.MaxHorizPrecM, 'f', -1, 64))
+		rejected = append(rejected, api.RejectedRecord{FQDN: loc.FQDN, Reason: reason})
+		return true
+	})
+	return kept, rejected
+}
+
+// filterTLDs removes results for names outside h.TLDFilter from req and
+// returns the rejected LOC records with the reason.
+func (h *ScannerHandlers) filterTLDs(req *api.SubmitBatchRequest) []api.RejectedRecord {
+	if h.TLDFilter == nil {
+		return nil
+	}
+	var rejected []api.RejectedRecord
+	req.LOCRecords = slices.DeleteFunc(req.LOCRecords, func(loc api.LOCRecord) bool {
+		err := h.TLDFilter.Check(loc.FQDN)
+		if err != nil {
+			rejected = append(rejected, api.RejectedRecord{FQDN: loc.FQDN, Reason: err.Error()})
+		}
+		return err != nil
+	})
+	// Nothing is kept about excluded names
+	req.ParseErrors = slices.DeleteFunc(req.ParseErrors, func(e api.ParseError) bool { return !h.TLDFilter.Allows(e.FQDN) })
+	req.ScanErrors = slices.DeleteFunc(req.ScanErrors, func(e api.ScanError) bool { return !h.TLDFilter.Allows(e.FQDN) })
+	req.DomainFacts = slices.DeleteFunc(req.DomainFacts, func(f api.DomainFacts) bool { return !h.TLDFilter.Allows(f.FQDN) })
+	return rejected
+}
+
+// partialLookups returns the names with LOC answers that won't be stored,
+// because they failed to parse or validate or were rejected as imprecise.
+func partialLookups(errs []api.ParseError, rejected []api.RejectedRecord) []string {
+	var fqdns []string
+	for _, e := range errs {
+		fqdns = append(fqdns, e.FQDN)
+	}
+	for _, r := range rejected {
+		fqdns = append(fqdns, r.FQDN)
+	}
+	slices.Sort(fqdns)
+	return slices.Compact(fqdns)
+}
+
+// maxObservedAtSkew is how far in the future a scanner's observed_at may be,
+// to allow for clock drift. Such times are clamped to now.
+const maxObservedAtSkew = 5 * time.Minute
+
+// parseStats validates the submitted records and returns the ones worth
+// storing, along with the batch parse outcome. Records with out-of-range or
+// non-finite values, or an observed_at too far after now, are counted as
+// failed next to the scanner's own parse errors.
+func parseStats(req api.SubmitBatchRequest, now time.Time) ([]api.LOCRecord, api.ParseStats) {
+	stats := api.ParseStats{Errors: slices.Clone(req.ParseErrors)}
+	valid := make([]api.LOCRecord, 0, len(req.LOCRecords))
+	for _, loc := range req.LOCRecords {
+		err := loc.Validate()
+		if err == nil && loc.ObservedAt != nil && loc.ObservedAt.After(now) {
+			if loc.ObservedAt.Sub(now) > maxObservedAtSkew {
+				err = fmt.Errorf("observed_at %s is in the future", loc.ObservedAt.Format(time.RFC3339))
+			} else {
+				loc.ObservedAt = &now
+			}
+		}
+		if err != nil {
+			log.Printf("Rejected invalid record for %s: %v (lat=%f, lon=%f)", loc.FQDN, err, loc.Latitude, loc.Longitude)
+			stats.Errors = append(stats.Errors, api.ParseError{
+				FQDN:      loc.FQDN,
+				RawRecord: loc.RawRecord,
+				Message:   err.Error(),
+			})
+			continue
+		}
+		valid = append(valid, loc)
+	}
+	stats.Parsed = len(valid)
+	stats.Failed = len(stats.Errors)
+	return valid, stats
 }
@@ -0,0 +1,146 @@
+package handlers
+
+import (
+	"encoding/xml"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// sitemapPageSize is the number of domain URLs per sitemap page. The
+// protocol allows 50,000 URLs per file; the home page is added to page 1.
+const sitemapPageSize = 45000
+
+// sitemapCacheTTL is the Cache-Control max-age for robots.txt and sitemaps.
+const sitemapCacheTTL = time.Hour
+
+const sitemapNS = "http://www.sitemaps.org/schemas/sitemap/0.9"
+
+type sitemapURLSet struct {
+	XMLName xml.Name     `xml:"urlset"`
+	NS      string       `xml:"xmlns,attr"`
+	URLs    []sitemapURL `xml:"url"`
+}
+
+type sitemapURL struct {
+	Loc string `xml:"loc"`
+}
+
+type sitemapIndex struct {
+	XMLName  xml.Name     `xml:"sitemapindex"`
+	NS       string       `xml:"xmlns,attr"`
+	Sitemaps []sitemapURL `xml:"sitemap"`
+}
+
+// domainPageURL is the frontend URL listing a root domain's records.
+func domainPageURL(baseURL, domain string) string {
+	return baseURL + "/?q=" + url.QueryEscape(domain)
+}
+
+// buildSitemap lists the domain pages, plus the home page on page 1.
+func buildSitemap(baseURL string, domains []string, page int) sitemapURLSet {
+	set := sitemapURLSet{NS: sitemapNS, URLs: make([]sitemapURL, 0, len(domains)+1)}
+	if page == 1 {
+		set.URLs = append(set.URLs, sitemapURL{Loc: baseURL + "/"})
+	}
+	for _, d := range domains {
+		set.URLs = append(set.URLs, sitemapURL{Loc: domainPageURL(baseURL, d)})
+	}
+	return set
+}
+
+// buildSitemapIndex points to pages sitemap pages.
+func buildSitemapIndex(baseURL string, pages int) sitemapIndex {
+	index := sitemapIndex{NS: sitemapNS, Sitemaps: make([]sitemapURL, pages)}
+	for i := range pages {
+		index.Sitemaps[i].Loc = fmt.Sprintf("%s/sitemap-%d.xml", baseURL, i+1)
+	}
+	return index
+}
+
+// defaultRobotsTxt allows crawling the public site and API, keeps crawlers
+// out of the admin pages and points them to the sitemap.
+func defaultRobotsTxt(baseURL string) string {
+	return "User-agent: *\n" +
+		"Allow: /\n" +
+		"Disallow: /admin\n" +
+		"Disallow: /api/admin/\n" +
+		"Disallow: /api/scanner/\n" +
+		"\n" +
+		"Sitemap: " + baseURL + "/sitemap.xml\n"
+}
+
+// GetRobotsTxt handles GET /robots.txt.
+// Serves the configured robots.txt, or a default pointing to the sitemap.
+func (h *PublicHandlers) GetRobotsTxt(w http.ResponseWriter, r *http.Request) {
+	body := h.RobotsTxt
+	if body == "" {
+		body = defaultRobotsTxt(h.baseURL(r))
+	}
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	setCacheControl(w, sitemapCacheTTL)
+	_, _ = w.Write([]byte(body)) // Error is client disconnect, can't recover
+}
+
+// GetSitemap handles GET /sitemap.xml.
+// Lists the home page and one page per root domain with LOC records. Once
+// there are more domains than fit one file, it returns a sitemap index of
+// /sitemap-{page}.xml files instead.
+func (h *PublicHandlers) GetSitemap(w http.ResponseWriter, r *http.Request) {
+	total, err := h.DB.CountUniqueRootDomainsWithLOC(r.Context())
+	if err != nil {
+		writeError(w, "failed to count domains", http.StatusInternalServerError)
+		return
+	}
+	if total > sitemapPageSize {
+		pages := (total + sitemapPageSize - 1) / sitemapPageSize
+		writeXML(w, buildSitemapIndex(h.baseURL(r), pages))
+		return
+	}
+	h.writeSitemapPage(w, r, 1)
+}
+
+// GetSitemapPage handles GET /sitemap-{page}.xml, one page of a sitemap index.
+func (h *PublicHandlers) GetSitemapPage(w http.ResponseWriter, r *http.Request) {
+	page, err := strconv.Atoi(chi.URLParam(r, "page"))
+	if err != nil || page < 1 {
+		writeError(w, "sitemap not found", http.StatusNotFound)
+		return
+	}
+	h.writeSitemapPage(w, r, page)
+}
+
+func (h *PublicHandlers) writeSitemapPage(w http.ResponseWriter, r *http.Request, page int) {
+	domains, total, err := h.DB.ListRootDomainsWithLOC(r.Context(), sitemapPageSize, (page-1)*sitemapPageSize)
+	if err != nil {
+		writeError(w, "failed to list domains", http.StatusInternalServerError)
+		return
+	}
+	if page > 1 && (page-1)*sitemapPageSize >= total {
+		writeError(w, "sitemap not found", http.StatusNotFound)
+		return
+	}
+
+	names := make([]string, len(domains))
+	for i, d := range domains {
+		names[i] = d.RootDomain
+	}
+	writeXML(w, buildSitemap(h.baseURL(r), names, page))
+}
+
+// writeXML writes v as an XML document with the sitemap cache lifetime.
+func writeXML(w http.ResponseWriter, v any) {
+	data, err := xml.Marshal(v)
+	if err != nil {
+		writeError(w, "failed to encode sitemap", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/xml; charset=utf-8")
+	setCacheControl(w, sitemapCacheTTL)
+	_, _ = w.Write([]byte(xml.Header)) // Error is client disconnect, can't recover
+	_, _ = w.Write(data)
+}
@@ -0,0 +1,121 @@
+package handlers
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"time"
+
+	"github.com/locplace/scanner/pkg/api"
+
+	_ "modernc.org/sqlite" // Pure-Go SQLite driver, registered as "sqlite"
+)
+
+// sqliteSchema creates the loc_records table of the SQLite export. Columns
+// match the public record fields; timestamps are RFC 3339 text and
+// cname_chain is a JSON array.
+const sqliteSchema = `CREATE TABLE loc_records (
+    fqdn          TEXT NOT NULL,
+    root_domain   TEXT NOT NULL,
+    raw_record    TEXT NOT NULL,
+    latitude      REAL NOT NULL,
+    longitude     REAL NOT NULL,
+    altitude_m    REAL NOT NULL,
+    size_m        REAL NOT NULL,
+    horiz_prec_m  REAL NOT NULL,
+    vert_prec_m   REAL NOT NULL,
+    first_seen_at TEXT NOT NULL,
+    last_seen_at  TEXT NOT NULL,
+    cname_chain   TEXT NOT NULL DEFAULT '[]',
+    PRIMARY KEY (fqdn, raw_record)
+)`
+
+// sqliteIndexes are created after the inserts, which is faster than
+// maintaining them row by row.
+var sqliteIndexes = []string{
+	`CREATE INDEX idx_loc_records_root_domain ON loc_records (root_domain)`,
+	`CREATE INDEX idx_loc_records_coords ON loc_records (latitude, longitude)`,
+}
+
+// sqliteExport builds the SQLite database file of the export. Records are
+// inserted as they are passed to add, in one transaction, so the export can
+// be streamed straight from the database.
+type sqliteExport struct {
+	db     *sql.DB
+	tx     *sql.Tx
+	insert *sql.Stmt
+}
+
+// newSQLiteExport creates the database at path, which must not exist yet,
+// with an empty loc_records table. The caller must call finish or close.
+func newSQLiteExport(ctx context.Context, path string) (*sqliteExport, error) {
+	// The file is thrown away if the export fails, so skip the journal
+	sdb, err := sql.Open("sqlite", "file:"+path+"?_pragma=journal_mode(OFF)&_pragma=synchronous(OFF)")
+	if err != nil {
+		return nil, err
+	}
+	sdb.SetMaxOpenConns(1)
+	e := &sqliteExport{db: sdb}
+	if _, err := sdb.ExecContext(ctx, sqliteSchema); err != nil {
+		e.close()
+		return nil, err
+	}
+	if e.tx, err = sdb.BeginTx(ctx, nil); err != nil {
+		e.close()
+		return nil, err
+	}
+	e.insert, err = e.tx.PrepareContext(ctx, `INSERT INTO loc_records VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`)
+	if err != nil {
+		e.close()
+		return nil, err
+	}
+	return e, nil
+}
+
+// add inserts one record.
+func (e *sqliteExport) add(ctx context.Context, rec api.PublicLOCRecord) error {
+	chain := rec.CNAMEChain
+	if chain == nil {
+		chain = []string{}
+	}
+	chainJSON, err := json.Marshal(chain)
+	if err != nil {
+		return err
+	}
+	_, err = e.insert.ExecContext(ctx,
+		rec.FQDN, rec.RootDomain, rec.RawRecord,
+		rec.Latitude, rec.Longitude, rec.AltitudeM,
+		rec.SizeM, rec.HorizPrecM, rec.VertPrecM,
+		rec.FirstSeenAt.UTC().Format(time.RFC3339),
+		rec.LastSeenAt.UTC().Format(time.RFC3339),
+		string(chainJSON))
+	return err
+}
+
+// finish commits the records, creates the indexes and closes the database,
+// leaving a complete file at the path. It must only be called after every
+// record was added.
+func (e *sqliteExport) finish(ctx context.Context) error {
+	defer e.close()
+	if err := e.insert.Close(); err != nil {
+		return err
+	}
+	if err := e.tx.Commit(); err != nil {
+		return err
+	}
+	for _, stmt := range sqliteIndexes {
+		if _, err := e.db.ExecContext(ctx, stmt); err != nil {
+			return err
+		}
+	}
+	return e.db.Close()
+}
+
+// close releases the database without committing. It is safe to call after
+// finish.
+func (e *sqliteExport) close() {
+	if e.tx != nil {
+		_ = e.tx.Rollback() //nolint:errcheck // Already committed or aborted
+	}
+	_ = e.db.Close() //nolint:errcheck // Nothing to recover
+}
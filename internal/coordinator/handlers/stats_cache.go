@@ -0,0 +1,59 @@
+package handlers
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/locplace/scanner/pkg/api"
+)
+
+// StatsCache keeps the last GET /api/public/stats response for a short time,
+// so frequent polling does not run the stats queries on every request.
+type StatsCache struct {
+	ttl time.Duration
+	now func() time.Time
+
+	mu      sync.Mutex
+	stats   api.StatsResponse
+	expires time.Time
+}
+
+// NewStatsCache creates a cache that keeps stats for ttl.
+func NewStatsCache(ttl time.Duration) *StatsCache {
+	return &StatsCache{
+		ttl: ttl,
+		now: time.Now,
+	}
+}
+
+// Get returns the cached stats, calling load if they are missing or expired.
+// Concurrent callers wait for a single load. Errors are not cached.
+func (c *StatsCache) Get(ctx context.Context, load func(context.Context) (api.StatsResponse, error)) (api.StatsResponse, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.now().Before(c.expires) {
+		return c.stats, nil
+	}
+
+	stats, err := load(ctx)
+	if err != nil {
+		return api.StatsResponse{}, err
+	}
+	c.stats = stats
+	c.expires = c.now().Add(c.ttl)
+	return stats, nil
+}
+
+// Purge drops the cached stats so the next Get loads fresh ones. It reports
+// whether there was a cache to purge; a nil cache (caching disabled) is fine.
+func (c *StatsCache) Purge() bool {
+	if c == nil {
+		return false
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.expires = time.Time{}
+	return true
+}
@@ -0,0 +1,17 @@
+package handlers
+
+import (
+	"net/http"
+	"time"
+)
+
+// streamWriteTimeout is how long a streaming handler may take to write its
+// next chunk. Streams can run for longer than the server's WriteTimeout, so
+// they push the deadline forward as they make progress.
+const streamWriteTimeout = 30 * time.Second
+
+// extendWriteDeadline gives a streaming response another streamWriteTimeout
+// to write. Writers without deadline support are left as is.
+func extendWriteDeadline(w http.ResponseWriter) {
+	_ = http.NewResponseController(w).SetWriteDeadline(time.Now().Add(streamWriteTimeout)) //nolint:errcheck // Unsupported by some writers, e.g. in tests
+}
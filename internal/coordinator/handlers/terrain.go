@@ -0,0 +1,45 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/locplace/scanner/pkg/api"
+)
+
+// errNoTerrain is returned for terrain=true by a build without the terrain
+// model (see api.TerrainAvailable).
+var errNoTerrain = errors.New("no terrain model is embedded in this build")
+
+// parseTerrainParam reads the terrain=true option, which adds the altitude
+// anomaly from the embedded terrain model to each record.
+func parseTerrainParam(r *http.Request) (bool, error) {
+	s := r.URL.Query().Get("terrain")
+	if s == "" {
+		return false, nil
+	}
+	v, err := strconv.ParseBool(s)
+	if err != nil {
+		return false, errors.New("terrain must be true or false")
+	}
+	if v && !api.TerrainAvailable() {
+		return false, errNoTerrain
+	}
+	return v, nil
+}
+
+// writeTerrainParamError writes the response for a parseTerrainParam error.
+func writeTerrainParamError(w http.ResponseWriter, err error) {
+	status := http.StatusBadRequest
+	if errors.Is(err, errNoTerrain) {
+		status = http.StatusNotImplemented
+	}
+	writeError(w, err.Error(), status)
+}
+
+// altitudeAnomaly returns rec's altitude anomaly in the API form.
+func altitudeAnomaly(rec api.LOCRecord) *api.AltitudeAnomaly {
+	delta, anomalous := rec.AltitudeAnomalyM()
+	return &api.AltitudeAnomaly{DeltaM: delta, Anomalous: anomalous}
+}
@@ -85,6 +85,13 @@ var (
 		Help: "Number of unique root domains that have at least one LOC record (gauge, from DB).",
 	})
 
+	// LOCRecordsByTLD is the number of LOC records per TLD, for the largest
+	// TLDs only (see UpdaterConfig.TopTLDs); the rest are summed as "other".
+	LOCRecordsByTLD = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "locplace_loc_records_by_tld",
+		Help: "Number of LOC records per TLD for the top TLDs, the rest summed as tld=\"other\" (gauge, from DB).",
+	}, []string{"tld"})
+
 	// ScannersTotal is the total number of registered scanner clients.
 	ScannersTotal = prometheus.NewGauge(prometheus.GaugeOpts{
 		Name: "locplace_scanners_total",
@@ -96,6 +103,18 @@ var (
 		Name: "locplace_scanners_active",
 		Help: "Number of scanner clients with a heartbeat within the timeout period (gauge, from DB).",
 	})
+
+	// MaintenanceMode is 1 while the coordinator rejects writes.
+	MaintenanceMode = prometheus.NewGauge(prometheus.GaugeOpts{
+		Name: "locplace_maintenance_mode",
+		Help: "1 if the coordinator is in read-only maintenance mode, 0 otherwise (gauge).",
+	})
+
+	// ExportsActive counts export requests currently being served.
+	ExportsActive = prometheus.NewGauge(prometheus.GaugeOpts{
+		Name: "locplace_exports_active",
+		Help: "Number of export requests (GeoJSON, JSONL, SQLite) currently being served (gauge).",
+	})
 )
 
 // Database pool metrics.
@@ -119,6 +138,29 @@ var (
 		Name: "locplace_db_pool_max_conns",
 		Help: "Maximum number of connections allowed in the pool.",
 	})
+
+	// The acquire stats below are cumulative values reported by pgxpool.
+	// Use rate()/deriv() on them to see pool contention over time.
+
+	DBPoolAcquireCount = prometheus.NewGauge(prometheus.GaugeOpts{
+		Name: "locplace_db_pool_acquire_count",
+		Help: "Cumulative count of successful connection acquires from the pool.",
+	})
+
+	DBPoolAcquireDuration = prometheus.NewGauge(prometheus.GaugeOpts{
+		Name: "locplace_db_pool_acquire_duration_seconds",
+		Help: "Cumulative time spent waiting for successful connection acquires, in seconds.",
+	})
+
+	DBPoolEmptyAcquireCount = prometheus.NewGauge(prometheus.GaugeOpts{
+		Name: "locplace_db_pool_empty_acquire_count",
+		Help: "Cumulative count of acquires that had to wait because the pool was empty.",
+	})
+
+	DBPoolCanceledAcquireCount = prometheus.NewGauge(prometheus.GaugeOpts{
+		Name: "locplace_db_pool_canceled_acquire_count",
+		Help: "Cumulative count of acquires canceled by a context (e.g. timeouts) before getting a connection.",
+	})
 )
 
 // ========================================
@@ -162,12 +204,45 @@ var (
 		Name: "locplace_reaper_batches_released_total",
 		Help: "Total number of batches released by the reaper due to timeout (counter).",
 	})
+
+	// ScanErrorsTotal counts failed lookups reported by scanners, by error class.
+	ScanErrorsTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "locplace_scan_errors_total",
+		Help: "Total number of failed lookups reported by scanners, by error class (counter).",
+	}, []string{"class"})
+
+	// LOCParseResultsTotal counts submitted LOC records by parse outcome.
+	LOCParseResultsTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "locplace_loc_parse_results_total",
+		Help: "Total number of LOC records submitted by scanners, by parse result: parsed or failed (counter).",
+	}, []string{"result"})
+
+	// RecordsRejectedTotal counts valid LOC records refused by policy.
+	RecordsRejectedTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "locplace_records_rejected_total",
+		Help: "Total number of valid LOC records not stored by policy, by reason: tld or precision (counter).",
+	}, []string{"reason"})
+
+	// CachePurgesTotal counts stats cache purges.
+	CachePurgesTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "locplace_cache_purges_total",
+		Help: "Total number of stats cache purges, by trigger: manual (POST /api/admin/cache/purge) or auto (after bulk admin changes) (counter).",
+	}, []string{"trigger"})
+
+	// FileRescansTotal counts single domain files reset for re-scanning.
+	FileRescansTotal = prometheus.NewCounter(prometheus.CounterOpts{
+		Name: "locplace_file_rescans_total",
+		Help: "Total number of domain files reset for re-scanning via the admin API (counter).",
+	})
 )
 
 // ========================================
 // HTTP Metrics
 // ========================================
 
+// sizeBuckets cover 64 B to 256 MiB, the manual-scan upload limit.
+var sizeBuckets = prometheus.ExponentialBuckets(64, 4, 12)
+
 var (
 	// HTTPRequestsTotal counts HTTP requests by method, path, and status.
 	HTTPRequestsTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
@@ -182,6 +257,20 @@ var (
 		Buckets: []float64{.001, .005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10},
 	}, []string{"method", "path"})
 
+	// HTTPRequestSize tracks request body bytes read by handlers.
+	HTTPRequestSize = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "locplace_http_request_size_bytes",
+		Help:    "HTTP request body size in bytes, as read by the handler.",
+		Buckets: sizeBuckets,
+	}, []string{"method", "path"})
+
+	// HTTPResponseSize tracks response body bytes written, after compression.
+	HTTPResponseSize = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "locplace_http_response_size_bytes",
+		Help:    "HTTP response body size in bytes, after compression.",
+		Buckets: sizeBuckets,
+	}, []string{"method", "path"})
+
 	// HTTPRequestsInFlight tracks concurrent request count.
 	HTTPRequestsInFlight = prometheus.NewGauge(prometheus.GaugeOpts{
 		Name: "locplace_http_requests_in_flight",
@@ -220,14 +309,21 @@ func Register() {
 	// Gauges - Results
 	prometheus.MustRegister(LOCRecordsTotal)
 	prometheus.MustRegister(DomainsWithLOC)
+	prometheus.MustRegister(LOCRecordsByTLD)
 	prometheus.MustRegister(ScannersTotal)
 	prometheus.MustRegister(ScannersActive)
+	prometheus.MustRegister(MaintenanceMode)
+	prometheus.MustRegister(ExportsActive)
 
 	// DB pool
 	prometheus.MustRegister(DBPoolTotalConns)
 	prometheus.MustRegister(DBPoolAcquiredConns)
 	prometheus.MustRegister(DBPoolIdleConns)
 	prometheus.MustRegister(DBPoolMaxConns)
+	prometheus.MustRegister(DBPoolAcquireCount)
+	prometheus.MustRegister(DBPoolAcquireDuration)
+	prometheus.MustRegister(DBPoolEmptyAcquireCount)
+	prometheus.MustRegister(DBPoolCanceledAcquireCount)
 
 	// Counters
 	prometheus.MustRegister(ScanCompletionsTotal)
@@ -236,10 +332,17 @@ func Register() {
 	prometheus.MustRegister(LOCDiscoveriesTotal)
 	prometheus.MustRegister(ReaperRunsTotal)
 	prometheus.MustRegister(ReaperBatchesReleasedTotal)
+	prometheus.MustRegister(FileRescansTotal)
+	prometheus.MustRegister(ScanErrorsTotal)
+	prometheus.MustRegister(LOCParseResultsTotal)
+	prometheus.MustRegister(RecordsRejectedTotal)
+	prometheus.MustRegister(CachePurgesTotal)
 
 	// HTTP
 	prometheus.MustRegister(HTTPRequestsTotal)
 	prometheus.MustRegister(HTTPRequestDuration)
+	prometheus.MustRegister(HTTPRequestSize)
+	prometheus.MustRegister(HTTPResponseSize)
 	prometheus.MustRegister(HTTPRequestsInFlight)
 	prometheus.MustRegister(HTTPReferrerRequests)
 
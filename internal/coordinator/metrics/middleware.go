@@ -1,15 +1,146 @@
 package metrics
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"log"
+	"net"
 	"net/http"
 	"strconv"
+	"strings"
+	"sync"
 	"time"
+
+	chimw "github.com/go-chi/chi/v5/middleware"
+)
+
+// ReferrerOther is the label used for referrer domains that are not broken out.
+const ReferrerOther = "other"
+
+// Labels used by ReferrerModeInternal.
+const (
+	ReferrerInternal = "internal"
+	ReferrerExternal = "external"
 )
 
-// responseWriter wraps http.ResponseWriter to capture the status code.
+// ReferrerMode controls how much of the referrer domain reaches metric labels.
+type ReferrerMode string
+
+const (
+	ReferrerModeFull     ReferrerMode = "full"     // Domain names (the default)
+	ReferrerModeHash     ReferrerMode = "hash"     // Salted SHA-256 prefixes of domain names
+	ReferrerModeInternal ReferrerMode = "internal" // Only internal, external or direct
+)
+
+// ParseReferrerMode parses a ReferrerMode; "" means ReferrerModeFull.
+func ParseReferrerMode(s string) (ReferrerMode, error) {
+	switch m := ReferrerMode(strings.ToLower(s)); m {
+	case "":
+		return ReferrerModeFull, nil
+	case ReferrerModeFull, ReferrerModeHash, ReferrerModeInternal:
+		return m, nil
+	default:
+		return "", fmt.Errorf("referrer mode must be full, hash or internal, got %q", s)
+	}
+}
+
+// ReferrerBucketer bounds the label space of HTTPReferrerRequests. Allowlisted
+// domains always get their own label; beyond those, the first maxDomains
+// distinct domains seen are broken out and everything else becomes "other".
+// This keeps crafted Referer headers from exploding metric cardinality.
+//
+// Mode can hide domain names: ReferrerModeHash labels domains with a salted
+// hash (allowlisted ones included), and ReferrerModeInternal only tells the
+// request's own host and allowlisted domains (internal) apart from the rest
+// (external). Set Mode and HashSalt before first use.
+type ReferrerBucketer struct {
+	Mode     ReferrerMode
+	HashSalt string
+
+	allow      map[string]bool
+	maxDomains int
+
+	mu   sync.Mutex
+	seen map[string]bool
+}
+
+// NewReferrerBucketer creates a bucketer. allowlist entries are matched
+// case-insensitively; maxDomains may be 0 to only break out allowlisted domains.
+func NewReferrerBucketer(allowlist []string, maxDomains int) *ReferrerBucketer {
+	allow := make(map[string]bool, len(allowlist))
+	for _, d := range allowlist {
+		if d = strings.ToLower(strings.TrimSpace(d)); d != "" {
+			allow[d] = true
+		}
+	}
+	return &ReferrerBucketer{
+		allow:      allow,
+		maxDomains: maxDomains,
+		seen:       make(map[string]bool),
+	}
+}
+
+// Label returns the metric label for a Referer header value on a request
+// for host (the Host header, used by ReferrerModeInternal).
+func (b *ReferrerBucketer) Label(referer, host string) string {
+	domain := strings.ToLower(ExtractReferrerDomain(referer))
+	if domain == "direct" {
+		return domain
+	}
+	if b.Mode == ReferrerModeInternal {
+		if b.allow[domain] || hostname(domain) == hostname(strings.ToLower(host)) {
+			return ReferrerInternal
+		}
+		return ReferrerExternal
+	}
+
+	label := domain
+	if !b.allow[domain] {
+		label = b.bucket(domain)
+	}
+	if b.Mode == ReferrerModeHash && label != ReferrerOther {
+		return b.hash(label)
+	}
+	return label
+}
+
+// bucket returns domain if it is among the first maxDomains seen, else ReferrerOther.
+func (b *ReferrerBucketer) bucket(domain string) string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.seen[domain] {
+		return domain
+	}
+	if len(b.seen) < b.maxDomains {
+		b.seen[domain] = true
+		return domain
+	}
+	return ReferrerOther
+}
+
+// hash returns a short salted hash of domain, stable across restarts for
+// the same salt so dashboards can still follow a referrer over time.
+func (b *ReferrerBucketer) hash(domain string) string {
+	sum := sha256.Sum256([]byte(b.HashSalt + domain))
+	return "h_" + hex.EncodeToString(sum[:6])
+}
+
+// hostname strips an optional port from host.
+func hostname(host string) string {
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		return h
+	}
+	return host
+}
+
+// responseWriter wraps http.ResponseWriter to capture the status code and
+// the number of body bytes written.
 type responseWriter struct {
 	http.ResponseWriter
 	statusCode int
+	bytes      int64
 }
 
 func (rw *responseWriter) WriteHeader(code int) {
@@ -17,35 +148,97 @@ func (rw *responseWriter) WriteHeader(code int) {
 	rw.ResponseWriter.WriteHeader(code)
 }
 
-// Middleware returns HTTP middleware that records request metrics.
+func (rw *responseWriter) Write(p []byte) (int, error) {
+	n, err := rw.ResponseWriter.Write(p)
+	rw.bytes += int64(n)
+	return n, err
+}
+
+// countingBody wraps a request body to count the bytes the handler reads.
+// Content-Length is missing for chunked uploads, so it isn't used.
+type countingBody struct {
+	io.ReadCloser
+	bytes int64
+}
+
+func (b *countingBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	b.bytes += int64(n)
+	return n, err
+}
+
+// Flush implements http.Flusher so streaming responses work through the wrapper.
+func (rw *responseWriter) Flush() {
+	if f, ok := rw.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// Unwrap returns the underlying ResponseWriter for http.ResponseController.
+func (rw *responseWriter) Unwrap() http.ResponseWriter {
+	return rw.ResponseWriter
+}
+
+// Middleware returns HTTP middleware that records request metrics, breaking out
+// at most 50 referrer domains.
 func Middleware(next http.Handler) http.Handler {
-	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		start := time.Now()
+	return MiddlewareWithReferrers(NewReferrerBucketer(nil, 50))(next)
+}
 
-		// Track in-flight requests
-		HTTPRequestsInFlight.Inc()
-		defer HTTPRequestsInFlight.Dec()
+// MiddlewareWithReferrers is like Middleware but labels referrers with b.
+func MiddlewareWithReferrers(b *ReferrerBucketer) func(http.Handler) http.Handler {
+	return NewMiddleware(MiddlewareConfig{Referrers: b})
+}
 
-		// Wrap response writer to capture status code
-		wrapped := &responseWriter{ResponseWriter: w, statusCode: http.StatusOK}
+// MiddlewareConfig configures NewMiddleware.
+type MiddlewareConfig struct {
+	Referrers *ReferrerBucketer // Labels for HTTPReferrerRequests
+	// SlowRequestThreshold logs requests that take at least this long, with
+	// the chi request ID if chimw.RequestID runs first (0 = disabled).
+	SlowRequestThreshold time.Duration
+}
 
-		// Process request
-		next.ServeHTTP(wrapped, r)
+// NewMiddleware returns HTTP middleware that records request metrics.
+func NewMiddleware(cfg MiddlewareConfig) func(http.Handler) http.Handler {
+	b := cfg.Referrers
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			start := time.Now()
 
-		// Record metrics
-		duration := time.Since(start).Seconds()
-		path := NormalizePath(r.URL.Path)
-		status := strconv.Itoa(wrapped.statusCode)
+			// Track in-flight requests
+			HTTPRequestsInFlight.Inc()
+			defer HTTPRequestsInFlight.Dec()
 
-		HTTPRequestsTotal.WithLabelValues(r.Method, path, status).Inc()
-		HTTPRequestDuration.WithLabelValues(r.Method, path).Observe(duration)
+			// Wrap response writer and body to capture status code and sizes
+			wrapped := &responseWriter{ResponseWriter: w, statusCode: http.StatusOK}
+			body := &countingBody{ReadCloser: r.Body}
+			r.Body = body
 
-		// Track referrer for non-API requests (public pages)
-		if !isAPIPath(r.URL.Path) {
-			referrer := ExtractReferrerDomain(r.Header.Get("Referer"))
-			HTTPReferrerRequests.WithLabelValues(referrer).Inc()
-		}
-	})
+			// Process request
+			next.ServeHTTP(wrapped, r)
+
+			// Record metrics
+			elapsed := time.Since(start)
+			duration := elapsed.Seconds()
+			path := NormalizePath(r.URL.Path)
+			status := strconv.Itoa(wrapped.statusCode)
+
+			if cfg.SlowRequestThreshold > 0 && elapsed >= cfg.SlowRequestThreshold {
+				log.Printf("Slow request: id=%s %s %s status=%s duration=%s bytes=%d",
+					chimw.GetReqID(r.Context()), r.Method, r.URL.Path, status, elapsed.Round(time.Millisecond), wrapped.bytes)
+			}
+
+			HTTPRequestsTotal.WithLabelValues(r.Method, path, status).Inc()
+			HTTPRequestDuration.WithLabelValues(r.Method, path).Observe(duration)
+			HTTPRequestSize.WithLabelValues(r.Method, path).Observe(float64(body.bytes))
+			HTTPResponseSize.WithLabelValues(r.Method, path).Observe(float64(wrapped.bytes))
+
+			// Track referrer for non-API requests (public pages)
+			if !isAPIPath(r.URL.Path) {
+				HTTPReferrerRequests.WithLabelValues(b.Label(r.Header.Get("Referer"), r.Host)).Inc()
+			}
+		})
+	}
 }
 
 func isAPIPath(path string) bool {
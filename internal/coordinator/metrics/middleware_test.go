@@ -0,0 +1,133 @@
+package metrics
+
+import (
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	chimw "github.com/go-chi/chi/v5/middleware"
+)
+
+func TestReferrerBucketer_Label(t *testing.T) {
+	b := NewReferrerBucketer([]string{"Partner.example"}, 1)
+
+	tests := []struct {
+		referer string
+		want    string
+	}{
+		{"", "direct"},
+		{"https://partner.example/map", "partner.example"},
+		{"https://first.example/", "first.example"},
+		{"https://second.example/", ReferrerOther},
+		{"https://first.example/again", "first.example"},
+		{"https://PARTNER.example/", "partner.example"},
+	}
+
+	for _, tt := range tests {
+		if got := b.Label(tt.referer, "locplace.example"); got != tt.want {
+			t.Errorf("Label(%q) = %q, want %q", tt.referer, got, tt.want)
+		}
+	}
+}
+
+func TestReferrerBucketer_Modes(t *testing.T) {
+	t.Run("hash", func(t *testing.T) {
+		b := NewReferrerBucketer([]string{"partner.example"}, 1)
+		b.Mode, b.HashSalt = ReferrerModeHash, "salt"
+
+		partner := b.Label("https://partner.example/", "")
+		first := b.Label("https://first.example/", "")
+		if strings.Contains(partner, "partner") || strings.Contains(first, "first") || !strings.HasPrefix(first, "h_") {
+			t.Errorf("labels should be hashed, got %q and %q", partner, first)
+		}
+		if again := b.Label("https://first.example/x", ""); again != first {
+			t.Errorf("same domain should hash the same, got %q and %q", again, first)
+		}
+		if got := b.Label("https://second.example/", ""); got != ReferrerOther {
+			t.Errorf("domains beyond the cap = %q, want %q", got, ReferrerOther)
+		}
+		if got := b.Label("", ""); got != "direct" {
+			t.Errorf("no referrer = %q, want direct", got)
+		}
+
+		other := NewReferrerBucketer(nil, 1)
+		other.Mode, other.HashSalt = ReferrerModeHash, "pepper"
+		if other.Label("https://first.example/", "") == first {
+			t.Error("different salts should give different labels")
+		}
+	})
+
+	t.Run("internal", func(t *testing.T) {
+		b := NewReferrerBucketer([]string{"docs.locplace.example"}, 50)
+		b.Mode = ReferrerModeInternal
+
+		tests := []struct {
+			referer, host, want string
+		}{
+			{"https://locplace.example/map", "locplace.example", ReferrerInternal},
+			{"http://localhost:8080/", "localhost:8080", ReferrerInternal},
+			{"https://LocPlace.example/", "locplace.example:443", ReferrerInternal},
+			{"https://docs.locplace.example/", "locplace.example", ReferrerInternal},
+			{"https://news.example/story", "locplace.example", ReferrerExternal},
+			{"", "locplace.example", "direct"},
+		}
+		for _, tt := range tests {
+			if got := b.Label(tt.referer, tt.host); got != tt.want {
+				t.Errorf("Label(%q, %q) = %q, want %q", tt.referer, tt.host, got, tt.want)
+			}
+		}
+	})
+}
+
+func TestParseReferrerMode(t *testing.T) {
+	for in, want := range map[string]ReferrerMode{"": ReferrerModeFull, "full": ReferrerModeFull, "Hash": ReferrerModeHash, "internal": ReferrerModeInternal} {
+		if got, err := ParseReferrerMode(in); err != nil || got != want {
+			t.Errorf("ParseReferrerMode(%q) = %q, %v; want %q", in, got, err, want)
+		}
+	}
+	if _, err := ParseReferrerMode("drop"); err == nil {
+		t.Error("ParseReferrerMode(\"drop\") should fail")
+	}
+}
+
+func TestSizeCounting(t *testing.T) {
+	body := &countingBody{ReadCloser: io.NopCloser(strings.NewReader("0123456789"))}
+	if _, err := io.Copy(io.Discard, body); err != nil {
+		t.Fatal(err)
+	}
+	if body.bytes != 10 {
+		t.Errorf("request bytes = %d, want 10", body.bytes)
+	}
+
+	rw := &responseWriter{ResponseWriter: httptest.NewRecorder(), statusCode: http.StatusOK}
+	_, _ = rw.Write([]byte("hello"))
+	_, _ = rw.Write([]byte(" world"))
+	if rw.bytes != 11 {
+		t.Errorf("response bytes = %d, want 11", rw.bytes)
+	}
+}
+
+func TestNewMiddleware_SlowRequestLog(t *testing.T) {
+	var buf strings.Builder
+	log.SetOutput(&buf)
+	defer log.SetOutput(os.Stderr)
+
+	ok := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
+	for _, threshold := range []time.Duration{0, time.Hour, time.Nanosecond} {
+		mw := NewMiddleware(MiddlewareConfig{Referrers: NewReferrerBucketer(nil, 1), SlowRequestThreshold: threshold})
+		chimw.RequestID(mw(ok)).ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/api/public/stats", nil))
+	}
+
+	// Only the 1ns threshold should have logged
+	if got := strings.Count(buf.String(), "Slow request"); got != 1 {
+		t.Fatalf("logged %d slow requests, want 1: %s", got, buf.String())
+	}
+	if !strings.Contains(buf.String(), "/api/public/stats") || strings.Contains(buf.String(), "id= ") {
+		t.Errorf("log line should contain path and request ID: %s", buf.String())
+	}
+}
@@ -3,6 +3,7 @@ package metrics
 import (
 	"context"
 	"log"
+	"math/rand/v2"
 	"time"
 
 	"github.com/jackc/pgx/v5/pgxpool"
@@ -14,6 +15,15 @@ import (
 type UpdaterConfig struct {
 	Interval         time.Duration
 	HeartbeatTimeout time.Duration
+	// StartupJitter is the maximum random delay before the first update, so
+	// replicas deployed together don't query the database at the same moment.
+	// Zero updates immediately on start.
+	StartupJitter time.Duration
+	// IntervalJitter is the maximum random delay added to each Interval.
+	IntervalJitter time.Duration
+	// TopTLDs is how many TLDs get their own locplace_loc_records_by_tld
+	// series; the rest are summed as "other". Zero disables the gauge.
+	TopTLDs int
 }
 
 // Updater periodically updates gauge metrics from the database.
@@ -36,23 +46,29 @@ func NewUpdater(database *db.DB, config UpdaterConfig) *Updater {
 func (u *Updater) Run(ctx context.Context) {
 	log.Printf("Metrics updater started: interval=%s", u.config.Interval)
 
-	// Update immediately on start
-	u.update(ctx)
-
-	ticker := time.NewTicker(u.config.Interval)
-	defer ticker.Stop()
+	timer := time.NewTimer(jitter(0, u.config.StartupJitter))
+	defer timer.Stop()
 
 	for {
 		select {
 		case <-ctx.Done():
 			log.Println("Metrics updater stopped")
 			return
-		case <-ticker.C:
+		case <-timer.C:
 			u.update(ctx)
+			timer.Reset(jitter(u.config.Interval, u.config.IntervalJitter))
 		}
 	}
 }
 
+// jitter returns base plus a random duration in [0, maxJitter).
+func jitter(base, maxJitter time.Duration) time.Duration {
+	if maxJitter <= 0 {
+		return base
+	}
+	return base + rand.N(maxJitter)
+}
+
 func (u *Updater) update(ctx context.Context) {
 	// Get metrics snapshot from database
 	snapshot, err := u.db.GetMetricsSnapshot(ctx, u.config.HeartbeatTimeout)
@@ -75,10 +91,50 @@ func (u *Updater) update(ctx context.Context) {
 	ScannersTotal.Set(float64(snapshot.ScannersTotal))
 	ScannersActive.Set(float64(snapshot.ScannersActive))
 
+	if u.config.TopTLDs > 0 {
+		u.updateTLDs(ctx)
+	}
+
 	// Update pool stats
 	poolStats := u.pool.Stat()
 	DBPoolTotalConns.Set(float64(poolStats.TotalConns()))
 	DBPoolAcquiredConns.Set(float64(poolStats.AcquiredConns()))
 	DBPoolIdleConns.Set(float64(poolStats.IdleConns()))
 	DBPoolMaxConns.Set(float64(poolStats.MaxConns()))
+	DBPoolAcquireCount.Set(float64(poolStats.AcquireCount()))
+	DBPoolAcquireDuration.Set(poolStats.AcquireDuration().Seconds())
+	DBPoolEmptyAcquireCount.Set(float64(poolStats.EmptyAcquireCount()))
+	DBPoolCanceledAcquireCount.Set(float64(poolStats.CanceledAcquireCount()))
+}
+
+// updateTLDs replaces the records-by-TLD series, so TLDs that drop out of
+// the top stop being reported.
+func (u *Updater) updateTLDs(ctx context.Context) {
+	counts, err := u.db.CountLOCRecordsByTLD(ctx)
+	if err != nil {
+		log.Printf("Metrics updater: failed to count records by TLD: %v", err)
+		return
+	}
+	LOCRecordsByTLD.Reset()
+	for tld, n := range topTLDBuckets(counts, u.config.TopTLDs) {
+		LOCRecordsByTLD.WithLabelValues(tld).Set(float64(n))
+	}
+}
+
+// otherTLDs is the label for records outside the top TLDs.
+const otherTLDs = "other"
+
+// topTLDBuckets keeps the n largest TLDs of counts (sorted largest first)
+// and sums the rest under otherTLDs. A TLD literally named "other" is
+// folded into that bucket too.
+func topTLDBuckets(counts []db.TLDCount, n int) map[string]int {
+	buckets := make(map[string]int, n+1)
+	for i, c := range counts {
+		if i < n && c.TLD != otherTLDs {
+			buckets[c.TLD] = c.Count
+		} else {
+			buckets[otherTLDs] += c.Count
+		}
+	}
+	return buckets
 }
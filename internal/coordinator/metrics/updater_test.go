@@ -0,0 +1,38 @@
+package metrics
+
+import (
+	"maps"
+	"testing"
+	"time"
+
+	"github.com/locplace/scanner/internal/coordinator/db"
+)
+
+func TestJitter(t *testing.T) {
+	if got := jitter(15*time.Second, 0); got != 15*time.Second {
+		t.Errorf("jitter without max = %v, want base", got)
+	}
+	if got := jitter(0, 0); got != 0 {
+		t.Errorf("jitter(0, 0) = %v, want immediate", got)
+	}
+	for range 100 {
+		got := jitter(15*time.Second, 5*time.Second)
+		if got < 15*time.Second || got >= 20*time.Second {
+			t.Fatalf("jitter = %v, want within [15s, 20s)", got)
+		}
+	}
+}
+
+func TestTopTLDBuckets(t *testing.T) {
+	counts := []db.TLDCount{{TLD: "com", Count: 500}, {TLD: "nl", Count: 120}, {TLD: "other", Count: 7}, {TLD: "edu", Count: 40}, {TLD: "de", Count: 3}}
+
+	got := topTLDBuckets(counts, 3)
+	want := map[string]int{"com": 500, "nl": 120, "other": 7 + 40 + 3}
+	if !maps.Equal(got, want) {
+		t.Errorf("topTLDBuckets(3) = %v, want %v", got, want)
+	}
+
+	if got := topTLDBuckets(counts[:2], 5); !maps.Equal(got, map[string]int{"com": 500, "nl": 120}) {
+		t.Errorf("topTLDBuckets with fewer TLDs than n = %v, want no other bucket", got)
+	}
+}
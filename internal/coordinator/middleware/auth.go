@@ -3,6 +3,10 @@ package middleware
 
 import (
 	"context"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/hex"
+	"fmt"
 	"net/http"
 	"strings"
 
@@ -14,40 +18,87 @@ type contextKey string
 const (
 	// ClientContextKey is the context key for the authenticated client.
 	ClientContextKey contextKey = "client"
+	// AdminKeyIDContextKey is the context key for the AdminKeyID of the
+	// admin key a request authenticated with.
+	AdminKeyIDContextKey contextKey = "admin_key_id"
 )
 
+// AdminKeyID identifies an admin API key in the audit log without revealing
+// it: the first 16 hex digits of its SHA-256 hash.
+func AdminKeyID(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:8])
+}
+
 // AdminAuth returns middleware that validates the admin API key.
 func AdminAuth(apiKey string) func(http.Handler) http.Handler {
+	keyID := AdminKeyID(apiKey)
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 			key := r.Header.Get("X-Admin-Key")
 			if key == "" || key != apiKey {
-				http.Error(w, `{"error":"unauthorized"}`, http.StatusUnauthorized)
+				writeError(w, "unauthorized", http.StatusUnauthorized)
 				return
 			}
-			next.ServeHTTP(w, r)
+			ctx := context.WithValue(r.Context(), AdminKeyIDContextKey, keyID)
+			next.ServeHTTP(w, r.WithContext(ctx))
 		})
 	}
 }
 
-// ScannerAuth returns middleware that validates scanner bearer tokens.
-func ScannerAuth(database *db.DB) func(http.Handler) http.Handler {
+// ScannerAuthMode selects how scanners authenticate.
+type ScannerAuthMode string
+
+// Scanner authentication modes.
+const (
+	ScannerAuthToken ScannerAuthMode = "token" // Bearer token (default)
+	ScannerAuthMTLS  ScannerAuthMode = "mtls"  // Verified TLS client certificate only
+	ScannerAuthAny   ScannerAuthMode = "any"   // Either; a presented certificate is tried first
+)
+
+// ParseScannerAuthMode parses a SCANNER_AUTH value. Empty means token.
+func ParseScannerAuthMode(s string) (ScannerAuthMode, error) {
+	switch mode := ScannerAuthMode(strings.ToLower(strings.TrimSpace(s))); mode {
+	case "":
+		return ScannerAuthToken, nil
+	case ScannerAuthToken, ScannerAuthMTLS, ScannerAuthAny:
+		return mode, nil
+	default:
+		return "", fmt.Errorf("unknown scanner auth mode %q (want token, mtls or any)", s)
+	}
+}
+
+// UsesCertificates reports whether the mode accepts client certificates.
+func (m ScannerAuthMode) UsesCertificates() bool {
+	return m == ScannerAuthMTLS || m == ScannerAuthAny
+}
+
+// ScannerAuth returns middleware that authenticates scanners by bearer token,
+// by verified TLS client certificate mapped to a registered client's
+// cert_identity, or either, depending on mode. Certificates are verified by
+// the TLS server (tls.Config.ClientCAs), so only verified chains count here.
+func ScannerAuth(database *db.DB, mode ScannerAuthMode) func(http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			var client *db.ScannerClient
+			var err error
+			cert := verifiedClientCert(r)
 			auth := r.Header.Get("Authorization")
-			if auth == "" || !strings.HasPrefix(auth, "Bearer ") {
-				http.Error(w, `{"error":"unauthorized"}`, http.StatusUnauthorized)
+			switch {
+			case mode.UsesCertificates() && cert != nil:
+				client, err = database.GetClientByCertIdentity(r.Context(), CertIdentities(cert))
+			case mode != ScannerAuthMTLS && strings.HasPrefix(auth, "Bearer "):
+				client, err = database.GetClientByToken(r.Context(), strings.TrimPrefix(auth, "Bearer "))
+			default:
+				writeError(w, "unauthorized", http.StatusUnauthorized)
 				return
 			}
-
-			token := strings.TrimPrefix(auth, "Bearer ")
-			client, err := database.GetClientByToken(r.Context(), token)
 			if err != nil {
-				http.Error(w, `{"error":"internal server error"}`, http.StatusInternalServerError)
+				writeError(w, "internal server error", http.StatusInternalServerError)
 				return
 			}
 			if client == nil {
-				http.Error(w, `{"error":"unauthorized"}`, http.StatusUnauthorized)
+				writeError(w, "unauthorized", http.StatusUnauthorized)
 				return
 			}
 
@@ -57,9 +108,41 @@ func ScannerAuth(database *db.DB) func(http.Handler) http.Handler {
 	}
 }
 
+// verifiedClientCert returns the leaf of the client certificate chain the
+// TLS server verified, or nil.
+func verifiedClientCert(r *http.Request) *x509.Certificate {
+	if r.TLS == nil || len(r.TLS.VerifiedChains) == 0 || len(r.TLS.VerifiedChains[0]) == 0 {
+		return nil
+	}
+	return r.TLS.VerifiedChains[0][0]
+}
+
+// CertIdentities returns the identities a client certificate can be
+// registered under, most specific first: URI SANs (e.g. SPIFFE IDs), DNS
+// SANs, email SANs, then the subject common name.
+func CertIdentities(cert *x509.Certificate) []string {
+	var ids []string
+	for _, u := range cert.URIs {
+		ids = append(ids, u.String())
+	}
+	ids = append(ids, cert.DNSNames...)
+	ids = append(ids, cert.EmailAddresses...)
+	if cert.Subject.CommonName != "" {
+		ids = append(ids, cert.Subject.CommonName)
+	}
+	return ids
+}
+
 // GetClient retrieves the authenticated client from the request context.
 // Returns nil if no client is present or if the value is not a *ScannerClient.
 func GetClient(ctx context.Context) *db.ScannerClient {
 	client, _ := ctx.Value(ClientContextKey).(*db.ScannerClient) //nolint:errcheck // Type assertion returns (nil, false) on failure, which is the desired behavior
 	return client
 }
+
+// GetAdminKeyID retrieves the AdminKeyID of the admin key from the request
+// context. Returns "" outside AdminAuth.
+func GetAdminKeyID(ctx context.Context) string {
+	id, _ := ctx.Value(AdminKeyIDContextKey).(string) //nolint:errcheck // "" when absent
+	return id
+}
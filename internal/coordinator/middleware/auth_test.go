@@ -2,8 +2,12 @@ package middleware
 
 import (
 	"context"
+	"crypto/x509"
+	"crypto/x509/pkix"
 	"net/http"
 	"net/http/httptest"
+	"net/url"
+	"slices"
 	"strings"
 	"testing"
 	"time"
@@ -123,6 +127,31 @@ func TestAdminAuth_EmptyConfiguredKey(t *testing.T) {
 	}
 }
 
+func TestAdminAuth_KeyID(t *testing.T) {
+	const key = "test-admin-key-12345"
+	var got string
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = GetAdminKeyID(r.Context())
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/test", nil)
+	req.Header.Set("X-Admin-Key", key)
+	AdminAuth(key)(next).ServeHTTP(httptest.NewRecorder(), req)
+
+	if got != AdminKeyID(key) {
+		t.Errorf("GetAdminKeyID() = %q, want %q", got, AdminKeyID(key))
+	}
+	if len(got) != 16 || strings.Contains(got, key) {
+		t.Errorf("AdminKeyID() = %q, want 16 hex digits not revealing the key", got)
+	}
+	if AdminKeyID("other-key") == got {
+		t.Error("different keys should have different IDs")
+	}
+	if id := GetAdminKeyID(context.Background()); id != "" {
+		t.Errorf("GetAdminKeyID() without AdminAuth = %q, want empty", id)
+	}
+}
+
 func TestGetClient(t *testing.T) {
 	tests := []struct {
 		name       string
@@ -273,7 +302,7 @@ func TestScannerAuth_HeaderParsing(t *testing.T) {
 				t.Error("next handler should not be called")
 			})
 
-			middleware := ScannerAuth(nil) // nil DB is fine for early-exit tests
+			middleware := ScannerAuth(nil, ScannerAuthToken) // nil DB is fine for early-exit tests
 			handler := middleware(next)
 
 			req := httptest.NewRequest(http.MethodGet, "/test", nil)
@@ -298,3 +327,53 @@ func TestClientContextKey_Type(t *testing.T) {
 		t.Errorf("ClientContextKey = %v, want %v", ClientContextKey, contextKey("client"))
 	}
 }
+
+func TestScannerAuth_MTLSRejectsTokens(t *testing.T) {
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Error("next handler should not be called")
+	})
+	handler := ScannerAuth(nil, ScannerAuthMTLS)(next) // Rejected before any DB lookup
+
+	req := httptest.NewRequest(http.MethodPost, "/api/scanner/jobs", nil)
+	req.Header.Set("Authorization", "Bearer some-token")
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+
+	if rr.Code != http.StatusUnauthorized {
+		t.Errorf("status code = %d, want %d", rr.Code, http.StatusUnauthorized)
+	}
+}
+
+func TestParseScannerAuthMode(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    ScannerAuthMode
+		wantErr bool
+	}{
+		{in: "", want: ScannerAuthToken},
+		{in: "token", want: ScannerAuthToken},
+		{in: " MTLS ", want: ScannerAuthMTLS},
+		{in: "any", want: ScannerAuthAny},
+		{in: "basic", wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := ParseScannerAuthMode(tt.in)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("ParseScannerAuthMode(%q) = %q, %v; want %q, error %v", tt.in, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
+
+func TestCertIdentities(t *testing.T) {
+	spiffe, _ := url.Parse("spiffe://locplace/scanner/eu-1")
+	cert := &x509.Certificate{
+		Subject:        pkix.Name{CommonName: "scanner-eu-1"},
+		URIs:           []*url.URL{spiffe},
+		DNSNames:       []string{"eu-1.scanners.loc.place"},
+		EmailAddresses: []string{"ops@loc.place"},
+	}
+	want := []string{"spiffe://locplace/scanner/eu-1", "eu-1.scanners.loc.place", "ops@loc.place", "scanner-eu-1"}
+	if got := CertIdentities(cert); !slices.Equal(got, want) {
+		t.Errorf("CertIdentities() = %v, want %v", got, want)
+	}
+}
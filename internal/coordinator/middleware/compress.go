@@ -0,0 +1,16 @@
+package middleware
+
+import "net/http"
+
+// UncompressedRanges returns middleware that drops Accept-Encoding from
+// requests carrying a Range header. Byte ranges refer to the uncompressed
+// representation, so a compressor further down the chain must not encode a
+// partial response; this must run before the compression middleware.
+func UncompressedRanges(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Range") != "" {
+			r.Header.Del("Accept-Encoding")
+		}
+		next.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,49 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	chimw "github.com/go-chi/chi/v5/middleware"
+)
+
+func TestUncompressedRanges(t *testing.T) {
+	body := strings.Repeat(`{"type":"Feature"},`, 200)
+	h := UncompressedRanges(chimw.Compress(5, "application/json")(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		http.ServeContent(w, r, "", time.Time{}, strings.NewReader(body))
+	})))
+
+	tests := []struct {
+		name     string
+		rangeHdr string
+		wantEnc  string
+		wantCode int
+	}{
+		{name: "full request is compressed", wantEnc: "gzip", wantCode: http.StatusOK},
+		{name: "ranged request is not", rangeHdr: "bytes=10-", wantEnc: "", wantCode: http.StatusPartialContent},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/records.geojson", nil)
+			req.Header.Set("Accept-Encoding", "gzip")
+			if tt.rangeHdr != "" {
+				req.Header.Set("Range", tt.rangeHdr)
+			}
+			rec := httptest.NewRecorder()
+			h.ServeHTTP(rec, req)
+			if rec.Code != tt.wantCode {
+				t.Errorf("status = %d, want %d", rec.Code, tt.wantCode)
+			}
+			if got := rec.Header().Get("Content-Encoding"); got != tt.wantEnc {
+				t.Errorf("Content-Encoding = %q, want %q", got, tt.wantEnc)
+			}
+			if tt.rangeHdr != "" && rec.Body.String() != body[10:] {
+				t.Errorf("ranged body does not match the uncompressed bytes")
+			}
+		})
+	}
+}
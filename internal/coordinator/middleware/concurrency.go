@@ -0,0 +1,53 @@
+package middleware
+
+import "net/http"
+
+// Gauge tracks a value that goes up and down; prometheus.Gauge satisfies it.
+type Gauge interface {
+	Inc()
+	Dec()
+}
+
+// concurrencyRetryAfter is the Retry-After hint, in seconds, sent when the
+// limit is reached.
+const concurrencyRetryAfter = "10"
+
+// ConcurrencyLimiter caps how many requests run a handler at once. Exports
+// hold a database connection for their whole duration, so a few large
+// downloads could otherwise exhaust the pool.
+type ConcurrencyLimiter struct {
+	slots  chan struct{}
+	active Gauge
+}
+
+// NewConcurrencyLimiter allows max concurrent requests (0 = unlimited).
+// active, if non-nil, tracks the number of requests in flight.
+func NewConcurrencyLimiter(max int, active Gauge) *ConcurrencyLimiter {
+	l := &ConcurrencyLimiter{active: active}
+	if max > 0 {
+		l.slots = make(chan struct{}, max)
+	}
+	return l
+}
+
+// Handler rejects requests beyond the limit with 503 and Retry-After
+// instead of queueing them.
+func (l *ConcurrencyLimiter) Handler(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if l.slots != nil {
+			select {
+			case l.slots <- struct{}{}:
+				defer func() { <-l.slots }()
+			default:
+				w.Header().Set("Retry-After", concurrencyRetryAfter)
+				writeError(w, "too many concurrent exports, try again later", http.StatusServiceUnavailable)
+				return
+			}
+		}
+		if l.active != nil {
+			l.active.Inc()
+			defer l.active.Dec()
+		}
+		next.ServeHTTP(w, r)
+	})
+}
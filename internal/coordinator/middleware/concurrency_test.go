@@ -0,0 +1,86 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+)
+
+type countingGauge struct {
+	mu      sync.Mutex
+	v, peak int
+}
+
+func (g *countingGauge) Inc() {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.v++
+	g.peak = max(g.peak, g.v)
+}
+
+func (g *countingGauge) Dec() {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.v--
+}
+
+func TestConcurrencyLimiter(t *testing.T) {
+	gauge := &countingGauge{}
+	release := make(chan struct{})
+	started := make(chan struct{})
+	l := NewConcurrencyLimiter(1, gauge)
+	handler := l.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		started <- struct{}{}
+		<-release
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	first := httptest.NewRecorder()
+	done := make(chan struct{})
+	go func() {
+		handler.ServeHTTP(first, httptest.NewRequest(http.MethodGet, "/", nil))
+		close(done)
+	}()
+	<-started
+
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/", nil))
+	if rr.Code != http.StatusServiceUnavailable {
+		t.Errorf("second request status = %d, want 503", rr.Code)
+	}
+	if rr.Header().Get("Retry-After") == "" {
+		t.Error("503 should carry Retry-After")
+	}
+	if ct := rr.Header().Get("Content-Type"); ct != "application/json" {
+		t.Errorf("Content-Type = %q, want application/json", ct)
+	}
+
+	close(release)
+	<-done
+	if first.Code != http.StatusOK {
+		t.Errorf("first request status = %d, want 200", first.Code)
+	}
+	if gauge.v != 0 || gauge.peak != 1 {
+		t.Errorf("gauge = %d (peak %d), want 0 (peak 1)", gauge.v, gauge.peak)
+	}
+
+	// The slot is free again
+	go func() { <-started }()
+	rr = httptest.NewRecorder()
+	handler.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/", nil))
+	if rr.Code != http.StatusOK {
+		t.Errorf("request after release status = %d, want 200", rr.Code)
+	}
+}
+
+func TestConcurrencyLimiter_Unlimited(t *testing.T) {
+	handler := NewConcurrencyLimiter(0, nil).Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/", nil))
+	if rr.Code != http.StatusOK {
+		t.Errorf("status = %d, want 200", rr.Code)
+	}
+}
@@ -0,0 +1,16 @@
+package middleware
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/locplace/scanner/pkg/api"
+)
+
+// writeError writes a JSON error body, matching the handlers' error
+// responses. http.Error would send it as text/plain.
+func writeError(w http.ResponseWriter, message string, status int) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(api.ErrorResponse{Error: message}) // Error is client disconnect, can't recover
+}
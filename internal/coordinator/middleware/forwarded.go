@@ -0,0 +1,57 @@
+package middleware
+
+import (
+	"net/http"
+	"net/netip"
+	"strings"
+)
+
+// ForwardedHost returns middleware that applies the host and scheme a
+// trusted proxy received the request on, so absolute URLs point at the
+// public address. It sets r.Host and r.URL.Scheme from the Forwarded
+// header's host= and proto=, falling back to X-Forwarded-Host and
+// X-Forwarded-Proto. Like RealIP, the headers are only honored when the
+// connecting peer is a trusted proxy, so it must run before RealIP
+// replaces r.RemoteAddr.
+func ForwardedHost(trusted []netip.Prefix) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if peer, ok := parseHostAddr(r.RemoteAddr); ok && isTrusted(peer, trusted) {
+				host, proto := forwardedHostProto(r.Header)
+				if host != "" {
+					r.Host = host
+				}
+				if proto == "http" || proto == "https" {
+					r.URL.Scheme = proto
+				}
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// forwardedHostProto returns the host and lowercased scheme the client
+// used. Proxies append to the headers, so the first element is the one
+// facing the client.
+func forwardedHostProto(h http.Header) (host, proto string) {
+	if fwd := h.Get("Forwarded"); fwd != "" {
+		first, _, _ := strings.Cut(fwd, ",")
+		for _, pair := range strings.Split(first, ";") {
+			key, value, ok := strings.Cut(strings.TrimSpace(pair), "=")
+			if !ok {
+				continue
+			}
+			value = strings.Trim(value, `"`)
+			switch strings.ToLower(key) {
+			case "host":
+				host = value
+			case "proto":
+				proto = strings.ToLower(value)
+			}
+		}
+		return host, proto
+	}
+	host, _, _ = strings.Cut(h.Get("X-Forwarded-Host"), ",")
+	proto, _, _ = strings.Cut(h.Get("X-Forwarded-Proto"), ",")
+	return strings.TrimSpace(host), strings.ToLower(strings.TrimSpace(proto))
+}
@@ -0,0 +1,50 @@
+package middleware
+
+import (
+	"net/http"
+	"sync/atomic"
+)
+
+// Maintenance is a runtime-toggleable read-only mode. While enabled, routes
+// wrapped with ReadOnly reject writes with 503 so the database can be
+// maintained without taking public reads down.
+type Maintenance struct {
+	enabled atomic.Bool
+}
+
+// NewMaintenance returns a Maintenance in the given initial state.
+func NewMaintenance(enabled bool) *Maintenance {
+	m := &Maintenance{}
+	m.enabled.Store(enabled)
+	return m
+}
+
+// Enabled reports whether maintenance mode is on.
+func (m *Maintenance) Enabled() bool {
+	return m.enabled.Load()
+}
+
+// Set turns maintenance mode on or off.
+func (m *Maintenance) Set(enabled bool) {
+	m.enabled.Store(enabled)
+}
+
+// maintenanceRetryAfter is the Retry-After hint, in seconds, sent with 503s.
+const maintenanceRetryAfter = "60"
+
+// ReadOnly returns middleware that rejects requests other than GET, HEAD and
+// OPTIONS with 503 while maintenance mode is enabled.
+func (m *Maintenance) ReadOnly(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if m.Enabled() {
+			switch r.Method {
+			case http.MethodGet, http.MethodHead, http.MethodOptions:
+			default:
+				w.Header().Set("Retry-After", maintenanceRetryAfter)
+				writeError(w, "coordinator is in read-only maintenance mode", http.StatusServiceUnavailable)
+				return
+			}
+		}
+		next.ServeHTTP(w, r)
+	})
+}
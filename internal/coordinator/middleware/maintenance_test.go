@@ -0,0 +1,46 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestMaintenance_ReadOnly(t *testing.T) {
+	m := NewMaintenance(false)
+	handler := m.ReadOnly(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	tests := []struct {
+		name        string
+		maintenance bool
+		method      string
+		want        int
+	}{
+		{"write when off", false, http.MethodPost, http.StatusOK},
+		{"read when on", true, http.MethodGet, http.StatusOK},
+		{"head when on", true, http.MethodHead, http.StatusOK},
+		{"post when on", true, http.MethodPost, http.StatusServiceUnavailable},
+		{"delete when on", true, http.MethodDelete, http.StatusServiceUnavailable},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m.Set(tt.maintenance)
+			rr := httptest.NewRecorder()
+			handler.ServeHTTP(rr, httptest.NewRequest(tt.method, "/api/scanner/results", nil))
+			if rr.Code != tt.want {
+				t.Errorf("status = %d, want %d", rr.Code, tt.want)
+			}
+			if tt.want == http.StatusServiceUnavailable {
+				if rr.Header().Get("Retry-After") == "" {
+					t.Error("503 should carry Retry-After")
+				}
+				if ct := rr.Header().Get("Content-Type"); ct != "application/json" {
+					t.Errorf("Content-Type = %q, want application/json", ct)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,89 @@
+package middleware
+
+import (
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// RateLimiter limits requests per client IP using a fixed window.
+// Place it after chimw.RealIP so RemoteAddr holds the client address.
+type RateLimiter struct {
+	limit  int
+	window time.Duration
+	now    func() time.Time
+
+	mu      sync.Mutex
+	clients map[string]*rateWindow
+	pruned  time.Time
+}
+
+type rateWindow struct {
+	start time.Time
+	count int
+}
+
+// NewRateLimiter allows limit requests per window for each client IP. A
+// limit of 0 or less disables limiting.
+func NewRateLimiter(limit int, window time.Duration) *RateLimiter {
+	return &RateLimiter{
+		limit:   limit,
+		window:  window,
+		now:     time.Now,
+		clients: make(map[string]*rateWindow),
+	}
+}
+
+// Allow reports whether a request from key is within the limit, counting it if so.
+func (l *RateLimiter) Allow(key string) bool {
+	if l.limit <= 0 {
+		return true
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+	l.prune(now)
+
+	w := l.clients[key]
+	if w == nil || now.Sub(w.start) >= l.window {
+		l.clients[key] = &rateWindow{start: now, count: 1}
+		return true
+	}
+	if w.count >= l.limit {
+		return false
+	}
+	w.count++
+	return true
+}
+
+// prune drops expired windows, at most once per window. Caller holds mu.
+func (l *RateLimiter) prune(now time.Time) {
+	if now.Sub(l.pruned) < l.window {
+		return
+	}
+	for key, w := range l.clients {
+		if now.Sub(w.start) >= l.window {
+			delete(l.clients, key)
+		}
+	}
+	l.pruned = now
+}
+
+// Handler returns middleware that rejects requests over the limit with 429.
+func (l *RateLimiter) Handler(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		key, _, err := net.SplitHostPort(r.RemoteAddr)
+		if err != nil {
+			key = r.RemoteAddr
+		}
+		if !l.Allow(key) {
+			w.Header().Set("Retry-After", strconv.Itoa(int(l.window.Seconds())))
+			writeError(w, "rate limit exceeded", http.StatusTooManyRequests)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,55 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestRateLimiter_Allow(t *testing.T) {
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	l := NewRateLimiter(2, time.Minute)
+	l.now = func() time.Time { return now }
+
+	if !l.Allow("a") || !l.Allow("a") {
+		t.Fatal("first two requests should be allowed")
+	}
+	if l.Allow("a") {
+		t.Error("third request in the window should be rejected")
+	}
+	if !l.Allow("b") {
+		t.Error("other clients should have their own limit")
+	}
+
+	now = now.Add(time.Minute)
+	if !l.Allow("a") {
+		t.Error("request in a new window should be allowed")
+	}
+}
+
+func TestRateLimiter_ZeroDisables(t *testing.T) {
+	l := NewRateLimiter(0, time.Minute)
+	for i := range 100 {
+		if !l.Allow("a") {
+			t.Fatalf("request %d rejected with limiting disabled", i+1)
+		}
+	}
+}
+
+func TestRateLimiter_Handler(t *testing.T) {
+	l := NewRateLimiter(1, time.Minute)
+	handler := l.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	for i, want := range []int{http.StatusOK, http.StatusTooManyRequests} {
+		req := httptest.NewRequest(http.MethodPost, "/api/public/parse", nil)
+		req.RemoteAddr = "192.0.2.1:1234"
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+		if rr.Code != want {
+			t.Errorf("request %d: status = %d, want %d", i+1, rr.Code, want)
+		}
+	}
+}
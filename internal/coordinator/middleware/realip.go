@@ -0,0 +1,114 @@
+package middleware
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"strings"
+)
+
+// ParseTrustedProxies parses a comma-separated list of IPs and CIDR prefixes.
+func ParseTrustedProxies(s string) ([]netip.Prefix, error) {
+	var prefixes []netip.Prefix
+	for _, part := range strings.Split(s, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		if strings.Contains(part, "/") {
+			p, err := netip.ParsePrefix(part)
+			if err != nil {
+				return nil, fmt.Errorf("invalid trusted proxy %q: %w", part, err)
+			}
+			prefixes = append(prefixes, p.Masked())
+			continue
+		}
+		addr, err := netip.ParseAddr(part)
+		if err != nil {
+			return nil, fmt.Errorf("invalid trusted proxy %q: %w", part, err)
+		}
+		prefixes = append(prefixes, netip.PrefixFrom(addr, addr.BitLen()))
+	}
+	return prefixes, nil
+}
+
+// RealIP returns middleware that sets r.RemoteAddr to the real client IP.
+// X-Forwarded-For and Forwarded are only honored when the connecting peer is a
+// trusted proxy; the client is the right-most address in the chain that is not
+// itself trusted. With no trusted proxies the headers are ignored entirely.
+func RealIP(trusted []netip.Prefix) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if ip := clientIP(r, trusted); ip != "" {
+				r.RemoteAddr = ip
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// clientIP determines the client IP for r, or "" to leave RemoteAddr as is.
+func clientIP(r *http.Request, trusted []netip.Prefix) string {
+	if len(trusted) == 0 {
+		return ""
+	}
+	peer, ok := parseHostAddr(r.RemoteAddr)
+	if !ok || !isTrusted(peer, trusted) {
+		return ""
+	}
+
+	hops := forwardedFor(r.Header.Get("Forwarded"))
+	if len(hops) == 0 {
+		hops = strings.Split(r.Header.Get("X-Forwarded-For"), ",")
+	}
+
+	// Walk from the nearest hop outwards; each trusted proxy vouches for the next
+	for i := len(hops) - 1; i >= 0; i-- {
+		addr, ok := parseHostAddr(strings.TrimSpace(hops[i]))
+		if !ok {
+			// Unparseable hop: stop rather than trust anything further out
+			return ""
+		}
+		if !isTrusted(addr, trusted) {
+			return addr.String()
+		}
+	}
+	return ""
+}
+
+// forwardedFor extracts the for= values from an RFC 7239 Forwarded header.
+func forwardedFor(header string) []string {
+	var hops []string
+	for _, elem := range strings.Split(header, ",") {
+		for _, pair := range strings.Split(elem, ";") {
+			key, value, ok := strings.Cut(strings.TrimSpace(pair), "=")
+			if ok && strings.EqualFold(key, "for") {
+				hops = append(hops, strings.Trim(value, `"`))
+			}
+		}
+	}
+	return hops
+}
+
+// parseHostAddr parses an IP with an optional port ("1.2.3.4", "1.2.3.4:80",
+// "[2001:db8::1]:80", "[2001:db8::1]").
+func parseHostAddr(s string) (netip.Addr, bool) {
+	if host, _, err := net.SplitHostPort(s); err == nil {
+		s = host
+	}
+	addr, err := netip.ParseAddr(strings.Trim(s, "[]"))
+	if err != nil {
+		return netip.Addr{}, false
+	}
+	return addr.Unmap(), true
+}
+
+func isTrusted(addr netip.Addr, trusted []netip.Prefix) bool {
+	for _, p := range trusted {
+		if p.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,175 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestRealIP(t *testing.T) {
+	trusted, err := ParseTrustedProxies("10.0.0.0/8, 192.0.2.1")
+	if err != nil {
+		t.Fatalf("ParseTrustedProxies() error: %v", err)
+	}
+
+	tests := []struct {
+		name       string
+		trusted    bool
+		remoteAddr string
+		xff        string
+		forwarded  string
+		want       string
+	}{
+		{
+			name:       "no trusted proxies ignores headers",
+			remoteAddr: "10.1.1.1:1234",
+			xff:        "203.0.113.7",
+			want:       "10.1.1.1:1234",
+		},
+		{
+			name:       "untrusted peer ignores headers",
+			trusted:    true,
+			remoteAddr: "198.51.100.9:1234",
+			xff:        "203.0.113.7",
+			want:       "198.51.100.9:1234",
+		},
+		{
+			name:       "trusted peer uses x-forwarded-for",
+			trusted:    true,
+			remoteAddr: "10.1.1.1:1234",
+			xff:        "203.0.113.7",
+			want:       "203.0.113.7",
+		},
+		{
+			name:       "spoofed left-most entry is skipped",
+			trusted:    true,
+			remoteAddr: "10.1.1.1:1234",
+			xff:        "1.1.1.1, 203.0.113.7, 10.2.2.2",
+			want:       "203.0.113.7",
+		},
+		{
+			name:       "forwarded header with ipv6",
+			trusted:    true,
+			remoteAddr: "192.0.2.1:443",
+			forwarded:  `for="[2001:db8:cafe::17]:4711";proto=https`,
+			want:       "2001:db8:cafe::17",
+		},
+		{
+			name:       "all hops trusted keeps peer",
+			trusted:    true,
+			remoteAddr: "10.1.1.1:1234",
+			xff:        "10.3.3.3",
+			want:       "10.1.1.1:1234",
+		},
+		{
+			name:       "garbage hop keeps peer",
+			trusted:    true,
+			remoteAddr: "10.1.1.1:1234",
+			xff:        "203.0.113.7, not-an-ip",
+			want:       "10.1.1.1:1234",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := trusted
+			if !tt.trusted {
+				cfg = nil
+			}
+
+			var got string
+			handler := RealIP(cfg)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				got = r.RemoteAddr
+			}))
+
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			req.RemoteAddr = tt.remoteAddr
+			if tt.xff != "" {
+				req.Header.Set("X-Forwarded-For", tt.xff)
+			}
+			if tt.forwarded != "" {
+				req.Header.Set("Forwarded", tt.forwarded)
+			}
+			handler.ServeHTTP(httptest.NewRecorder(), req)
+
+			if got != tt.want {
+				t.Errorf("RemoteAddr = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseTrustedProxies_Invalid(t *testing.T) {
+	if _, err := ParseTrustedProxies("10.0.0.0/33"); err == nil {
+		t.Error("expected error for invalid prefix")
+	}
+	if _, err := ParseTrustedProxies("proxy.internal"); err == nil {
+		t.Error("expected error for hostname")
+	}
+}
+
+func TestForwardedHost(t *testing.T) {
+	trusted, err := ParseTrustedProxies("10.0.0.0/8")
+	if err != nil {
+		t.Fatalf("ParseTrustedProxies() error: %v", err)
+	}
+
+	tests := []struct {
+		name       string
+		remoteAddr string
+		headers    map[string]string
+		wantHost   string
+		wantScheme string
+	}{
+		{
+			name:       "untrusted peer ignores headers",
+			remoteAddr: "198.51.100.9:1234",
+			headers:    map[string]string{"X-Forwarded-Host": "evil.example", "X-Forwarded-Proto": "https"},
+			wantHost:   "internal:8080",
+		},
+		{
+			name:       "trusted peer uses x-forwarded headers",
+			remoteAddr: "10.1.1.1:1234",
+			headers:    map[string]string{"X-Forwarded-Host": "loc.place, proxy2.internal", "X-Forwarded-Proto": "HTTPS"},
+			wantHost:   "loc.place",
+			wantScheme: "https",
+		},
+		{
+			name:       "forwarded header wins",
+			remoteAddr: "10.1.1.1:1234",
+			headers: map[string]string{
+				"Forwarded":        `for=203.0.113.7;host="loc.place";proto=https, for=10.2.2.2;host=internal`,
+				"X-Forwarded-Host": "other.example",
+			},
+			wantHost:   "loc.place",
+			wantScheme: "https",
+		},
+		{
+			name:       "unknown scheme ignored",
+			remoteAddr: "10.1.1.1:1234",
+			headers:    map[string]string{"X-Forwarded-Proto": "gopher"},
+			wantHost:   "internal:8080",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var gotHost, gotScheme string
+			handler := ForwardedHost(trusted)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				gotHost, gotScheme = r.Host, r.URL.Scheme
+			}))
+
+			req := httptest.NewRequest(http.MethodGet, "/sitemap.xml", nil)
+			req.Host = "internal:8080"
+			req.RemoteAddr = tt.remoteAddr
+			for k, v := range tt.headers {
+				req.Header.Set(k, v)
+			}
+			handler.ServeHTTP(httptest.NewRecorder(), req)
+
+			if gotHost != tt.wantHost || gotScheme != tt.wantScheme {
+				t.Errorf("host/scheme = %q/%q, want %q/%q", gotHost, gotScheme, tt.wantHost, tt.wantScheme)
+			}
+		})
+	}
+}
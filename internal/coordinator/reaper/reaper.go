@@ -16,6 +16,11 @@ type Reaper struct {
 	Interval         time.Duration
 	BatchTimeout     time.Duration
 	HeartbeatTimeout time.Duration
+	ChangesRetention time.Duration // Removals older than this are pruned (0 = kept forever)
+
+	// Paused, if set, skips runs while it returns true, so batches aren't
+	// reaped while scanners can't submit during read-only maintenance.
+	Paused func() bool
 }
 
 // Run starts the reaper loop. It blocks until the context is canceled.
@@ -40,6 +45,9 @@ func (r *Reaper) Run(ctx context.Context) {
 }
 
 func (r *Reaper) runOnce(ctx context.Context) {
+	if r.Paused != nil && r.Paused() {
+		return
+	}
 	metrics.ReaperRunsTotal.Inc()
 
 	// Reset batches from dead sessions (sessions that haven't heartbeated)
@@ -60,4 +68,13 @@ func (r *Reaper) runOnce(ctx context.Context) {
 		metrics.ReaperBatchesReleasedTotal.Add(float64(released))
 		log.Printf("Reaper reset %d stale batches (no session)", released)
 	}
+
+	if r.ChangesRetention > 0 {
+		pruned, err := r.DB.PruneLOCRecordDeletions(ctx, r.ChangesRetention)
+		if err != nil {
+			log.Printf("Reaper error pruning removed records: %v", err)
+		} else if pruned > 0 {
+			log.Printf("Reaper pruned %d removed records older than %s", pruned, r.ChangesRetention)
+		}
+	}
 }
@@ -3,6 +3,7 @@ package coordinator
 
 import (
 	"net/http"
+	"net/netip"
 	"time"
 
 	"github.com/go-chi/chi/v5"
@@ -11,13 +12,52 @@ import (
 	"github.com/locplace/scanner/frontend"
 	"github.com/locplace/scanner/internal/coordinator/db"
 	"github.com/locplace/scanner/internal/coordinator/handlers"
+	"github.com/locplace/scanner/internal/coordinator/metrics"
 	"github.com/locplace/scanner/internal/coordinator/middleware"
+	"github.com/locplace/scanner/internal/coordinator/tldfilter"
 )
 
 // Config holds server configuration.
 type Config struct {
 	AdminAPIKey      string
 	HeartbeatTimeout time.Duration
+	FeedSize         int            // Entries in the Atom feed of new records
+	ParseRateLimit   int            // Requests per minute per IP for POST /api/public/parse (0 = unlimited)
+	IdempotencyTTL   time.Duration  // How long result submission Idempotency-Keys are remembered
+	TrustedProxies   []netip.Prefix // Proxies whose X-Forwarded-*/Forwarded headers are honored
+	ScannerAuth      middleware.ScannerAuthMode
+	CacheTTLs        handlers.CacheTTLs
+	ExportDecimals   handlers.ExportDecimals // Default coordinate rounding per export format
+	StatsCacheTTL    time.Duration           // How long GET /api/public/stats responses are reused (0 = no caching)
+	// MaxConcurrentExports caps exports served at once across all export
+	// endpoints (0 = unlimited).
+	MaxConcurrentExports int
+	// Maintenance is the read-only maintenance switch (toggled via
+	// /api/admin/maintenance). The caller shares it with the reaper and
+	// feeder so they pause too; nil starts with maintenance off.
+	Maintenance *middleware.Maintenance
+	// RobotsTxt replaces the generated /robots.txt when set.
+	RobotsTxt string
+	// PublicBaseURL is used for absolute links in the feed, sitemap,
+	// robots.txt and pagination Link headers instead of the request's host
+	// (see handlers.ParseBaseURL).
+	PublicBaseURL string
+	// StaleAfter is how long after they were last seen records are marked
+	// is_stale in responses (0 = never).
+	StaleAfter time.Duration
+	// ChangesRetention is how long the changes feed keeps removals
+	// (0 = forever); the reaper prunes older ones.
+	ChangesRetention time.Duration
+	// KeepUnparsedRecords stores LOC answers that failed to parse for review.
+	KeepUnparsedRecords bool
+	// TLDFilter limits stored results to the configured TLDs (nil = all).
+	TLDFilter *tldfilter.Filter
+	// MaxHorizPrecM rejects submitted records with a coarser
+	// horizontal precision (0 = accept all).
+	MaxHorizPrecM float64
+	// MinScannerAPIVersion rejects result submissions older than this
+	// api.ScannerAPIVersion (0 = accept all supported versions).
+	MinScannerAPIVersion int
 }
 
 // NewServer creates a new HTTP server with all routes configured.
@@ -27,46 +67,118 @@ func NewServer(database *db.DB, cfg Config) http.Handler {
 	// Global middleware
 	r.Use(chimw.Logger)
 	r.Use(chimw.Recoverer)
-	r.Use(chimw.RealIP)
-	r.Use(chimw.Compress(5, "application/json", "application/geo+json", "text/html", "text/plain"))
+	r.Use(middleware.ForwardedHost(cfg.TrustedProxies)) // Checks the peer, so before RealIP
+	r.Use(middleware.RealIP(cfg.TrustedProxies))
+	r.Use(middleware.UncompressedRanges) // Ranges index the uncompressed body
+	r.Use(chimw.Compress(5, "application/json", "application/geo+json", "application/x-ndjson", "application/atom+xml", "text/html", "text/plain"))
+
+	maintenance := cfg.Maintenance
+	if maintenance == nil {
+		maintenance = middleware.NewMaintenance(false)
+	}
+	handlers.SetMaintenanceMetric(maintenance.Enabled())
+
+	// Shared by every export endpoint, since they all hold a DB connection
+	exports := middleware.NewConcurrencyLimiter(cfg.MaxConcurrentExports, metrics.ExportsActive)
+
+	var statsCache *handlers.StatsCache
+	if cfg.StatsCacheTTL > 0 {
+		statsCache = handlers.NewStatsCache(cfg.StatsCacheTTL)
+	}
 
 	// Initialize handlers
 	adminHandlers := &handlers.AdminHandlers{
 		DB:               database,
 		HeartbeatTimeout: cfg.HeartbeatTimeout,
+		Maintenance:      maintenance,
+		StatsCache:       statsCache,
+		StaleAfter:       cfg.StaleAfter,
+		TLDFilter:        cfg.TLDFilter,
+		MaxHorizPrecM:    cfg.MaxHorizPrecM,
+		BaseURL:          cfg.PublicBaseURL,
 	}
 	scannerHandlers := &handlers.ScannerHandlers{
-		DB: database,
+		DB:            database,
+		Idempotency:   handlers.NewIdempotencyCache(cfg.IdempotencyTTL),
+		MinAPIVersion: cfg.MinScannerAPIVersion,
+		KeepUnparsed:  cfg.KeepUnparsedRecords,
+		TLDFilter:     cfg.TLDFilter,
+		MaxHorizPrecM: cfg.MaxHorizPrecM,
 	}
 	publicHandlers := &handlers.PublicHandlers{
 		DB:               database,
 		HeartbeatTimeout: cfg.HeartbeatTimeout,
+		FeedSize:         cfg.FeedSize,
+		CacheTTLs:        cfg.CacheTTLs,
+		Decimals:         cfg.ExportDecimals,
+		RobotsTxt:        cfg.RobotsTxt,
+		BaseURL:          cfg.PublicBaseURL,
+		StatsCache:       statsCache,
+		StaleAfter:       cfg.StaleAfter,
+		ChangesRetention: cfg.ChangesRetention,
 	}
 
 	// Admin routes (authenticated with API key)
 	r.Route("/api/admin", func(r chi.Router) {
 		r.Use(middleware.AdminAuth(cfg.AdminAPIKey))
-		r.Post("/clients", adminHandlers.RegisterClient)
-		r.Get("/clients", adminHandlers.ListClients)
-		r.Delete("/clients/{id}", adminHandlers.DeleteClient)
-		r.Post("/discover-files", adminHandlers.DiscoverFiles)
-		r.Post("/reset-scan", adminHandlers.ResetScan)
-		r.Post("/manual-scan", adminHandlers.ManualScan)
+		r.Get("/maintenance", adminHandlers.GetMaintenance)
+		r.Put("/maintenance", adminHandlers.SetMaintenance)
+		r.Get("/schema", adminHandlers.GetSchemaVersion)
+		r.Get("/audit", adminHandlers.ListAudit)
+		r.With(exports.Handler).Post("/export/sqlite", adminHandlers.ExportSQLite) // Read-only despite POST
+		// Only drops cached data, so allowed in maintenance mode
+		r.Post("/cache/purge", adminHandlers.PurgeCache)
+
+		r.Group(func(r chi.Router) {
+			r.Use(maintenance.ReadOnly)
+			r.Post("/clients", adminHandlers.RegisterClient)
+			r.Get("/clients", adminHandlers.ListClients)
+			r.Delete("/clients/{id}", adminHandlers.DeleteClient)
+			r.Post("/clients/prune", adminHandlers.PruneClients)
+			r.Post("/discover-files", adminHandlers.DiscoverFiles)
+			r.Post("/reset-scan", adminHandlers.ResetScan)
+			r.Post("/files/{id}/rescan", adminHandlers.RescanFile)
+			r.Post("/manual-scan", adminHandlers.ManualScan)
+			r.Get("/scan-errors", adminHandlers.ListScanErrors)
+			r.Get("/unparsed-records", adminHandlers.ListUnparsedRecords)
+			r.Post("/reparse", adminHandlers.Reparse)
+			r.Patch("/records/{fqdn}", adminHandlers.UpdateRecord)
+		})
 	})
 
-	// Scanner routes (authenticated with bearer token)
+	// Scanner routes (authenticated with bearer token, all writes)
 	r.Route("/api/scanner", func(r chi.Router) {
-		r.Use(middleware.ScannerAuth(database))
-		r.Post("/jobs", scannerHandlers.GetJobs)
+		r.Use(middleware.ScannerAuth(database, cfg.ScannerAuth))
+		// Heartbeats keep leases alive, so scanners can submit once
+		// maintenance ends
 		r.Post("/heartbeat", scannerHandlers.Heartbeat)
-		r.Post("/results", scannerHandlers.SubmitResults)
+		r.Group(func(r chi.Router) {
+			r.Use(maintenance.ReadOnly)
+			r.Post("/jobs", scannerHandlers.GetJobs)
+			r.Post("/results", scannerHandlers.SubmitResults)
+		})
 	})
 
 	// Public routes (no authentication)
 	r.Route("/api/public", func(r chi.Router) {
 		r.Get("/records", publicHandlers.ListRecords)
-		r.Get("/records.geojson", publicHandlers.GetRecordsGeoJSON)
+		r.With(exports.Handler).Get("/records.geojson", publicHandlers.GetRecordsGeoJSON)
+		r.With(exports.Handler).Get("/records.jsonl", publicHandlers.ListRecordsJSONL)
+		r.Get("/records/feed.atom", publicHandlers.GetRecordsFeed)
+		r.With(exports.Handler).Get("/changes", publicHandlers.GetChanges)
+		r.Get("/records/recent", publicHandlers.GetRecentRecords)
+		r.Get("/records/{fqdn}", publicHandlers.GetRecord)
+		r.Get("/records/{fqdn}/verify", publicHandlers.VerifyRecordLocation)
+		r.Get("/records/{fqdn}/similar", publicHandlers.GetSimilarRecords)
+		r.Get("/root-domains", publicHandlers.ListRootDomains)
+		r.Get("/domains/{domain}/status", publicHandlers.GetDomainStatus)
+		r.Get("/bounds", publicHandlers.GetBounds)
 		r.Get("/stats", publicHandlers.GetStats)
+		r.Get("/stats/summary", publicHandlers.GetStatsSummary)
+		r.Get("/stats/records-per-domain", publicHandlers.GetRecordsPerDomain)
+		r.Get("/stats/density", publicHandlers.GetDensity)
+		r.With(middleware.NewRateLimiter(cfg.ParseRateLimit, time.Minute).Handler).
+			Post("/parse", publicHandlers.ParseRecord)
 	})
 
 	// Health check
@@ -75,6 +187,18 @@ func NewServer(database *db.DB, cfg Config) http.Handler {
 		_, _ = w.Write([]byte("ok")) // Error is client disconnect, can't recover
 	})
 
+	// Crawler support; the sitemap lists a frontend page per root domain
+	r.Get("/robots.txt", publicHandlers.GetRobotsTxt)
+	r.Get("/sitemap.xml", publicHandlers.GetSitemap)
+	r.Get("/sitemap-{page}.xml", publicHandlers.GetSitemapPage)
+
+	// Unknown API paths get a JSON 404 instead of the frontend's index.html.
+	// NotFound and MethodNotAllowed propagate to the /api/* subrouters.
+	r.NotFound(handlers.NotFound)
+	r.MethodNotAllowed(handlers.MethodNotAllowed)
+	r.Handle("/api", http.HandlerFunc(handlers.NotFound))
+	r.Handle("/api/*", http.HandlerFunc(handlers.NotFound))
+
 	// Serve frontend (must be last to not override API routes)
 	r.Handle("/*", frontend.Handler())
 
@@ -0,0 +1,41 @@
+package coordinator
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestNewServer_APINotFound(t *testing.T) {
+	// Unknown routes never reach the database
+	handler := NewServer(nil, Config{AdminAPIKey: "test"})
+
+	tests := []struct {
+		name     string
+		method   string
+		path     string
+		wantCode int
+		wantJSON bool
+	}{
+		{"typo in API prefix", http.MethodGet, "/api/pubic/records", http.StatusNotFound, true},
+		{"unknown public endpoint", http.MethodGet, "/api/public/nope", http.StatusNotFound, true},
+		{"bare api", http.MethodGet, "/api", http.StatusNotFound, true},
+		{"wrong method", http.MethodDelete, "/api/public/stats", http.StatusMethodNotAllowed, true},
+		{"frontend route", http.MethodGet, "/map", http.StatusOK, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rr := httptest.NewRecorder()
+			handler.ServeHTTP(rr, httptest.NewRequest(tt.method, tt.path, nil))
+			if rr.Code != tt.wantCode {
+				t.Errorf("status = %d, want %d", rr.Code, tt.wantCode)
+			}
+			isJSON := strings.HasPrefix(rr.Header().Get("Content-Type"), "application/json")
+			if isJSON != tt.wantJSON {
+				t.Errorf("Content-Type = %q, want JSON: %v", rr.Header().Get("Content-Type"), tt.wantJSON)
+			}
+		})
+	}
+}
@@ -0,0 +1,82 @@
+// Package tldfilter restricts the top-level domains the coordinator queues
+// and stores, for deployments that only care about some of them.
+package tldfilter
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Filter decides whether a name is in scope by its TLD. Entries may also be
+// multi-label suffixes such as "co.uk". A nil *Filter allows every name.
+type Filter struct {
+	allow map[string]bool // Empty allows every suffix not denied
+	deny  map[string]bool
+}
+
+// Parse builds a Filter from comma-separated allow and deny lists (e.g.
+// "com, .org"). It returns nil if both are empty.
+func Parse(allow, deny string) (*Filter, error) {
+	a, err := parseList(allow)
+	if err != nil {
+		return nil, err
+	}
+	d, err := parseList(deny)
+	if err != nil {
+		return nil, err
+	}
+	if len(a) == 0 && len(d) == 0 {
+		return nil, nil
+	}
+	return &Filter{allow: a, deny: d}, nil
+}
+
+func parseList(s string) (map[string]bool, error) {
+	set := make(map[string]bool)
+	for _, part := range strings.Split(s, ",") {
+		tld := strings.ToLower(strings.Trim(strings.TrimSpace(part), "."))
+		if tld == "" {
+			continue
+		}
+		if strings.ContainsAny(tld, " /*") || strings.Contains(tld, "..") {
+			return nil, fmt.Errorf("invalid TLD %q", part)
+		}
+		set[tld] = true
+	}
+	return set, nil
+}
+
+// Check returns nil if name is in scope, or an error giving the reason.
+func (f *Filter) Check(name string) error {
+	if f == nil {
+		return nil
+	}
+	name = strings.ToLower(strings.TrimSuffix(name, "."))
+	if s, ok := matchSuffix(name, f.deny); ok {
+		return fmt.Errorf("TLD .%s is excluded", s)
+	}
+	if len(f.allow) > 0 {
+		if _, ok := matchSuffix(name, f.allow); !ok {
+			return fmt.Errorf("TLD .%s is not in the allowlist", name[strings.LastIndexByte(name, '.')+1:])
+		}
+	}
+	return nil
+}
+
+// Allows reports whether name is in scope.
+func (f *Filter) Allows(name string) bool {
+	return f.Check(name) == nil
+}
+
+// matchSuffix returns the shortest suffix of name (by labels) that is in set.
+func matchSuffix(name string, set map[string]bool) (string, bool) {
+	for i := len(name) - 1; i >= 0; i-- {
+		if name[i] == '.' && set[name[i+1:]] {
+			return name[i+1:], true
+		}
+	}
+	if set[name] {
+		return name, true
+	}
+	return "", false
+}
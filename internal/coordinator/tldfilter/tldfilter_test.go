@@ -0,0 +1,62 @@
+package tldfilter
+
+import "testing"
+
+func TestParse_Empty(t *testing.T) {
+	f, err := Parse("", " , ")
+	if err != nil || f != nil {
+		t.Fatalf("Parse(empty) = %v, %v, want nil, nil", f, err)
+	}
+	if !f.Allows("anything.example") {
+		t.Error("nil filter should allow everything")
+	}
+}
+
+func TestParse_Invalid(t *testing.T) {
+	for _, s := range []string{"co..uk", "c om", "*.com"} {
+		if _, err := Parse(s, ""); err == nil {
+			t.Errorf("Parse(%q) should fail", s)
+		}
+	}
+}
+
+func TestCheck(t *testing.T) {
+	f, err := Parse("com, .NL, co.uk", "example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name string
+		want bool
+	}{
+		{"www.locplace.com", true},
+		{"WWW.Example.NL.", true},
+		{"nl", true},
+		{"shop.co.uk", true},
+		{"example.org.uk", false}, // Only co.uk is allowed
+		{"example.org", false},
+		{"host.example.com", false}, // Denied despite com being allowed
+		{"example.com", false},
+		{"notexample.com", true},
+	}
+	for _, tt := range tests {
+		if got := f.Allows(tt.name); got != tt.want {
+			t.Errorf("Allows(%q) = %v, want %v (%v)", tt.name, got, tt.want, f.Check(tt.name))
+		}
+	}
+
+	if err := f.Check("example.org"); err == nil || err.Error() != "TLD .org is not in the allowlist" {
+		t.Errorf("Check(example.org) = %v", err)
+	}
+}
+
+func TestCheck_DenyOnly(t *testing.T) {
+	f, err := Parse("", "xyz")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if f.Allows("spam.xyz") || !f.Allows("example.org") {
+		t.Error("deny-only filter should exclude .xyz and allow the rest")
+	}
+}
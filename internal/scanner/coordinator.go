@@ -3,10 +3,15 @@ package scanner
 import (
 	"bytes"
 	"context"
+	"crypto/tls"
+	"crypto/x509"
 	"encoding/json"
 	"fmt"
 	"io"
 	"net/http"
+	"os"
+	"strconv"
+	"sync"
 	"time"
 
 	"github.com/google/uuid"
@@ -20,6 +25,19 @@ type CoordinatorClient struct {
 	Token      string
 	SessionID  string // Unique ID for this scanner session (generated on startup)
 	HTTPClient *http.Client
+
+	KeepBestPrecision bool // Sent with result submissions
+	ReplaceIfNewer    bool // Sent with result submissions
+
+	// Version and Capabilities are reported with every heartbeat.
+	Version      string
+	Capabilities []string
+
+	// resumeSessionID is the session ID from before a restart. It is sent with
+	// job requests until the coordinator has no more of its batches to hand
+	// back.
+	resumeMu        sync.Mutex
+	resumeSessionID string
 }
 
 // NewCoordinatorClient creates a new coordinator API client.
@@ -35,6 +53,104 @@ func NewCoordinatorClient(baseURL, token string) *CoordinatorClient {
 	}
 }
 
+// setAuth adds the bearer token. Without one the scanner authenticates with
+// its TLS client certificate (see ClientTLSConfig).
+func (c *CoordinatorClient) setAuth(req *http.Request) {
+	if c.Token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.Token)
+	}
+}
+
+// ClientTLSConfig returns a TLS config presenting the certificate in
+// certFile/keyFile to the coordinator, for SCANNER_AUTH=mtls. A non-empty
+// caFile replaces the system roots for verifying the coordinator.
+func ClientTLSConfig(certFile, keyFile, caFile string) (*tls.Config, error) {
+	cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+	if err != nil {
+		return nil, fmt.Errorf("load client certificate: %w", err)
+	}
+	cfg := &tls.Config{
+		MinVersion:   tls.VersionTLS12,
+		Certificates: []tls.Certificate{cert},
+	}
+	if caFile != "" {
+		pem, err := os.ReadFile(caFile)
+		if err != nil {
+			return nil, fmt.Errorf("read CA file: %w", err)
+		}
+		cfg.RootCAs = x509.NewCertPool()
+		if !cfg.RootCAs.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("CA file %s contains no PEM certificates", caFile)
+		}
+	}
+	return cfg, nil
+}
+
+// SetResumeSessionID sets the previous session ID whose leased batches should be
+// handed back to this session by the next job requests.
+func (c *CoordinatorClient) SetResumeSessionID(sessionID string) {
+	c.resumeMu.Lock()
+	defer c.resumeMu.Unlock()
+	c.resumeSessionID = sessionID
+}
+
+func (c *CoordinatorClient) takeResumeSessionID() string {
+	c.resumeMu.Lock()
+	defer c.resumeMu.Unlock()
+	return c.resumeSessionID
+}
+
+func (c *CoordinatorClient) clearResumeSessionID(sessionID string) {
+	c.resumeMu.Lock()
+	defer c.resumeMu.Unlock()
+	if c.resumeSessionID == sessionID {
+		c.resumeSessionID = ""
+	}
+}
+
+// UnavailableError is returned when the coordinator answers 503, e.g. while
+// it is in read-only maintenance mode. The request can be retried later.
+type UnavailableError struct {
+	Op         string
+	RetryAfter time.Duration // From the Retry-After header; 0 if absent
+	Body       string
+}
+
+func (e *UnavailableError) Error() string {
+	return fmt.Sprintf("%s failed: %d %s", e.Op, http.StatusServiceUnavailable, e.Body)
+}
+
+// responseError describes a non-200 response to op.
+func responseError(op string, resp *http.Response) error {
+	bodyBytes, _ := io.ReadAll(resp.Body) //nolint:errcheck // Best effort to get error details
+	if resp.StatusCode == http.StatusServiceUnavailable {
+		return &UnavailableError{
+			Op:         op,
+			RetryAfter: parseRetryAfter(resp.Header.Get("Retry-After")),
+			Body:       string(bodyBytes),
+		}
+	}
+	return fmt.Errorf("%s failed: %d %s", op, resp.StatusCode, string(bodyBytes))
+}
+
+// parseRetryAfter parses a Retry-After header given in seconds or as an
+// HTTP date, returning 0 if it is missing or malformed.
+func parseRetryAfter(s string) time.Duration {
+	if s == "" {
+		return 0
+	}
+	if secs, err := strconv.Atoi(s); err == nil {
+		if secs < 0 {
+			return 0
+		}
+		return time.Duration(secs) * time.Second
+	}
+	if t, err := http.ParseTime(s); err == nil {
+		return max(time.Until(t), 0)
+	}
+	return 0
+}
+
 // Batch represents a batch of FQDNs to scan.
 type Batch struct {
 	ID      int64
@@ -43,7 +159,10 @@ type Batch struct {
 
 // GetBatch requests a batch of FQDNs to scan from the coordinator.
 func (c *CoordinatorClient) GetBatch(ctx context.Context) (*Batch, error) {
-	req := api.GetBatchRequest{SessionID: c.SessionID}
+	req := api.GetBatchRequest{
+		SessionID:       c.SessionID,
+		ResumeSessionID: c.takeResumeSessionID(),
+	}
 	body, err := json.Marshal(req)
 	if err != nil {
 		return nil, err
@@ -54,7 +173,7 @@ func (c *CoordinatorClient) GetBatch(ctx context.Context) (*Batch, error) {
 		return nil, err
 	}
 	httpReq.Header.Set("Content-Type", "application/json")
-	httpReq.Header.Set("Authorization", "Bearer "+c.Token)
+	c.setAuth(httpReq)
 
 	resp, err := c.HTTPClient.Do(httpReq)
 	if err != nil {
@@ -63,8 +182,7 @@ func (c *CoordinatorClient) GetBatch(ctx context.Context) (*Batch, error) {
 	defer resp.Body.Close() //nolint:errcheck // Close error not actionable
 
 	if resp.StatusCode != http.StatusOK {
-		bodyBytes, _ := io.ReadAll(resp.Body) //nolint:errcheck // Best effort to get error details
-		return nil, fmt.Errorf("get batch failed: %d %s", resp.StatusCode, string(bodyBytes))
+		return nil, responseError("get batch", resp)
 	}
 
 	var result api.GetBatchResponse
@@ -72,6 +190,11 @@ func (c *CoordinatorClient) GetBatch(ctx context.Context) (*Batch, error) {
 		return nil, err
 	}
 
+	// Keep asking for the previous session's leases until none are left
+	if req.ResumeSessionID != "" && !result.Resumed {
+		c.clearResumeSessionID(req.ResumeSessionID)
+	}
+
 	// Empty response means no batches available
 	if result.BatchID == 0 && len(result.Domains) == 0 {
 		return nil, nil
@@ -85,7 +208,11 @@ func (c *CoordinatorClient) GetBatch(ctx context.Context) (*Batch, error) {
 
 // Heartbeat sends a keepalive signal to the coordinator.
 func (c *CoordinatorClient) Heartbeat(ctx context.Context) error {
-	req := api.HeartbeatRequest{SessionID: c.SessionID}
+	req := api.HeartbeatRequest{
+		SessionID:    c.SessionID,
+		Version:      c.Version,
+		Capabilities: c.Capabilities,
+	}
 	body, err := json.Marshal(req)
 	if err != nil {
 		return err
@@ -96,7 +223,7 @@ func (c *CoordinatorClient) Heartbeat(ctx context.Context) error {
 		return err
 	}
 	httpReq.Header.Set("Content-Type", "application/json")
-	httpReq.Header.Set("Authorization", "Bearer "+c.Token)
+	c.setAuth(httpReq)
 
 	resp, err := c.HTTPClient.Do(httpReq)
 	if err != nil {
@@ -105,8 +232,7 @@ func (c *CoordinatorClient) Heartbeat(ctx context.Context) error {
 	defer resp.Body.Close() //nolint:errcheck // Close error not actionable
 
 	if resp.StatusCode != http.StatusOK {
-		bodyBytes, _ := io.ReadAll(resp.Body) //nolint:errcheck // Best effort to get error details
-		return fmt.Errorf("heartbeat failed: %d %s", resp.StatusCode, string(bodyBytes))
+		return responseError("heartbeat", resp)
 	}
 
 	return nil
@@ -114,11 +240,16 @@ func (c *CoordinatorClient) Heartbeat(ctx context.Context) error {
 
 // SubmitBatch sends scan results for a batch to the coordinator.
 // Uses a longer timeout than other requests since large result sets may take time to process.
-func (c *CoordinatorClient) SubmitBatch(ctx context.Context, batchID int64, domainsChecked int, locRecords []api.LOCRecord) error {
+func (c *CoordinatorClient) SubmitBatch(ctx context.Context, batchID int64, domainsChecked int, locRecords []api.LOCRecord, scanErrors []api.ScanError, parseErrors []api.ParseError, facts []api.DomainFacts) error {
 	req := api.SubmitBatchRequest{
-		BatchID:        batchID,
-		DomainsChecked: domainsChecked,
-		LOCRecords:     locRecords,
+		BatchID:           batchID,
+		DomainsChecked:    domainsChecked,
+		LOCRecords:        locRecords,
+		ScanErrors:        scanErrors,
+		ParseErrors:       parseErrors,
+		KeepBestPrecision: c.KeepBestPrecision,
+		ReplaceIfNewer:    c.ReplaceIfNewer,
+		DomainFacts:       facts,
 	}
 	body, err := json.Marshal(req)
 	if err != nil {
@@ -134,7 +265,10 @@ func (c *CoordinatorClient) SubmitBatch(ctx context.Context, batchID int64, doma
 		return err
 	}
 	httpReq.Header.Set("Content-Type", "application/json")
-	httpReq.Header.Set("Authorization", "Bearer "+c.Token)
+	c.setAuth(httpReq)
+	httpReq.Header.Set(api.ScannerAPIVersionHeader, strconv.Itoa(api.ScannerAPIVersion))
+	// Retries of the same batch reuse the key, so the coordinator processes it once
+	httpReq.Header.Set("Idempotency-Key", fmt.Sprintf("%s-%d", c.SessionID, batchID))
 
 	resp, err := c.HTTPClient.Do(httpReq)
 	if err != nil {
@@ -143,8 +277,7 @@ func (c *CoordinatorClient) SubmitBatch(ctx context.Context, batchID int64, doma
 	defer resp.Body.Close() //nolint:errcheck // Close error not actionable
 
 	if resp.StatusCode != http.StatusOK {
-		bodyBytes, _ := io.ReadAll(resp.Body) //nolint:errcheck // Best effort to get error details
-		return fmt.Errorf("submit batch failed: %d %s", resp.StatusCode, string(bodyBytes))
+		return responseError("submit batch", resp)
 	}
 
 	return nil
@@ -2,19 +2,27 @@ package scanner
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"log"
 	"net"
+	"strconv"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/miekg/dns"
 	"github.com/zmap/zdns/v2/src/zdns"
+
+	"github.com/locplace/scanner/pkg/api"
 )
 
 // DNSConfig holds configuration for DNS lookups.
 type DNSConfig struct {
-	// Nameservers to use for lookups.
+	// Nameservers to use for lookups, as IPv4 addresses with an optional
+	// port ("9.9.9.9" or "10.0.0.53:5353"). Queries rotate round-robin
+	// across them.
 	Nameservers []string
 	// Timeout for each DNS query.
 	Timeout time.Duration
@@ -39,6 +47,9 @@ type DNSScanner struct {
 	initOnce     sync.Once
 	initErr      error
 	mu           sync.Mutex
+
+	nameservers []zdns.NameServer // Parsed config.Nameservers, set by initPool
+	nextNS      atomic.Uint64     // Round-robin position in nameservers
 }
 
 // NewDNSScanner creates a new DNS scanner.
@@ -58,6 +69,19 @@ func NewDNSScanner(config DNSConfig) *DNSScanner {
 // initPool initializes the resolver pool (called once lazily)
 func (s *DNSScanner) initPool() error {
 	s.initOnce.Do(func() {
+		for _, ns := range s.config.Nameservers {
+			parsed, err := parseNameserver(ns)
+			if err != nil {
+				s.initErr = err
+				return
+			}
+			s.nameservers = append(s.nameservers, parsed)
+		}
+		if len(s.nameservers) == 0 {
+			s.initErr = errors.New("no DNS nameservers configured")
+			return
+		}
+
 		for i := 0; i < s.poolSize; i++ {
 			resolver, err := s.createResolver()
 			if err != nil {
@@ -72,24 +96,61 @@ func (s *DNSScanner) initPool() error {
 
 // createResolver creates a new zdns resolver instance
 func (s *DNSScanner) createResolver() (*zdns.Resolver, error) {
-	// Build nameserver list
-	nameservers := make([]zdns.NameServer, len(s.config.Nameservers))
-	for i, ns := range s.config.Nameservers {
-		nameservers[i] = zdns.NameServer{
-			IP:   net.ParseIP(ns),
-			Port: 53,
-		}
-	}
-
 	// Create resolver config
 	config := zdns.NewResolverConfig()
-	config.ExternalNameServersV4 = nameservers
+	config.ExternalNameServersV4 = s.nameservers
 	config.Timeout = s.config.Timeout
 	config.IPVersionMode = zdns.IPv4Only
 
 	return zdns.InitResolver(config)
 }
 
+// nextNameserver returns the nameserver for the next query, rotating
+// round-robin through the configured list.
+func (s *DNSScanner) nextNameserver() *zdns.NameServer {
+	i := s.nextNS.Add(1) - 1
+	ns := s.nameservers[i%uint64(len(s.nameservers))]
+	return &ns
+}
+
+// parseNameserver parses "ip" or "ip:port" into a zdns nameserver. Only IPv4
+// is accepted, since resolvers run in IPv4-only mode.
+func parseNameserver(s string) (zdns.NameServer, error) {
+	host, port := s, uint16(53)
+	if h, p, err := net.SplitHostPort(s); err == nil {
+		n, err := strconv.ParseUint(p, 10, 16)
+		if err != nil || n == 0 {
+			return zdns.NameServer{}, fmt.Errorf("invalid nameserver port in %q", s)
+		}
+		host, port = h, uint16(n)
+	}
+	ip := net.ParseIP(host)
+	if ip == nil || ip.To4() == nil {
+		return zdns.NameServer{}, fmt.Errorf("nameserver %q is not an IPv4 address", s)
+	}
+	return zdns.NameServer{IP: ip.To4(), Port: port}, nil
+}
+
+// ParseNameservers parses a comma-separated nameserver list, as accepted in
+// DNSConfig.Nameservers.
+func ParseNameservers(list string) ([]string, error) {
+	var nameservers []string
+	for _, ns := range strings.Split(list, ",") {
+		ns = strings.TrimSpace(ns)
+		if ns == "" {
+			continue
+		}
+		if _, err := parseNameserver(ns); err != nil {
+			return nil, err
+		}
+		nameservers = append(nameservers, ns)
+	}
+	if len(nameservers) == 0 {
+		return nil, errors.New("no nameservers given")
+	}
+	return nameservers, nil
+}
+
 // getResolver borrows a resolver from the pool
 func (s *DNSScanner) getResolver() (*zdns.Resolver, error) {
 	if err := s.initPool(); err != nil {
@@ -123,15 +184,49 @@ func (s *DNSScanner) Close() error {
 
 // LOCResult represents the result of a LOC lookup.
 type LOCResult struct {
-	FQDN      string
-	HasLOC    bool
-	RawRecord string
-	Error     error
+	FQDN   string
+	HasLOC bool
+	// RawRecords holds one presentation-format record per LOC RR, in
+	// answer order; a name may publish several.
+	RawRecords []string
+	Error      error
+	// ErrorClass is set when the lookup failed in a way that says nothing
+	// about whether a LOC record exists (see api.ScanErrorClasses).
+	ErrorClass string
+	Status     string // DNS status reported by zdns, e.g. "SERVFAIL"
+	// CNAMEChain lists the CNAME targets followed to reach the LOC record,
+	// in order; the last entry is the name that holds it.
+	CNAMEChain []string
+	ObservedAt time.Time // When the lookup was made
+}
+
+// ScanErrorClass classifies a failed lookup. It returns "" for answers that
+// are conclusive (NOERROR, NXDOMAIN, no answer), so only genuine resolution
+// failures are reported as scan errors.
+func ScanErrorClass(status zdns.Status, err error) string {
+	if err != nil {
+		if errors.Is(err, context.DeadlineExceeded) {
+			return api.ScanErrorTimeout
+		}
+		return api.ScanErrorOther
+	}
+	switch status {
+	case zdns.StatusNoError, zdns.StatusNXDomain, zdns.StatusNoAnswer, zdns.StatusNoRecord:
+		return ""
+	case zdns.StatusTimeout, zdns.StatusIterTimeout:
+		return api.ScanErrorTimeout
+	case zdns.StatusServFail:
+		return api.ScanErrorServFail
+	case zdns.StatusRefused:
+		return api.ScanErrorRefused
+	default:
+		return api.ScanErrorOther
+	}
 }
 
 // LookupLOC performs a LOC record lookup for a single domain.
 func (s *DNSScanner) LookupLOC(ctx context.Context, fqdn string) LOCResult {
-	result := LOCResult{FQDN: fqdn}
+	result := LOCResult{FQDN: fqdn, ObservedAt: time.Now()}
 
 	// Sanitize input: strip trailing dot to prevent zdns fatal error
 	// ("name already has trailing dot")
@@ -149,38 +244,187 @@ func (s *DNSScanner) LookupLOC(ctx context.Context, fqdn string) LOCResult {
 	}
 	defer s.returnResolver(resolver)
 
-	// Create LOC query
-	question := &zdns.Question{
-		Type:  dns.TypeLOC,
-		Class: dns.ClassINET,
-		Name:  fqdn,
+	// Follow CNAMEs that the resolver did not chase itself, up to maxCNAMEHops
+	name := fqdn
+	seen := map[string]bool{fqdn: true}
+	for {
+		question := &zdns.Question{
+			Type:  dns.TypeLOC,
+			Class: dns.ClassINET,
+			Name:  name,
+		}
+
+		// Perform lookup
+		queryResult, _, status, err := resolver.ExternalLookup(ctx, question, s.nextNameserver())
+		result.Status = string(status)
+		if err != nil {
+			result.Error = err
+			result.ErrorClass = ScanErrorClass(status, err)
+			return result
+		}
+
+		// Check status
+		if status != zdns.StatusNoError {
+			// NXDOMAIN etc. mean no LOC record; SERVFAIL/timeouts are reported as scan errors
+			result.ErrorClass = ScanErrorClass(status, nil)
+			return result
+		}
+
+		var answers []any
+		if queryResult != nil {
+			answers = queryResult.Answers
+		}
+		locAnswers, cnames := scanAnswers(answers)
+		result.CNAMEChain = append(result.CNAMEChain, cnames...)
+
+		if len(locAnswers) > 0 {
+			for _, a := range locAnswers {
+				raw, err := locPresentation(a)
+				if err != nil {
+					log.Printf("Warning: ignoring LOC record for %s: %v", fqdn, err)
+					result.Error = err
+					continue
+				}
+				result.RawRecords = append(result.RawRecords, raw)
+			}
+			if len(result.RawRecords) > 0 {
+				result.HasLOC = true
+				result.Error = nil // Usable records outweigh the ignored ones
+			}
+			return result
+		}
+
+		if len(cnames) == 0 {
+			return result // No LOC record
+		}
+		target := cnames[len(cnames)-1]
+		if seen[target] || len(result.CNAMEChain) > maxCNAMEHops {
+			log.Printf("Warning: CNAME chain for %s loops or exceeds %d hops, giving up", fqdn, maxCNAMEHops)
+			return result
+		}
+		seen[target] = true
+		name = target
+	}
+}
+
+// maxCNAMEHops bounds how many CNAMEs LookupLOC follows for a single FQDN.
+const maxCNAMEHops = 8
+
+// scanAnswers returns the LOC answers and the CNAME targets, in answer
+// order, from a zdns answer section.
+func scanAnswers(answers []any) ([]zdns.LOCAnswer, []string) {
+	var locs []zdns.LOCAnswer
+	var cnames []string
+	for _, answer := range answers {
+		// zdns returns value types, not pointers
+		switch a := answer.(type) {
+		case zdns.LOCAnswer:
+			locs = append(locs, a)
+		case zdns.Answer:
+			if a.RrType == dns.TypeCNAME {
+				cnames = append(cnames, strings.TrimSuffix(a.Answer, "."))
+			}
+		}
+	}
+	return locs, cnames
+}
+
+// ErrUnsupportedLOCVersion is returned for LOC records with a nonzero VERSION.
+// RFC 1876 only defines version 0; other versions use an undefined format
+// whose fields must not be interpreted as v0.
+var ErrUnsupportedLOCVersion = errors.New("unsupported LOC record version")
+
+// locPresentation returns the presentation-format coordinates of a decoded LOC
+// answer, rejecting versions other than 0.
+func locPresentation(a zdns.LOCAnswer) (string, error) {
+	if a.Version != 0 {
+		return "", fmt.Errorf("%w %d", ErrUnsupportedLOCVersion, a.Version)
 	}
+	return a.Coordinates, nil
+}
 
-	// Perform lookup
-	queryResult, _, status, err := resolver.ExternalLookup(ctx, question, nil)
+// LookupFacts collects api.DomainFacts for fqdn with A, AAAA and MX
+// lookups. It returns an error if any lookup fails inconclusively, so
+// partial facts are never reported.
+func (s *DNSScanner) LookupFacts(ctx context.Context, fqdn string) (api.DomainFacts, error) {
+	facts := api.DomainFacts{FQDN: fqdn}
+
+	resolver, err := s.getResolver()
 	if err != nil {
-		result.Error = err
-		return result
+		return facts, err
 	}
+	defer s.returnResolver(resolver)
 
-	// Check status
-	if status != zdns.StatusNoError {
-		return result // No LOC record, not an error
+	found := make(map[uint16]bool, 3)
+	for _, qtype := range []uint16{dns.TypeA, dns.TypeAAAA, dns.TypeMX} {
+		question := &zdns.Question{Type: qtype, Class: dns.ClassINET, Name: fqdn}
+		queryResult, _, status, err := resolver.ExternalLookup(ctx, question, s.nextNameserver())
+		if class := ScanErrorClass(status, err); class != "" {
+			if err == nil {
+				err = fmt.Errorf("%s lookup: %s", dns.TypeToString[qtype], status)
+			}
+			return facts, err
+		}
+		if queryResult != nil {
+			found[qtype] = hasAnswer(queryResult.Answers, qtype)
+		}
 	}
 
-	// Check for LOC answers
-	if queryResult != nil && queryResult.Answers != nil {
-		for _, answer := range queryResult.Answers {
-			// zdns returns value types, not pointers
-			if locAnswer, ok := answer.(zdns.LOCAnswer); ok {
-				result.HasLOC = true
-				result.RawRecord = locAnswer.Coordinates
-				return result
+	facts.HasAAAA = found[dns.TypeAAAA]
+	facts.Resolves = found[dns.TypeA] || facts.HasAAAA
+	facts.HasMX = found[dns.TypeMX]
+	return facts, nil
+}
+
+// hasAnswer reports whether answers hold a record of type rrType, skipping
+// the CNAMEs that lead to it.
+func hasAnswer(answers []any, rrType uint16) bool {
+	for _, answer := range answers {
+		switch a := answer.(type) {
+		case zdns.Answer:
+			if a.RrType == rrType {
+				return true
+			}
+		case zdns.PrefAnswer:
+			if a.RrType == rrType {
+				return true
 			}
 		}
 	}
+	return false
+}
+
+// LookupFactsBatch collects domain facts for multiple FQDNs concurrently.
+// FQDNs whose lookups fail are left out.
+func (s *DNSScanner) LookupFactsBatch(ctx context.Context, fqdns []string) []api.DomainFacts {
+	results := make([]*api.DomainFacts, len(fqdns))
+	var wg sync.WaitGroup
+	sem := make(chan struct{}, s.config.Workers)
+
+	for i, fqdn := range fqdns {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			select {
+			case sem <- struct{}{}:
+				defer func() { <-sem }()
+			case <-ctx.Done():
+				return
+			}
+			if facts, err := s.LookupFacts(ctx, fqdn); err == nil {
+				results[i] = &facts
+			}
+		}()
+	}
+	wg.Wait()
 
-	return result
+	var facts []api.DomainFacts
+	for _, f := range results {
+		if f != nil {
+			facts = append(facts, *f)
+		}
+	}
+	return facts
 }
 
 // LookupLOCBatch performs LOC lookups for multiple domains concurrently.
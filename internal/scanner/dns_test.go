@@ -1,8 +1,19 @@
 package scanner
 
 import (
+	"context"
+	"errors"
+	"net"
+	"slices"
+	"strconv"
+	"strings"
 	"testing"
 	"time"
+
+	"github.com/miekg/dns"
+	"github.com/zmap/zdns/v2/src/zdns"
+
+	"github.com/locplace/scanner/pkg/api"
 )
 
 func TestDefaultDNSConfig(t *testing.T) {
@@ -78,10 +89,10 @@ func TestNewDNSScanner(t *testing.T) {
 func TestLOCResult_Fields(t *testing.T) {
 	// Test that LOCResult struct can hold all expected data
 	result := LOCResult{
-		FQDN:      "example.com",
-		HasLOC:    true,
-		RawRecord: "52 22 23.000 N 4 53 32.000 E -2.00m 1m 10000m 10m",
-		Error:     nil,
+		FQDN:       "example.com",
+		HasLOC:     true,
+		RawRecords: []string{"52 22 23.000 N 4 53 32.000 E -2.00m 1m 10000m 10m"},
+		Error:      nil,
 	}
 
 	if result.FQDN != "example.com" {
@@ -90,8 +101,8 @@ func TestLOCResult_Fields(t *testing.T) {
 	if !result.HasLOC {
 		t.Error("HasLOC should be true")
 	}
-	if result.RawRecord == "" {
-		t.Error("RawRecord should not be empty")
+	if len(result.RawRecords) == 0 {
+		t.Error("RawRecords should not be empty")
 	}
 	if result.Error != nil {
 		t.Errorf("Error should be nil, got %v", result.Error)
@@ -112,3 +123,159 @@ func TestDNSConfig_ZeroValues(t *testing.T) {
 		t.Errorf("Zero-value Nameservers = %v, want empty", config.Nameservers)
 	}
 }
+
+func TestScanErrorClass(t *testing.T) {
+	tests := []struct {
+		name   string
+		status zdns.Status
+		err    error
+		want   string
+	}{
+		{"no error", zdns.StatusNoError, nil, ""},
+		{"nxdomain is conclusive", zdns.StatusNXDomain, nil, ""},
+		{"no answer is conclusive", zdns.StatusNoAnswer, nil, ""},
+		{"servfail", zdns.StatusServFail, nil, api.ScanErrorServFail},
+		{"refused", zdns.StatusRefused, nil, api.ScanErrorRefused},
+		{"timeout status", zdns.StatusTimeout, nil, api.ScanErrorTimeout},
+		{"deadline error", zdns.StatusError, context.DeadlineExceeded, api.ScanErrorTimeout},
+		{"other error", zdns.StatusError, errors.New("boom"), api.ScanErrorOther},
+		{"unexpected status", zdns.StatusFormErr, nil, api.ScanErrorOther},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ScanErrorClass(tt.status, tt.err); got != tt.want {
+				t.Errorf("ScanErrorClass(%q, %v) = %q, want %q", tt.status, tt.err, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLocPresentation_Version(t *testing.T) {
+	const coords = "52 22 23.000 N 4 53 32.000 E -2.00m 1.00m 10000.00m 10.00m"
+
+	raw, err := locPresentation(zdns.LOCAnswer{Version: 0, Coordinates: coords})
+	if err != nil || raw != coords {
+		t.Errorf("version 0: got %q, %v; want %q, nil", raw, err, coords)
+	}
+
+	_, err = locPresentation(zdns.LOCAnswer{Version: 1, Coordinates: coords})
+	if !errors.Is(err, ErrUnsupportedLOCVersion) {
+		t.Errorf("version 1: err = %v, want ErrUnsupportedLOCVersion", err)
+	}
+}
+
+func TestScanAnswers(t *testing.T) {
+	cname := func(name, target string) zdns.Answer {
+		return zdns.Answer{Type: "CNAME", RrType: dns.TypeCNAME, Name: name, Answer: target}
+	}
+	loc := zdns.LOCAnswer{Coordinates: "52 22 23.000 N 4 53 32.000 E -2.00m 1.00m 10000.00m 10.00m"}
+	loc2 := zdns.LOCAnswer{Coordinates: "51 30 12.000 N 0 7 39.000 W 5.00m 1.00m 500.00m 2.00m"}
+
+	tests := []struct {
+		name       string
+		answers    []any
+		wantLOC    int
+		wantCNAMEs []string
+	}{
+		{name: "direct LOC", answers: []any{loc}, wantLOC: 1},
+		{name: "multiple LOC records", answers: []any{loc, loc2}, wantLOC: 2},
+		{
+			name:       "LOC behind CNAME chain",
+			answers:    []any{cname("www.example.com", "a.example.net."), cname("a.example.net", "b.example.org."), loc},
+			wantLOC:    1,
+			wantCNAMEs: []string{"a.example.net", "b.example.org"},
+		},
+		{
+			name:       "CNAME without LOC",
+			answers:    []any{cname("www.example.com", "cdn.example.net.")},
+			wantCNAMEs: []string{"cdn.example.net"},
+		},
+		{name: "no answers"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gotLOC, gotCNAMEs := scanAnswers(tt.answers)
+			if len(gotLOC) != tt.wantLOC {
+				t.Errorf("LOC answers = %d, want %d", len(gotLOC), tt.wantLOC)
+			}
+			if strings.Join(gotCNAMEs, ",") != strings.Join(tt.wantCNAMEs, ",") {
+				t.Errorf("CNAMEs = %v, want %v", gotCNAMEs, tt.wantCNAMEs)
+			}
+		})
+	}
+}
+
+func TestParseNameservers(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    []string
+		wantErr bool
+	}{
+		{in: "9.9.9.9", want: []string{"9.9.9.9"}},
+		{in: "10.0.0.53:5353, 1.1.1.1,", want: []string{"10.0.0.53:5353", "1.1.1.1"}},
+		{in: "", wantErr: true},
+		{in: "dns.example.com", wantErr: true},
+		{in: "2001:db8::53", wantErr: true},
+		{in: "1.1.1.1:0", wantErr: true},
+		{in: "1.1.1.1:99999", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			got, err := ParseNameservers(tt.in)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseNameservers(%q) error = %v, wantErr %v", tt.in, err, tt.wantErr)
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("ParseNameservers(%q) = %v, want %v", tt.in, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNextNameserver_RoundRobin(t *testing.T) {
+	s := NewDNSScanner(DNSConfig{Nameservers: []string{"1.1.1.1", "9.9.9.9:5353"}})
+	for _, ns := range s.config.Nameservers {
+		parsed, err := parseNameserver(ns)
+		if err != nil {
+			t.Fatal(err)
+		}
+		s.nameservers = append(s.nameservers, parsed)
+	}
+
+	want := []string{"1.1.1.1:53", "9.9.9.9:5353", "1.1.1.1:53"}
+	for i, w := range want {
+		ns := s.nextNameserver()
+		if got := net.JoinHostPort(ns.IP.String(), strconv.Itoa(int(ns.Port))); got != w {
+			t.Errorf("query %d went to %s, want %s", i, got, w)
+		}
+	}
+}
+
+func TestHasAnswer(t *testing.T) {
+	cname := zdns.Answer{Type: "CNAME", RrType: dns.TypeCNAME, Name: "www.example.com", Answer: "cdn.example.net."}
+	a := zdns.Answer{Type: "A", RrType: dns.TypeA, Name: "cdn.example.net", Answer: "192.0.2.1"}
+	mx := zdns.PrefAnswer{Answer: zdns.Answer{Type: "MX", RrType: dns.TypeMX, Name: "example.com", Answer: "mail.example.com."}, Preference: 10}
+
+	tests := []struct {
+		name    string
+		answers []any
+		rrType  uint16
+		want    bool
+	}{
+		{name: "A behind CNAME", answers: []any{cname, a}, rrType: dns.TypeA, want: true},
+		{name: "CNAME only", answers: []any{cname}, rrType: dns.TypeA, want: false},
+		{name: "MX", answers: []any{mx}, rrType: dns.TypeMX, want: true},
+		{name: "MX queried for AAAA", answers: []any{mx}, rrType: dns.TypeAAAA, want: false},
+		{name: "no answers", rrType: dns.TypeMX, want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := hasAnswer(tt.answers, tt.rrType); got != tt.want {
+				t.Errorf("hasAnswer() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
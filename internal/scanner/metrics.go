@@ -20,6 +20,7 @@ type Metrics struct {
 	LOCRecordsFoundTotal prometheus.Counter
 	SubmitRetries        prometheus.Counter
 	SubmitFailures       prometheus.Counter
+	ParseFailures        prometheus.Counter
 }
 
 // NewMetrics creates and registers scanner metrics.
@@ -74,6 +75,11 @@ func NewMetrics(registry prometheus.Registerer) *Metrics {
 			Name: "scanner_submit_failures_total",
 			Help: "Total number of failed submissions (after all retries).",
 		}),
+
+		ParseFailures: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: "scanner_loc_parse_failures_total",
+			Help: "Total number of LOC answers that could not be parsed.",
+		}),
 	}
 
 	registry.MustRegister(
@@ -86,6 +92,7 @@ func NewMetrics(registry prometheus.Registerer) *Metrics {
 		m.LOCRecordsFoundTotal,
 		m.SubmitRetries,
 		m.SubmitFailures,
+		m.ParseFailures,
 	)
 
 	return m
@@ -2,11 +2,32 @@ package scanner
 
 import (
 	"context"
+	"crypto/tls"
+	"errors"
+	"io/fs"
 	"log"
+	"net/http"
+	"os"
+	"slices"
+	"strings"
 	"sync"
 	"time"
+
+	"github.com/locplace/scanner/pkg/api"
 )
 
+// Version is the scanner version reported to the coordinator. Release builds
+// set it with -ldflags "-X github.com/locplace/scanner/internal/scanner.Version=v1.2.3".
+var Version = "dev"
+
+// Capabilities lists the optional protocol features this scanner supports.
+var Capabilities = []string{
+	api.CapabilityCNAMEChain,
+	api.CapabilityScanErrors,
+	api.CapabilityParseErrors,
+	api.CapabilityIdempotencyKey,
+}
+
 // Config holds the scanner configuration.
 type Config struct {
 	CoordinatorURL    string
@@ -14,6 +35,26 @@ type Config struct {
 	WorkerCount       int
 	HeartbeatInterval time.Duration
 	DNSConfig         DNSConfig
+
+	// StateFile is an optional path where the scanner persists its session ID.
+	// After a restart, the batches still leased to the previous session are
+	// taken over and scanned first, instead of waiting for the reaper.
+	StateFile string
+
+	// KeepBestPrecision asks the coordinator not to replace stored records with
+	// lower-precision observations from this scanner.
+	KeepBestPrecision bool
+	// ReplaceIfNewer still lets this scanner's lower-precision observations
+	// replace stored records last seen before them (with KeepBestPrecision).
+	ReplaceIfNewer bool
+
+	// CollectDomainFacts also records whether each FQDN resolves and has
+	// AAAA/MX records. It triples the queries per FQDN, so it is off by default.
+	CollectDomainFacts bool
+
+	// TLSConfig, if set, is used for connections to the coordinator, e.g. to
+	// present a client certificate (see ClientTLSConfig).
+	TLSConfig *tls.Config
 }
 
 // DefaultConfig returns the default scanner configuration.
@@ -40,13 +81,57 @@ type Scanner struct {
 
 // New creates a new scanner.
 func New(config Config) *Scanner {
+	coordinator := NewCoordinatorClient(config.CoordinatorURL, config.Token)
+	coordinator.KeepBestPrecision = config.KeepBestPrecision
+	coordinator.ReplaceIfNewer = config.ReplaceIfNewer
+	if config.TLSConfig != nil {
+		transport := http.DefaultTransport.(*http.Transport).Clone() //nolint:errcheck // DefaultTransport is always an *http.Transport
+		transport.TLSClientConfig = config.TLSConfig
+		coordinator.HTTPClient.Transport = transport
+	}
+	coordinator.Version = Version
+	coordinator.Capabilities = Capabilities
+	if config.CollectDomainFacts {
+		coordinator.Capabilities = append(slices.Clone(Capabilities), api.CapabilityDomainFacts)
+	}
+
+	if config.StateFile != "" {
+		previous, err := loadSessionID(config.StateFile)
+		if err != nil {
+			log.Printf("Warning: failed to read state file %s: %v", config.StateFile, err)
+		} else if previous != "" {
+			log.Printf("Resuming from previous session %s", previous)
+			coordinator.SetResumeSessionID(previous)
+		}
+		if err := saveSessionID(config.StateFile, coordinator.SessionID); err != nil {
+			log.Printf("Warning: failed to write state file %s: %v", config.StateFile, err)
+		}
+	}
+
 	return &Scanner{
 		config:      config,
-		coordinator: NewCoordinatorClient(config.CoordinatorURL, config.Token),
+		coordinator: coordinator,
 		shutdownCh:  make(chan struct{}),
 	}
 }
 
+// loadSessionID reads a persisted session ID. A missing file is not an error.
+func loadSessionID(path string) (string, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return "", nil
+	}
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(data)), nil
+}
+
+// saveSessionID persists the session ID so the next run can resume from it.
+func saveSessionID(path, sessionID string) error {
+	return os.WriteFile(path, []byte(sessionID+"\n"), 0o600)
+}
+
 // InitiateShutdown signals workers to stop fetching new jobs.
 // Workers will finish their current batch before exiting.
 func (s *Scanner) InitiateShutdown() {
@@ -75,9 +160,10 @@ func (s *Scanner) Run(ctx context.Context) error {
 	// Start workers
 	var wg sync.WaitGroup
 	workerConfig := WorkerConfig{
-		DNSConfig:       s.config.DNSConfig,
-		RetryDelay:      5 * time.Second,
-		EmptyQueueDelay: 30 * time.Second,
+		DNSConfig:          s.config.DNSConfig,
+		RetryDelay:         5 * time.Second,
+		EmptyQueueDelay:    30 * time.Second,
+		CollectDomainFacts: s.config.CollectDomainFacts,
 	}
 
 	for i := 0; i < s.config.WorkerCount; i++ {
@@ -0,0 +1,123 @@
+package scanner
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/locplace/scanner/pkg/api"
+)
+
+func TestSessionIDStateFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "state")
+
+	// Missing file means no previous session
+	got, err := loadSessionID(path)
+	if err != nil {
+		t.Fatalf("loadSessionID() on missing file error: %v", err)
+	}
+	if got != "" {
+		t.Errorf("loadSessionID() on missing file = %q, want empty", got)
+	}
+
+	if err := saveSessionID(path, "3f2b6c1e-0000-4000-8000-000000000001"); err != nil {
+		t.Fatalf("saveSessionID() error: %v", err)
+	}
+
+	got, err = loadSessionID(path)
+	if err != nil {
+		t.Fatalf("loadSessionID() error: %v", err)
+	}
+	if got != "3f2b6c1e-0000-4000-8000-000000000001" {
+		t.Errorf("loadSessionID() = %q, want saved session ID", got)
+	}
+}
+
+func TestGetBatch_ResumesUntilNoneLeft(t *testing.T) {
+	leased := []int64{7, 9}
+	var resumeIDs []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req api.GetBatchRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			t.Errorf("decode request: %v", err)
+		}
+		resumeIDs = append(resumeIDs, req.ResumeSessionID)
+		resp := api.GetBatchResponse{BatchID: 100, Domains: []string{"new.example"}}
+		if req.ResumeSessionID != "" && len(leased) > 0 {
+			resp = api.GetBatchResponse{BatchID: leased[0], Domains: []string{"old.example"}, Resumed: true}
+			leased = leased[1:]
+		}
+		json.NewEncoder(w).Encode(resp) //nolint:errcheck
+	}))
+	defer srv.Close()
+
+	c := NewCoordinatorClient(srv.URL, "token")
+	c.SetResumeSessionID("previous")
+	var got []int64
+	for range 4 {
+		b, err := c.GetBatch(context.Background())
+		if err != nil {
+			t.Fatalf("GetBatch() error: %v", err)
+		}
+		got = append(got, b.ID)
+	}
+
+	if want := []int64{7, 9, 100, 100}; !slices.Equal(got, want) {
+		t.Errorf("batches = %v, want %v", got, want)
+	}
+	if want := []string{"previous", "previous", "previous", ""}; !slices.Equal(resumeIDs, want) {
+		t.Errorf("resume session IDs sent = %q, want %q", resumeIDs, want)
+	}
+}
+
+func TestNew_ResumesFromStateFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "state")
+
+	cfg := DefaultConfig()
+	cfg.StateFile = path
+
+	first := New(cfg)
+	if first.coordinator.takeResumeSessionID() != "" {
+		t.Error("first run should not resume a session")
+	}
+
+	second := New(cfg)
+	if got := second.coordinator.takeResumeSessionID(); got != first.coordinator.SessionID {
+		t.Errorf("resume session = %q, want %q", got, first.coordinator.SessionID)
+	}
+}
+
+func TestSubmitBatch_Unavailable(t *testing.T) {
+	retryAfter := "7"
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if retryAfter != "" {
+			w.Header().Set("Retry-After", retryAfter)
+		}
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer srv.Close()
+
+	c := NewCoordinatorClient(srv.URL, "token")
+	err := c.SubmitBatch(context.Background(), 1, 1, nil, nil, nil, nil)
+	delay, ok := unavailableDelay(err)
+	if !ok || delay != 7*time.Second {
+		t.Errorf("unavailableDelay(%v) = %s, %v; want 7s, true", err, delay, ok)
+	}
+
+	// Without Retry-After the scanner still waits instead of dropping results
+	retryAfter = ""
+	err = c.SubmitBatch(context.Background(), 1, 1, nil, nil, nil, nil)
+	if delay, ok := unavailableDelay(err); !ok || delay != unavailableRetryDelay {
+		t.Errorf("unavailableDelay(%v) = %s, %v; want %s, true", err, delay, ok, unavailableRetryDelay)
+	}
+
+	if _, ok := unavailableDelay(errors.New("submit batch failed: 500")); ok {
+		t.Error("unavailableDelay() matched a non-503 error")
+	}
+}
@@ -2,12 +2,14 @@ package scanner
 
 import (
 	"context"
+	"errors"
 	"log"
 	"math"
 	"math/rand/v2"
 	"time"
 
 	"github.com/locplace/scanner/pkg/api"
+	"github.com/locplace/scanner/pkg/loc"
 )
 
 // WorkerConfig holds configuration for a scanner worker.
@@ -16,6 +18,9 @@ type WorkerConfig struct {
 	RetryDelay      time.Duration
 	EmptyQueueDelay time.Duration
 	MaxBackoff      time.Duration
+	// CollectDomainFacts adds A/AAAA/MX lookups for each FQDN whose LOC
+	// lookup succeeded (see api.DomainFacts).
+	CollectDomainFacts bool
 }
 
 // DefaultWorkerConfig returns the default worker configuration.
@@ -66,6 +71,23 @@ func (w *Worker) backoffDelay() time.Duration {
 	return time.Duration(delay)
 }
 
+// unavailableRetryDelay is how long to wait after a 503 without Retry-After.
+const unavailableRetryDelay = 30 * time.Second
+
+// unavailableDelay reports whether err is a 503 from the coordinator (see
+// UnavailableError) and how long to wait before trying again. Such errors
+// are expected during maintenance and don't count towards backoff.
+func unavailableDelay(err error) (time.Duration, bool) {
+	var unavailable *UnavailableError
+	if !errors.As(err, &unavailable) {
+		return 0, false
+	}
+	if unavailable.RetryAfter > 0 {
+		return unavailable.RetryAfter, true
+	}
+	return unavailableRetryDelay, true
+}
+
 // recordError increments the consecutive error count.
 // Returns true if this is the first error (entering error state).
 func (w *Worker) recordError() bool {
@@ -125,6 +147,18 @@ func (w *Worker) Run(ctx context.Context) {
 			if w.Metrics != nil {
 				w.Metrics.GetJobsDuration.WithLabelValues("error").Observe(getBatchDuration)
 			}
+			if delay, ok := unavailableDelay(err); ok {
+				log.Printf("[Worker %d] Coordinator unavailable, retrying in %s", w.ID, delay)
+				select {
+				case <-w.ShutdownCh:
+					log.Printf("[Worker %d] Shutdown signal received, exiting", w.ID)
+					return
+				case <-ctx.Done():
+					return
+				case <-time.After(delay):
+				}
+				continue
+			}
 			if w.recordError() {
 				log.Printf("[Worker %d] Connection error: %v (entering backoff)", w.ID, err)
 			}
@@ -161,7 +195,7 @@ func (w *Worker) Run(ctx context.Context) {
 
 		// Process the batch
 		batchStart := time.Now()
-		locRecords := w.processBatch(ctx, batch.Domains)
+		locRecords, scanErrors, parseErrors, facts := w.processBatch(ctx, batch.Domains)
 		batchDuration := time.Since(batchStart).Seconds()
 
 		hasLOC := len(locRecords) > 0
@@ -171,7 +205,7 @@ func (w *Worker) Run(ctx context.Context) {
 		var submitDuration float64
 		for attempt := 1; attempt <= 3; attempt++ {
 			submitStart := time.Now()
-			err := w.Coordinator.SubmitBatch(ctx, batch.ID, len(batch.Domains), locRecords)
+			err := w.Coordinator.SubmitBatch(ctx, batch.ID, len(batch.Domains), locRecords, scanErrors, parseErrors, facts)
 			submitDuration = time.Since(submitStart).Seconds()
 
 			if err == nil {
@@ -187,6 +221,23 @@ func (w *Worker) Run(ctx context.Context) {
 				break
 			}
 
+			// The coordinator is in maintenance: wait it out without using up
+			// an attempt, since heartbeats keep the batch leased meanwhile
+			if retryDelay, ok := unavailableDelay(err); ok {
+				if w.Metrics != nil {
+					w.Metrics.SubmitRetries.Inc()
+				}
+				log.Printf("[Worker %d] Coordinator unavailable for batch %d, retrying in %s",
+					w.ID, batch.ID, retryDelay)
+				select {
+				case <-ctx.Done():
+					return
+				case <-time.After(retryDelay):
+				}
+				attempt--
+				continue
+			}
+
 			if attempt < 3 {
 				if w.Metrics != nil {
 					w.Metrics.SubmitRetries.Inc()
@@ -226,7 +277,9 @@ func (w *Worker) Run(ctx context.Context) {
 }
 
 // processBatch scans all FQDNs in the batch for LOC records.
-func (w *Worker) processBatch(ctx context.Context, fqdns []string) []api.LOCRecord {
+// Also returns the lookups that failed to resolve (SERVFAIL, timeouts, ...),
+// the LOC answers that could not be parsed and, if enabled, domain facts.
+func (w *Worker) processBatch(ctx context.Context, fqdns []string) ([]api.LOCRecord, []api.ScanError, []api.ParseError, []api.DomainFacts) {
 	log.Printf("[Worker %d] Processing batch of %d FQDNs", w.ID, len(fqdns))
 
 	// Scan all FQDNs for LOC records
@@ -241,23 +294,53 @@ func (w *Worker) processBatch(ctx context.Context, fqdns []string) []api.LOCReco
 
 	// Collect LOC records
 	var locRecords []api.LOCRecord
+	var scanErrors []api.ScanError
+	var parseErrors []api.ParseError
+	var resolved []string // Conclusive lookups, for domain facts
 	for _, locResult := range locResults {
-		if locResult.Error != nil {
+		if locResult.ErrorClass != "" {
+			msg := locResult.Status
+			if locResult.Error != nil {
+				msg = locResult.Error.Error()
+			}
+			scanErrors = append(scanErrors, api.ScanError{
+				FQDN:    locResult.FQDN,
+				Class:   locResult.ErrorClass,
+				Message: msg,
+			})
+		}
+		if locResult.Error != nil || locResult.ErrorClass != "" {
 			continue
 		}
+		resolved = append(resolved, locResult.FQDN)
 		if !locResult.HasLOC {
 			continue
 		}
 
-		// Parse the LOC record
-		locRecord, err := ParseLOCRecordLenient(locResult.FQDN, locResult.RawRecord)
-		if err != nil {
-			log.Printf("[Worker %d] Failed to parse LOC for %s: %v", w.ID, locResult.FQDN, err)
-			continue
-		}
+		// Parse each LOC record; a name may publish several
+		for _, raw := range locResult.RawRecords {
+			locRecord, err := loc.ParseLenient(locResult.FQDN, raw)
+			if err != nil {
+				log.Printf("[Worker %d] Failed to parse LOC for %s: %v", w.ID, locResult.FQDN, err)
+				parseErrors = append(parseErrors, api.ParseError{
+					FQDN:      locResult.FQDN,
+					RawRecord: raw,
+					Message:   err.Error(),
+				})
+				if w.Metrics != nil {
+					w.Metrics.ParseFailures.Inc()
+				}
+				continue
+			}
 
-		locRecords = append(locRecords, *locRecord)
-		log.Printf("[Worker %d] Found LOC record: %s -> %s", w.ID, locResult.FQDN, locResult.RawRecord)
+			locRecord.CNAMEChain = locResult.CNAMEChain
+			if !locResult.ObservedAt.IsZero() {
+				observedAt := locResult.ObservedAt.UTC()
+				locRecord.ObservedAt = &observedAt
+			}
+			locRecords = append(locRecords, *locRecord)
+			log.Printf("[Worker %d] Found LOC record: %s -> %s", w.ID, locResult.FQDN, raw)
+		}
 	}
 
 	// Record LOC records found distribution
@@ -265,5 +348,10 @@ func (w *Worker) processBatch(ctx context.Context, fqdns []string) []api.LOCReco
 		w.Metrics.LOCRecordsFound.Observe(float64(len(locRecords)))
 	}
 
-	return locRecords
+	var facts []api.DomainFacts
+	if w.Config.CollectDomainFacts && len(resolved) > 0 {
+		facts = w.DNS.LookupFactsBatch(ctx, resolved)
+	}
+
+	return locRecords, scanErrors, parseErrors, facts
 }
@@ -0,0 +1,42 @@
+package scanner
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/miekg/dns"
+
+	"github.com/locplace/scanner/pkg/api"
+	"github.com/locplace/scanner/pkg/loc"
+)
+
+// ParseZoneLOC reads a BIND zone file and returns its LOC records, for
+// ingesting authoritative data without DNS queries. $ORIGIN, $TTL,
+// parenthesized multi-line records and relative owner names (including @)
+// are handled by the zone parser; relative names need an $ORIGIN. Owner
+// names are returned lowercase without the trailing dot. The first malformed
+// line or unparseable LOC record fails the whole file.
+func ParseZoneLOC(r io.Reader) ([]api.LOCRecord, error) {
+	zp := dns.NewZoneParser(r, "", "")
+	zp.SetIncludeAllowed(false) // $INCLUDE could read arbitrary local files
+
+	var records []api.LOCRecord
+	for rr, ok := zp.Next(); ok; rr, ok = zp.Next() {
+		locRR, isLOC := rr.(*dns.LOC)
+		if !isLOC {
+			continue
+		}
+		fqdn := strings.ToLower(strings.TrimSuffix(locRR.Hdr.Name, "."))
+		raw := strings.TrimSpace(strings.TrimPrefix(locRR.String(), locRR.Hdr.String()))
+		rec, err := loc.Parse(fqdn, raw)
+		if err != nil {
+			return nil, fmt.Errorf("LOC record for %s: %w", fqdn, err)
+		}
+		records = append(records, *rec)
+	}
+	if err := zp.Err(); err != nil {
+		return nil, err
+	}
+	return records, nil
+}
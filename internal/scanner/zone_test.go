@@ -0,0 +1,66 @@
+package scanner
+
+import (
+	"math"
+	"strings"
+	"testing"
+)
+
+func TestParseZoneLOC(t *testing.T) {
+	zone := `$ORIGIN example.com.
+$TTL 3600
+@       IN SOA ns1 hostmaster ( 2024060101 7200 3600 1209600 3600 )
+        IN NS  ns1
+        IN LOC 52 22 23.000 N 4 53 32.000 E -2.00m 1m 10000m 10m
+ns1     IN A   192.0.2.1
+WWW     IN LOC ( 37 46 30.000 N
+                 122 25 10.000 W 10m )
+$ORIGIN sub.example.com.
+office  IN LOC 51 30 12.000 N 0 7 39.000 W 5m 1m 500m 2m
+abs.example.org. IN LOC 10 N 20 E 0m
+`
+	records, err := ParseZoneLOC(strings.NewReader(zone))
+	if err != nil {
+		t.Fatalf("ParseZoneLOC() error: %v", err)
+	}
+
+	want := []struct {
+		fqdn     string
+		lat, lon float64
+	}{
+		{"example.com", 52.373056, 4.892222},
+		{"www.example.com", 37.775, -122.419444},
+		{"office.sub.example.com", 51.503333, -0.1275},
+		{"abs.example.org", 10, 20},
+	}
+	if len(records) != len(want) {
+		t.Fatalf("got %d records, want %d: %+v", len(records), len(want), records)
+	}
+	for i, w := range want {
+		got := records[i]
+		if got.FQDN != w.fqdn || math.Abs(got.Latitude-w.lat) > 1e-5 || math.Abs(got.Longitude-w.lon) > 1e-5 {
+			t.Errorf("record %d = %s (%f, %f), want %s (%f, %f)", i, got.FQDN, got.Latitude, got.Longitude, w.fqdn, w.lat, w.lon)
+		}
+	}
+	if records[2].HorizPrecM != 500 || records[2].VertPrecM != 2 {
+		t.Errorf("office precision = %v/%v, want 500/2", records[2].HorizPrecM, records[2].VertPrecM)
+	}
+}
+
+func TestParseZoneLOC_Errors(t *testing.T) {
+	tests := []struct {
+		name string
+		zone string
+	}{
+		{"relative name without origin", "www IN LOC 52 N 4 E 0m\n"},
+		{"malformed LOC", "$ORIGIN example.com.\nwww 3600 IN LOC 91 N 4 E 0m\n"},
+		{"include", "$INCLUDE /etc/passwd\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := ParseZoneLOC(strings.NewReader(tt.zone)); err == nil {
+				t.Error("ParseZoneLOC() should fail")
+			}
+		})
+	}
+}
@@ -0,0 +1,12 @@
+-- Track FQDNs whose lookups failed (SERVFAIL, timeout, ...) so they can be
+-- told apart from FQDNs that simply have no LOC record.
+CREATE TABLE scan_errors (
+    fqdn            TEXT PRIMARY KEY,
+    error_class     TEXT NOT NULL,
+    last_error      TEXT NOT NULL DEFAULT '',
+    scan_attempts   INT NOT NULL DEFAULT 1,
+    first_failed_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    last_failed_at  TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_scan_errors_class ON scan_errors(error_class);
@@ -0,0 +1 @@
+ALTER TABLE loc_records DROP COLUMN IF EXISTS cname_chain;
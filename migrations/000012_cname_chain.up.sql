@@ -0,0 +1,2 @@
+-- CNAME targets followed to reach the LOC record (empty for direct records)
+ALTER TABLE loc_records ADD COLUMN cname_chain TEXT[] NOT NULL DEFAULT '{}';
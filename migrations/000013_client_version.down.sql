@@ -0,0 +1,2 @@
+ALTER TABLE scanner_clients DROP COLUMN IF EXISTS capabilities;
+ALTER TABLE scanner_clients DROP COLUMN IF EXISTS version;
@@ -0,0 +1,3 @@
+-- Software version and capabilities reported by the scanner in heartbeats
+ALTER TABLE scanner_clients ADD COLUMN version TEXT;
+ALTER TABLE scanner_clients ADD COLUMN capabilities TEXT[] NOT NULL DEFAULT '{}';
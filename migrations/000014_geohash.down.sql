@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS idx_loc_records_geohash;
+ALTER TABLE loc_records DROP COLUMN IF EXISTS geohash;
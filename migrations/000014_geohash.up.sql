@@ -0,0 +1,45 @@
+-- Geohash of each record's coordinates (12 characters), for prefix-based
+-- spatial queries without PostGIS. New rows get it from the coordinator.
+ALTER TABLE loc_records ADD COLUMN geohash TEXT;
+
+-- Backfill existing rows with the same encoding as api.Geohash
+CREATE FUNCTION locplace_geohash(lat DOUBLE PRECISION, lon DOUBLE PRECISION, len INT) RETURNS TEXT AS $$
+DECLARE
+    base32  TEXT := '0123456789bcdefghjkmnpqrstuvwxyz';
+    lat_min DOUBLE PRECISION := -90;
+    lat_max DOUBLE PRECISION := 90;
+    lon_min DOUBLE PRECISION := -180;
+    lon_max DOUBLE PRECISION := 180;
+    mid     DOUBLE PRECISION;
+    hash    TEXT := '';
+    ch      INT := 0;
+    nbits   INT := 0;
+    even    BOOLEAN := TRUE;
+BEGIN
+    WHILE length(hash) < len LOOP
+        IF even THEN
+            mid := (lon_min + lon_max) / 2;
+            IF lon >= mid THEN ch := ch * 2 + 1; lon_min := mid; ELSE ch := ch * 2; lon_max := mid; END IF;
+        ELSE
+            mid := (lat_min + lat_max) / 2;
+            IF lat >= mid THEN ch := ch * 2 + 1; lat_min := mid; ELSE ch := ch * 2; lat_max := mid; END IF;
+        END IF;
+        even := NOT even;
+        nbits := nbits + 1;
+        IF nbits = 5 THEN
+            hash := hash || substr(base32, ch + 1, 1);
+            ch := 0;
+            nbits := 0;
+        END IF;
+    END LOOP;
+    RETURN hash;
+END;
+$$ LANGUAGE plpgsql IMMUTABLE;
+
+UPDATE loc_records SET geohash = locplace_geohash(latitude, longitude, 12);
+DROP FUNCTION locplace_geohash(DOUBLE PRECISION, DOUBLE PRECISION, INT);
+
+ALTER TABLE loc_records ALTER COLUMN geohash SET NOT NULL;
+
+-- text_pattern_ops lets LIKE 'prefix%' use the index regardless of collation
+CREATE INDEX idx_loc_records_geohash ON loc_records (geohash text_pattern_ops);
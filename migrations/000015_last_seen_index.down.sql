@@ -0,0 +1 @@
+DROP INDEX IF EXISTS idx_loc_records_last_seen;
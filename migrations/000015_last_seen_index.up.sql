@@ -0,0 +1,2 @@
+-- Keyset pagination of the records list walks (last_seen_at, fqdn) in either direction
+CREATE INDEX idx_loc_records_last_seen ON loc_records (last_seen_at, fqdn);
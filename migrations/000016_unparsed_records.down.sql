@@ -0,0 +1 @@
+DROP TABLE IF EXISTS unparsed_records;
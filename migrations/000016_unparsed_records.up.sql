@@ -0,0 +1,12 @@
+-- LOC answers the scanner received but could not parse, kept for review so
+-- the parser can be improved. Rows are removed once the FQDN parses.
+CREATE TABLE unparsed_records (
+    fqdn          TEXT PRIMARY KEY,
+    raw_record    TEXT NOT NULL,
+    parse_error   TEXT NOT NULL,
+    occurrences   INT NOT NULL DEFAULT 1,
+    first_seen_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    last_seen_at  TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_unparsed_records_last_seen ON unparsed_records(last_seen_at);
@@ -0,0 +1,9 @@
+-- Side observations about scanned FQDNs (does it resolve, does it have
+-- AAAA/MX records), reported by scanners that collect them.
+CREATE TABLE domain_facts (
+    fqdn       TEXT PRIMARY KEY,
+    resolves   BOOLEAN NOT NULL,
+    has_aaaa   BOOLEAN NOT NULL,
+    has_mx     BOOLEAN NOT NULL,
+    checked_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
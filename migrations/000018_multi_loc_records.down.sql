@@ -0,0 +1,11 @@
+-- Keep only the most recently seen record of each name
+DELETE FROM loc_records l
+USING loc_records newer
+WHERE newer.fqdn = l.fqdn
+  AND (newer.last_seen_at, newer.raw_record) > (l.last_seen_at, l.raw_record);
+
+DROP INDEX IF EXISTS idx_loc_records_last_seen;
+CREATE INDEX idx_loc_records_last_seen ON loc_records (last_seen_at, fqdn);
+
+ALTER TABLE loc_records DROP CONSTRAINT loc_records_fqdn_raw_record_key;
+ALTER TABLE loc_records ADD CONSTRAINT loc_records_fqdn_key UNIQUE (fqdn);
@@ -0,0 +1,8 @@
+-- A name may publish several LOC records (an RRset), so records are keyed by
+-- name and record text instead of by name alone.
+ALTER TABLE loc_records DROP CONSTRAINT loc_records_fqdn_key;
+ALTER TABLE loc_records ADD CONSTRAINT loc_records_fqdn_raw_record_key UNIQUE (fqdn, raw_record);
+
+-- Keyset pagination needs a unique key, so raw_record breaks ties
+DROP INDEX IF EXISTS idx_loc_records_last_seen;
+CREATE INDEX idx_loc_records_last_seen ON loc_records (last_seen_at, fqdn, raw_record);
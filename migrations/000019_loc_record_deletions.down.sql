@@ -0,0 +1 @@
+DROP TABLE IF EXISTS loc_record_deletions;
@@ -0,0 +1,11 @@
+-- Tombstones for LOC records removed from loc_records, so the changes feed
+-- can report removals alongside additions and updates.
+CREATE TABLE loc_record_deletions (
+    id          BIGSERIAL PRIMARY KEY,
+    root_domain TEXT NOT NULL,
+    fqdn        TEXT NOT NULL,
+    raw_record  TEXT NOT NULL,
+    deleted_at  TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_loc_record_deletions_deleted_at ON loc_record_deletions(deleted_at);
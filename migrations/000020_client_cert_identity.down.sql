@@ -0,0 +1 @@
+ALTER TABLE scanner_clients DROP COLUMN IF EXISTS cert_identity;
@@ -0,0 +1,3 @@
+-- Optional TLS client certificate identity (a SAN or the subject CN) that
+-- authenticates a scanner instead of its token when mTLS is enabled.
+ALTER TABLE scanner_clients ADD COLUMN cert_identity TEXT UNIQUE;
@@ -0,0 +1,10 @@
+-- Administrative changes made through the admin API, newest last.
+CREATE TABLE audit_log (
+    id         BIGSERIAL PRIMARY KEY,
+    action     TEXT NOT NULL,
+    target     TEXT NOT NULL,
+    details    JSONB NOT NULL DEFAULT '{}',
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_audit_log_created_at ON audit_log(created_at);
@@ -0,0 +1,3 @@
+-- Identifier of the admin key that made the change (a truncated hash, see
+-- middleware.AdminKeyID); NULL when unknown.
+ALTER TABLE audit_log ADD COLUMN actor TEXT;
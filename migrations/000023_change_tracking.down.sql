@@ -0,0 +1,12 @@
+DROP INDEX IF EXISTS idx_loc_record_deletions_changed;
+CREATE INDEX IF NOT EXISTS idx_loc_record_deletions_deleted_at ON loc_record_deletions(deleted_at);
+ALTER TABLE loc_record_deletions
+    ALTER COLUMN deleted_at SET DEFAULT NOW(),
+    DROP COLUMN IF EXISTS change_id;
+
+DROP INDEX IF EXISTS idx_loc_records_changed;
+ALTER TABLE loc_records
+    DROP COLUMN IF EXISTS change_id,
+    DROP COLUMN IF EXISTS changed_at;
+
+DROP SEQUENCE IF EXISTS loc_change_id_seq;
@@ -0,0 +1,26 @@
+-- Write-time change tracking for GET /api/public/changes. Every write to
+-- loc_records stamps changed_at with the wall clock and takes a change_id
+-- from a sequence shared with loc_record_deletions, so the feed can page
+-- through both tables in (changed_at, change_id) order regardless of the
+-- observation times scanners report.
+CREATE SEQUENCE loc_change_id_seq;
+
+ALTER TABLE loc_records
+    ADD COLUMN changed_at TIMESTAMPTZ,
+    ADD COLUMN change_id  BIGINT;
+UPDATE loc_records SET changed_at = last_seen_at, change_id = nextval('loc_change_id_seq');
+ALTER TABLE loc_records
+    ALTER COLUMN changed_at SET NOT NULL,
+    ALTER COLUMN changed_at SET DEFAULT clock_timestamp(),
+    ALTER COLUMN change_id SET NOT NULL,
+    ALTER COLUMN change_id SET DEFAULT nextval('loc_change_id_seq');
+CREATE INDEX idx_loc_records_changed ON loc_records(changed_at, change_id);
+
+ALTER TABLE loc_record_deletions ADD COLUMN change_id BIGINT;
+UPDATE loc_record_deletions SET change_id = nextval('loc_change_id_seq');
+ALTER TABLE loc_record_deletions
+    ALTER COLUMN deleted_at SET DEFAULT clock_timestamp(),
+    ALTER COLUMN change_id SET NOT NULL,
+    ALTER COLUMN change_id SET DEFAULT nextval('loc_change_id_seq');
+DROP INDEX idx_loc_record_deletions_deleted_at;
+CREATE INDEX idx_loc_record_deletions_changed ON loc_record_deletions(deleted_at, change_id);
@@ -0,0 +1,8 @@
+-- Root domains set by an admin (PATCH /api/admin/records/{fqdn}). Records
+-- stored for the name later take this root domain instead of the one derived
+-- from the public suffix list.
+CREATE TABLE root_domain_overrides (
+    fqdn        TEXT PRIMARY KEY,
+    root_domain TEXT NOT NULL,
+    updated_at  TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
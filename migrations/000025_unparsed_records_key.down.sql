@@ -0,0 +1,7 @@
+-- Keep the most recently seen answer per name
+DELETE FROM unparsed_records u
+USING unparsed_records newer
+WHERE newer.fqdn = u.fqdn
+  AND (newer.last_seen_at, newer.raw_record) > (u.last_seen_at, u.raw_record);
+ALTER TABLE unparsed_records DROP CONSTRAINT unparsed_records_pkey;
+ALTER TABLE unparsed_records ADD PRIMARY KEY (fqdn);
@@ -0,0 +1,5 @@
+-- A name may publish several LOC records (see 000018), and each answer that
+-- fails to parse is kept for review, so entries are keyed by name and
+-- record text instead of by name alone.
+ALTER TABLE unparsed_records DROP CONSTRAINT unparsed_records_pkey;
+ALTER TABLE unparsed_records ADD PRIMARY KEY (fqdn, raw_record);
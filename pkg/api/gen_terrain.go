@@ -0,0 +1,111 @@
+//go:build ignore
+
+// gen_terrain builds terrain/grid.bin.gz, the coarse elevation model behind
+// LOCRecord.AltitudeAnomalyM, from NOAA's ETOPO1 ice surface grid:
+//
+//	https://www.ngdc.noaa.gov/mgg/global/relief/ETOPO1/data/ice_surface/grid_registered/binary/
+//
+// Download and unzip ETOPO1_Ice_g_bin.zip, then run go generate in pkg/api
+// (or go run gen_terrain.go -in ETOPO1_Ice_g_int.bin). The input is
+// 10801 rows of 21601 little-endian int16 elevations in meters, one arc
+// minute apart, from 90°N and 180°W.
+package main
+
+import (
+	"bufio"
+	"compress/gzip"
+	"encoding/binary"
+	"flag"
+	"io"
+	"log"
+	"math"
+	"os"
+)
+
+const (
+	srcRows   = 10801
+	srcCols   = 21601
+	srcPerDeg = 60
+
+	cellDeg = 1
+	rows    = 180 / cellDeg
+	cols    = 360 / cellDeg
+	stepM   = 40
+
+	// marginDeg widens every cell, so a record just across a cell edge still
+	// falls within the terrain it is next to.
+	marginDeg = 0.1
+)
+
+func main() {
+	in := flag.String("in", "ETOPO1_Ice_g_int.bin", "ETOPO1 grid-registered int16 binary")
+	out := flag.String("out", "terrain/grid.bin.gz", "output grid")
+	flag.Parse()
+
+	f, err := os.Open(*in)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer f.Close()
+	r := bufio.NewReaderSize(f, 1<<20)
+
+	lo := make([]float64, rows*cols)
+	hi := make([]float64, rows*cols)
+	for i := range lo {
+		lo[i], hi[i] = math.Inf(1), math.Inf(-1)
+	}
+
+	line := make([]int16, srcCols)
+	for sr := 0; sr < srcRows; sr++ {
+		if err := binary.Read(r, binary.LittleEndian, line); err != nil {
+			log.Fatalf("row %d: %v", sr, err)
+		}
+		lat := 90 - float64(sr)/srcPerDeg
+		rowA, rowB := cellIndex(90-lat-marginDeg, rows), cellIndex(90-lat+marginDeg, rows)
+		for sc, v := range line {
+			lon := -180 + float64(sc)/srcPerDeg
+			elev := math.Max(float64(v), 0) // Water counts as sea level
+			colA, colB := cellIndex(lon+180-marginDeg, cols), cellIndex(lon+180+marginDeg, cols)
+			for _, row := range []int{rowA, rowB} {
+				for _, col := range []int{colA, colB} {
+					i := row*cols + col
+					lo[i], hi[i] = math.Min(lo[i], elev), math.Max(hi[i], elev)
+				}
+			}
+		}
+	}
+	if _, err := r.ReadByte(); err != io.EOF {
+		log.Fatal("input is larger than the ETOPO1 grid")
+	}
+
+	grid := make([]byte, rows*cols*2)
+	for i := range lo {
+		grid[2*i] = byte(math.Floor(lo[i] / stepM))
+		grid[2*i+1] = byte(min(math.Ceil(hi[i]/stepM), 255))
+	}
+
+	o, err := os.Create(*out)
+	if err != nil {
+		log.Fatal(err)
+	}
+	zw, _ := gzip.NewWriterLevel(o, gzip.BestCompression)
+	if _, err := zw.Write(grid); err != nil {
+		log.Fatal(err)
+	}
+	if err := zw.Close(); err != nil {
+		log.Fatal(err)
+	}
+	if err := o.Close(); err != nil {
+		log.Fatal(err)
+	}
+}
+
+// cellIndex returns the cell containing offset degrees from the grid's
+// first edge, wrapping around for longitudes and clamping for latitudes.
+func cellIndex(offset float64, n int) int {
+	i := int(math.Floor(offset / cellDeg))
+	if n == cols {
+		return ((i % n) + n) % n
+	}
+	return min(max(i, 0), n-1)
+}
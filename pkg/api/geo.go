@@ -0,0 +1,126 @@
+package api
+
+import (
+	"fmt"
+	"math"
+)
+
+// EarthRadiusKm is the mean Earth radius used for distance calculations.
+const EarthRadiusKm = 6371.0088
+
+// DistanceKm returns the great-circle (haversine) distance in kilometers
+// between two points given in decimal degrees.
+func DistanceKm(lat1, lon1, lat2, lon2 float64) float64 {
+	const rad = math.Pi / 180
+	dLat := (lat2 - lat1) * rad
+	dLon := (lon2 - lon1) * rad
+	a := math.Sin(dLat/2)*math.Sin(dLat/2) +
+		math.Cos(lat1*rad)*math.Cos(lat2*rad)*math.Sin(dLon/2)*math.Sin(dLon/2)
+	return 2 * EarthRadiusKm * math.Asin(math.Min(1, math.Sqrt(a)))
+}
+
+// WebMercatorMaxLat is the latitude limit of Web Mercator, where the
+// projected map becomes square.
+const WebMercatorMaxLat = 85.05112877980659
+
+// webMercatorRadius is the WGS84 semi-major axis used by EPSG:3857.
+const webMercatorRadius = 6378137.0
+
+// WebMercator projects WGS84 decimal degrees to Web Mercator (EPSG:3857)
+// meters. Latitudes beyond WebMercatorMaxLat are clamped.
+func WebMercator(lat, lon float64) (x, y float64) {
+	lat = math.Max(-WebMercatorMaxLat, math.Min(WebMercatorMaxLat, lat))
+	const rad = math.Pi / 180
+	x = webMercatorRadius * lon * rad
+	y = webMercatorRadius * math.Log(math.Tan(math.Pi/4+lat*rad/2))
+	return x, y
+}
+
+// DistanceKm returns the distance in kilometers from the record's coordinates
+// to the given point.
+func (r LOCRecord) DistanceKm(lat, lon float64) float64 {
+	return DistanceKm(r.Latitude, r.Longitude, lat, lon)
+}
+
+// WithinKm reports whether the record's coordinates are within km kilometers
+// of the given point.
+func (r LOCRecord) WithinKm(lat, lon, km float64) bool {
+	return r.DistanceKm(lat, lon) <= km
+}
+
+// HasMeaningfulAltitude reports whether the altitude was probably set on
+// purpose. An altitude of exactly 0m with the default vertical precision is
+// the usual "didn't bother" value and is treated as unset. This is a
+// heuristic: a few records really are at sea level.
+func (r LOCRecord) HasMeaningfulAltitude() bool {
+	return r.AltitudeM != 0 || r.VertPrecM != DefaultVertPrecM
+}
+
+// Validate reports an error if any numeric field is NaN or infinite, or the
+// coordinates are outside [-90, 90] / [-180, 180]. Such values cannot be
+// encoded as JSON and break the geometry queries.
+func (r LOCRecord) Validate() error {
+	fields := []struct {
+		name string
+		v    float64
+	}{
+		{"latitude", r.Latitude},
+		{"longitude", r.Longitude},
+		{"altitude", r.AltitudeM},
+		{"size", r.SizeM},
+		{"horizontal precision", r.HorizPrecM},
+		{"vertical precision", r.VertPrecM},
+	}
+	for _, f := range fields {
+		if math.IsNaN(f.v) || math.IsInf(f.v, 0) {
+			return fmt.Errorf("%s is not a finite number", f.name)
+		}
+	}
+	if r.Latitude < -90 || r.Latitude > 90 || r.Longitude < -180 || r.Longitude > 180 {
+		return fmt.Errorf("coordinates out of range")
+	}
+	return nil
+}
+
+// Plausibility limits used by PlausibilityWarnings. The altitude range is a
+// little wider than the Mariana Trench to Everest; a size or precision
+// beyond MaxPlausibleExtentM (half the Earth's circumference) says nothing
+// about a location.
+const (
+	MinPlausibleAltitudeM = -11000.0
+	MaxPlausibleAltitudeM = 9000.0
+	MaxPlausibleSizeM     = 10_000_000.0 // Larger than a continent
+	MaxPlausibleExtentM   = 20_000_000.0
+)
+
+// PlausibilityWarnings flags values that are valid LOC but physically
+// implausible, which usually means a formatting bug in the zone file.
+// It returns nil for a plausible record.
+func (r LOCRecord) PlausibilityWarnings() []string {
+	var warnings []string
+	if r.AltitudeM < MinPlausibleAltitudeM || r.AltitudeM > MaxPlausibleAltitudeM {
+		warnings = append(warnings, fmt.Sprintf("altitude %gm is outside the Earth's surface range", r.AltitudeM))
+	}
+	if r.SizeM > MaxPlausibleSizeM {
+		warnings = append(warnings, fmt.Sprintf("size %gm is larger than a continent", r.SizeM))
+	}
+	if r.HorizPrecM == 0 {
+		warnings = append(warnings, "horizontal precision of 0m claims an exact position")
+	}
+	if r.VertPrecM == 0 {
+		warnings = append(warnings, "vertical precision of 0m claims an exact altitude")
+	}
+	if r.HorizPrecM > MaxPlausibleExtentM {
+		warnings = append(warnings, fmt.Sprintf("horizontal precision %gm exceeds half the Earth's circumference", r.HorizPrecM))
+	}
+	if r.VertPrecM > MaxPlausibleExtentM {
+		warnings = append(warnings, fmt.Sprintf("vertical precision %gm exceeds half the Earth's circumference", r.VertPrecM))
+	}
+	return warnings
+}
+
+// SizeRadiusM returns the radius of the sphere enclosing the entity.
+// RFC 1876 defines SIZE as the sphere's diameter, which is often misread as a radius.
+func (r LOCRecord) SizeRadiusM() float64 {
+	return r.SizeM / 2
+}
@@ -0,0 +1,165 @@
+package api
+
+import (
+	"math"
+	"testing"
+)
+
+func TestDistanceKm(t *testing.T) {
+	tests := []struct {
+		name                   string
+		lat1, lon1, lat2, lon2 float64
+		want                   float64
+		tolerance              float64
+	}{
+		{
+			name: "same point",
+			lat1: 52.373, lon1: 4.892, lat2: 52.373, lon2: 4.892,
+			want: 0, tolerance: 1e-9,
+		},
+		{
+			// Amsterdam to Paris, roughly 430 km
+			name: "amsterdam to paris",
+			lat1: 52.3676, lon1: 4.9041, lat2: 48.8566, lon2: 2.3522,
+			want: 430, tolerance: 5,
+		},
+		{
+			// One degree of latitude is ~111.2 km
+			name: "one degree of latitude",
+			lat1: 0, lon1: 0, lat2: 1, lon2: 0,
+			want: 111.19, tolerance: 0.1,
+		},
+		{
+			// Across the antimeridian should take the short way round
+			name: "across antimeridian",
+			lat1: 0, lon1: 179.5, lat2: 0, lon2: -179.5,
+			want: 111.19, tolerance: 0.1,
+		},
+		{
+			name: "antipodal points",
+			lat1: 0, lon1: 0, lat2: 0, lon2: 180,
+			want: math.Pi * EarthRadiusKm, tolerance: 0.01,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := DistanceKm(tt.lat1, tt.lon1, tt.lat2, tt.lon2)
+			if math.Abs(got-tt.want) > tt.tolerance {
+				t.Errorf("DistanceKm() = %v, want %v ± %v", got, tt.want, tt.tolerance)
+			}
+		})
+	}
+}
+
+func TestLOCRecord_WithinKm(t *testing.T) {
+	// nikhef.nl, Amsterdam
+	rec := LOCRecord{Latitude: 52.356, Longitude: 4.951}
+
+	if !rec.WithinKm(52.3676, 4.9041, 10) {
+		t.Error("expected record to be within 10 km of central Amsterdam")
+	}
+	if rec.WithinKm(48.8566, 2.3522, 100) {
+		t.Error("expected record not to be within 100 km of Paris")
+	}
+}
+
+func TestLOCRecord_SizeRadiusM(t *testing.T) {
+	// "1m" SIZE in the presentation format is a 1 m diameter
+	rec := LOCRecord{SizeM: 1}
+	if got := rec.SizeRadiusM(); got != 0.5 {
+		t.Errorf("SizeRadiusM() = %v, want 0.5", got)
+	}
+}
+
+func TestLOCRecord_HasMeaningfulAltitude(t *testing.T) {
+	tests := []struct {
+		name string
+		rec  LOCRecord
+		want bool
+	}{
+		{"zero with default precision", LOCRecord{AltitudeM: 0, VertPrecM: 10}, false},
+		{"zero with explicit precision", LOCRecord{AltitudeM: 0, VertPrecM: 2}, true},
+		{"below sea level", LOCRecord{AltitudeM: -2, VertPrecM: 10}, true},
+		{"mountain", LOCRecord{AltitudeM: 1500, VertPrecM: 10}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.rec.HasMeaningfulAltitude(); got != tt.want {
+				t.Errorf("HasMeaningfulAltitude() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLOCRecord_Validate(t *testing.T) {
+	tests := []struct {
+		name    string
+		rec     LOCRecord
+		wantErr bool
+	}{
+		{"valid", LOCRecord{Latitude: 52.37, Longitude: 4.89, SizeM: 1, HorizPrecM: 10000, VertPrecM: 10}, false},
+		{"poles and date line", LOCRecord{Latitude: -90, Longitude: 180}, false},
+		{"NaN latitude", LOCRecord{Latitude: math.NaN()}, true},
+		{"infinite longitude", LOCRecord{Longitude: math.Inf(-1)}, true},
+		{"infinite altitude", LOCRecord{AltitudeM: math.Inf(1)}, true},
+		{"NaN precision", LOCRecord{VertPrecM: math.NaN()}, true},
+		{"latitude out of range", LOCRecord{Latitude: 90.5}, true},
+		{"longitude out of range", LOCRecord{Longitude: -180.5}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.rec.Validate(); (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestLOCRecord_PlausibilityWarnings(t *testing.T) {
+	plausible := LOCRecord{Latitude: 52.37, Longitude: 4.89, AltitudeM: 12, SizeM: 1, HorizPrecM: 10000, VertPrecM: 10}
+	tests := []struct {
+		name string
+		mod  func(*LOCRecord)
+		want int
+	}{
+		{"plausible", func(*LOCRecord) {}, 0},
+		{"stratospheric altitude", func(r *LOCRecord) { r.AltitudeM, r.VertPrecM = 10000, 0.01 }, 1},
+		{"below the trench", func(r *LOCRecord) { r.AltitudeM = -20000 }, 1},
+		{"everest", func(r *LOCRecord) { r.AltitudeM = 8849 }, 0},
+		{"continent-sized", func(r *LOCRecord) { r.SizeM = 90000000 }, 1},
+		{"zero precision", func(r *LOCRecord) { r.HorizPrecM, r.VertPrecM = 0, 0 }, 2},
+		{"precision beyond the earth", func(r *LOCRecord) { r.HorizPrecM = 90000000 }, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := plausible
+			tt.mod(&rec)
+			if got := rec.PlausibilityWarnings(); len(got) != tt.want {
+				t.Errorf("PlausibilityWarnings() = %q, want %d warnings", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWebMercator(t *testing.T) {
+	tests := []struct {
+		name     string
+		lat, lon float64
+		x, y     float64
+	}{
+		{"origin", 0, 0, 0, 0},
+		{"london", 51.5074, -0.1278, -14226.630923, 6711542.475588},
+		{"sydney", -33.8688, 151.2093, 16832542.279207, -4011198.647308},
+		{"north east corner", WebMercatorMaxLat, 180, 20037508.342789, 20037508.342789},
+		{"pole is clamped", 90, 180, 20037508.342789, 20037508.342789},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			x, y := WebMercator(tt.lat, tt.lon)
+			if math.Abs(x-tt.x) > 1e-3 || math.Abs(y-tt.y) > 1e-3 {
+				t.Errorf("WebMercator(%v, %v) = (%f, %f), want (%f, %f)", tt.lat, tt.lon, x, y, tt.x, tt.y)
+			}
+		})
+	}
+}
@@ -0,0 +1,54 @@
+package api
+
+// geohashBase32 is the geohash alphabet (no a, i, l, o).
+const geohashBase32 = "0123456789bcdefghjkmnpqrstuvwxyz"
+
+// GeohashPrecision is the geohash length stored with each record
+// (cells of a few centimeters).
+const GeohashPrecision = 12
+
+// Geohash encodes a point in decimal degrees as a geohash of the given length
+// (1-12). Nearby points share a prefix, so a prefix match selects a
+// rectangular cell.
+func Geohash(lat, lon float64, precision int) string {
+	precision = max(1, min(precision, 12))
+	latMin, latMax := -90.0, 90.0
+	lonMin, lonMax := -180.0, 180.0
+
+	hash := make([]byte, 0, precision)
+	ch, bit := 0, 0
+	even := true // Bits alternate longitude, latitude
+	for len(hash) < precision {
+		if even {
+			mid := (lonMin + lonMax) / 2
+			if lon >= mid {
+				ch = ch<<1 | 1
+				lonMin = mid
+			} else {
+				ch <<= 1
+				lonMax = mid
+			}
+		} else {
+			mid := (latMin + latMax) / 2
+			if lat >= mid {
+				ch = ch<<1 | 1
+				latMin = mid
+			} else {
+				ch <<= 1
+				latMax = mid
+			}
+		}
+		even = !even
+
+		if bit++; bit == 5 {
+			hash = append(hash, geohashBase32[ch])
+			ch, bit = 0, 0
+		}
+	}
+	return string(hash)
+}
+
+// Geohash returns the geohash of the record's coordinates.
+func (r LOCRecord) Geohash(precision int) string {
+	return Geohash(r.Latitude, r.Longitude, precision)
+}
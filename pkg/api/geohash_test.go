@@ -0,0 +1,32 @@
+package api
+
+import "testing"
+
+func TestGeohash(t *testing.T) {
+	tests := []struct {
+		name      string
+		lat, lon  float64
+		precision int
+		want      string
+	}{
+		// Reference point from the original geohash.org announcement
+		{"jutland", 57.64911, 10.40744, 11, "u4pruydqqvj"},
+		{"origin", 0, 0, 5, "s0000"},
+		{"south west corner", -90, -180, 4, "0000"},
+		{"north east corner", 90, 180, 4, "zzzz"},
+		{"sydney", -33.8688, 151.2093, 6, "r3gx2f"},
+		{"precision clamped", 57.64911, 10.40744, 20, "u4pruydqqvj8"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Geohash(tt.lat, tt.lon, tt.precision); got != tt.want {
+				t.Errorf("Geohash(%v, %v, %d) = %q, want %q", tt.lat, tt.lon, tt.precision, got, tt.want)
+			}
+		})
+	}
+
+	rec := LOCRecord{Latitude: 57.64911, Longitude: 10.40744}
+	if got := rec.Geohash(5); got != "u4pru" {
+		t.Errorf("LOCRecord.Geohash(5) = %q, want u4pru", got)
+	}
+}
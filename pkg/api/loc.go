@@ -0,0 +1,130 @@
+package api
+
+import (
+	"fmt"
+	"math"
+)
+
+// Limits of the RFC 1876 LOC wire format. Altitude is stored in centimeters
+// above a base 100000m below the WGS84 spheroid in an unsigned 32-bit field;
+// size and precisions as a single digit mantissa and a power of ten in
+// centimeters, at most 9e9cm.
+const (
+	MinLOCAltitudeM = -100000.0
+	MaxLOCAltitudeM = 42849672.95
+	MaxLOCExtentM   = 90_000_000.0
+)
+
+// RFC 1876 defaults for omitted size and precision fields.
+const (
+	DefaultSizeM      = 1.0
+	DefaultHorizPrecM = 10000.0
+	DefaultVertPrecM  = 10.0
+)
+
+// milliarcsecPerDegree is the resolution of LOC coordinates on the wire.
+const milliarcsecPerDegree = 3_600_000
+
+// NewLOCRecordFromDecimal builds a record from decimal degrees and meters,
+// as a map click provides them. The values are checked against the LOC
+// wire format and rounded to what it can represent: coordinates to a
+// thousandth of an arcsecond, altitude to a centimeter, and size and
+// precisions to one significant digit. RawRecord is set to String().
+func NewLOCRecordFromDecimal(fqdn string, lat, lon, altM, sizeM, horizM, vertM float64) (LOCRecord, error) {
+	r := LOCRecord{
+		FQDN:       fqdn,
+		Latitude:   lat,
+		Longitude:  lon,
+		AltitudeM:  altM,
+		SizeM:      sizeM,
+		HorizPrecM: horizM,
+		VertPrecM:  vertM,
+	}
+	if err := r.Validate(); err != nil {
+		return LOCRecord{}, err
+	}
+	if altM < MinLOCAltitudeM || altM > MaxLOCAltitudeM {
+		return LOCRecord{}, fmt.Errorf("altitude must be between %gm and %gm", MinLOCAltitudeM, MaxLOCAltitudeM)
+	}
+	extents := []struct {
+		name string
+		v    *float64
+	}{
+		{"size", &r.SizeM},
+		{"horizontal precision", &r.HorizPrecM},
+		{"vertical precision", &r.VertPrecM},
+	}
+	for _, e := range extents {
+		if *e.v < 0 || *e.v > MaxLOCExtentM {
+			return LOCRecord{}, fmt.Errorf("%s must be between 0m and %gm", e.name, MaxLOCExtentM)
+		}
+		*e.v = roundExtent(*e.v)
+	}
+
+	r.Latitude = math.Round(lat*milliarcsecPerDegree) / milliarcsecPerDegree
+	r.Longitude = math.Round(lon*milliarcsecPerDegree) / milliarcsecPerDegree
+	r.AltitudeM = math.Round(altM*100) / 100
+	r.RawRecord = r.String()
+	return r, nil
+}
+
+// roundExtent rounds a size or precision in meters to one significant digit
+// in centimeters, the LOC mantissa/exponent encoding.
+func roundExtent(m float64) float64 {
+	cm := math.Round(m * 100)
+	if cm == 0 {
+		return 0
+	}
+	scale := math.Pow(10, math.Floor(math.Log10(cm)))
+	return math.Min(math.Round(cm/scale)*scale, MaxLOCExtentM*100) / 100
+}
+
+// String returns the record in RFC 1876 presentation format, as zdns prints
+// it: "52 22 23.000 N 4 53 32.000 E -2.00m 1m 10000m 10m". It is built from
+// the parsed fields, so it may be formatted differently from RawRecord.
+func (r LOCRecord) String() string {
+	return fmt.Sprintf("%s %s %.2fm %sm %sm %sm",
+		formatDMS(r.Latitude, "N", "S"), formatDMS(r.Longitude, "E", "W"),
+		r.AltitudeM, formatExtent(r.SizeM), formatExtent(r.HorizPrecM), formatExtent(r.VertPrecM))
+}
+
+// formatDMS formats decimal degrees as "d m s.sss H". Zero counts as the
+// positive hemisphere.
+func formatDMS(v float64, pos, neg string) string {
+	hemi := pos
+	if v < 0 {
+		hemi = neg
+	}
+	mas := int64(math.Round(math.Abs(v) * milliarcsecPerDegree))
+	deg := mas / milliarcsecPerDegree
+	mins := mas % milliarcsecPerDegree / 60_000
+	secs := float64(mas%60_000) / 1000
+	return fmt.Sprintf("%d %d %.3f %s", deg, mins, secs, hemi)
+}
+
+// formatExtent formats a size or precision in meters, with centimeters only
+// when there are any.
+func formatExtent(m float64) string {
+	if m == math.Trunc(m) {
+		return fmt.Sprintf("%.0f", m)
+	}
+	return fmt.Sprintf("%.2f", m)
+}
+
+// Record builds the LOC record for fqdn with NewLOCRecordFromDecimal.
+func (d DecimalLOC) Record(fqdn string) (LOCRecord, error) {
+	if d.Latitude == nil || d.Longitude == nil {
+		return LOCRecord{}, fmt.Errorf("latitude and longitude are required")
+	}
+	size, horiz, vert := DefaultSizeM, DefaultHorizPrecM, DefaultVertPrecM
+	if d.SizeM != nil {
+		size = *d.SizeM
+	}
+	if d.HorizPrecM != nil {
+		horiz = *d.HorizPrecM
+	}
+	if d.VertPrecM != nil {
+		vert = *d.VertPrecM
+	}
+	return NewLOCRecordFromDecimal(fqdn, *d.Latitude, *d.Longitude, d.AltitudeM, size, horiz, vert)
+}
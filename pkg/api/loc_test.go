@@ -0,0 +1,68 @@
+package api
+
+import "testing"
+
+func TestNewLOCRecordFromDecimal(t *testing.T) {
+	tests := []struct {
+		name                             string
+		lat, lon, alt, size, horiz, vert float64
+		wantRaw                          string
+		wantErr                          bool
+	}{
+		{
+			name: "nikhef",
+			lat:  52.37305556, lon: 4.89222222, alt: -2, size: 1, horiz: 10000, vert: 10,
+			wantRaw: "52 22 23.000 N 4 53 32.000 E -2.00m 1m 10000m 10m",
+		},
+		{
+			name: "southern and western",
+			lat:  -33.8568, lon: -70.5, alt: 520.5, size: 0.5, horiz: 30, vert: 2,
+			wantRaw: "33 51 24.480 S 70 30 0.000 W 520.50m 0.50m 30m 2m",
+		},
+		{
+			name: "extents rounded to one digit",
+			lat:  0, lon: 0, alt: 0, size: 14, horiz: 2600, vert: 0.004,
+			wantRaw: "0 0 0.000 N 0 0 0.000 E 0.00m 10m 3000m 0m",
+		},
+		{name: "latitude out of range", lat: 91, wantErr: true},
+		{name: "altitude below base", alt: -100001, wantErr: true},
+		{name: "negative size", size: -1, wantErr: true},
+		{name: "precision too large", horiz: 1e8, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec, err := NewLOCRecordFromDecimal("example.com", tt.lat, tt.lon, tt.alt, tt.size, tt.horiz, tt.vert)
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("expected error, got %q", rec.RawRecord)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if rec.RawRecord != tt.wantRaw || rec.String() != tt.wantRaw {
+				t.Errorf("raw = %q, want %q", rec.RawRecord, tt.wantRaw)
+			}
+			if rec.FQDN != "example.com" {
+				t.Errorf("FQDN = %q, want example.com", rec.FQDN)
+			}
+		})
+	}
+}
+
+func TestDecimalLOC_Record(t *testing.T) {
+	lat, lon := 52.0, 4.0
+	rec, err := DecimalLOC{Latitude: &lat, Longitude: &lon}.Record("example.com")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if rec.SizeM != DefaultSizeM || rec.HorizPrecM != DefaultHorizPrecM || rec.VertPrecM != DefaultVertPrecM {
+		t.Errorf("extents = %g/%g/%g, want RFC 1876 defaults", rec.SizeM, rec.HorizPrecM, rec.VertPrecM)
+	}
+
+	if _, err := (DecimalLOC{Latitude: &lat}).Record("example.com"); err == nil {
+		t.Error("expected error without longitude")
+	}
+}
@@ -0,0 +1,30 @@
+package api
+
+// Pagination is the envelope shared by every paginated list response.
+type Pagination struct {
+	Total      int  `json:"total"`
+	Limit      int  `json:"limit"`
+	Offset     int  `json:"offset"`
+	HasMore    bool `json:"has_more"`
+	NextOffset *int `json:"next_offset"` // null on the last page
+
+	// Keyset cursors, on endpoints that support after/before. Empty when
+	// there is no next or previous page.
+	NextCursor string `json:"next_cursor,omitempty"`
+	PrevCursor string `json:"prev_cursor,omitempty"`
+}
+
+// NewPagination builds the envelope for a page of count items starting at
+// offset out of total.
+func NewPagination(total, limit, offset, count int) Pagination {
+	p := Pagination{
+		Total:  total,
+		Limit:  limit,
+		Offset: offset,
+	}
+	if next := offset + count; count > 0 && next < total {
+		p.HasMore = true
+		p.NextOffset = &next
+	}
+	return p
+}
@@ -0,0 +1,33 @@
+package api
+
+import "testing"
+
+func TestNewPagination(t *testing.T) {
+	tests := []struct {
+		name                        string
+		total, limit, offset, count int
+		wantHasMore                 bool
+		wantNext                    int // -1 = nil
+	}{
+		{name: "first of several pages", total: 250, limit: 100, offset: 0, count: 100, wantHasMore: true, wantNext: 100},
+		{name: "last page", total: 250, limit: 100, offset: 200, count: 50, wantNext: -1},
+		{name: "exact fit", total: 100, limit: 100, offset: 0, count: 100, wantNext: -1},
+		{name: "empty", total: 0, limit: 100, offset: 0, count: 0, wantNext: -1},
+		{name: "offset past end", total: 10, limit: 100, offset: 50, count: 0, wantNext: -1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := NewPagination(tt.total, tt.limit, tt.offset, tt.count)
+			if p.HasMore != tt.wantHasMore {
+				t.Errorf("HasMore = %v, want %v", p.HasMore, tt.wantHasMore)
+			}
+			switch {
+			case tt.wantNext < 0 && p.NextOffset != nil:
+				t.Errorf("NextOffset = %d, want nil", *p.NextOffset)
+			case tt.wantNext >= 0 && (p.NextOffset == nil || *p.NextOffset != tt.wantNext):
+				t.Errorf("NextOffset = %v, want %d", p.NextOffset, tt.wantNext)
+			}
+		})
+	}
+}
@@ -0,0 +1,124 @@
+package api
+
+import (
+	"bytes"
+	"compress/gzip"
+	"embed"
+	"fmt"
+	"io"
+	"math"
+	"sync"
+)
+
+//go:generate go run gen_terrain.go -in ETOPO1_Ice_g_int.bin -out terrain/grid.bin.gz
+
+// The terrain grid backs AltitudeAnomalyM. For every 1° cell it holds the
+// lowest and highest surface elevation found in the cell, with water at sea
+// level, generated from NOAA's ETOPO1 (public domain) by gen_terrain.go.
+// terrain/.gitkeep keeps the pattern matching when the grid was not
+// generated; the check then reports nothing.
+//
+//go:embed terrain/*
+var terrainFS embed.FS
+
+// Layout of terrain/grid.bin.gz once decompressed: terrainRows rows from
+// 90°N southward, each terrainCols cells from 180°W eastward, each cell two
+// bytes, the minimum and maximum elevation in terrainStepM steps. Minima are
+// rounded down and maxima up, so the range only ever widens.
+const (
+	terrainCellDeg = 1
+	terrainRows    = 180 / terrainCellDeg
+	terrainCols    = 360 / terrainCellDeg
+	terrainStepM   = 40
+)
+
+// AltitudeAnomalyThresholdM is how far outside its cell's terrain range a
+// record's altitude must be to count as an anomaly. It covers what the
+// coarse grid can't tell apart from terrain: buildings and masts (up to
+// about 800m), the difference between the WGS84 spheroid LOC uses and the
+// sea-level heights of the model (up to about 100m), and the rounding of the
+// grid.
+const AltitudeAnomalyThresholdM = 1000.0
+
+// terrainGrid is a decoded terrain grid.
+type terrainGrid []byte
+
+// loadTerrain decodes the embedded grid once. It returns nil if no grid is
+// embedded or it is malformed.
+var loadTerrain = sync.OnceValue(func() terrainGrid {
+	data, err := terrainFS.ReadFile("terrain/grid.bin.gz")
+	if err != nil {
+		return nil
+	}
+	grid, err := decodeTerrainGrid(data)
+	if err != nil {
+		return nil
+	}
+	return grid
+})
+
+// decodeTerrainGrid decompresses a grid and checks its size.
+func decodeTerrainGrid(data []byte) (terrainGrid, error) {
+	zr, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	grid, err := io.ReadAll(zr)
+	if err != nil {
+		return nil, err
+	}
+	if len(grid) != terrainRows*terrainCols*2 {
+		return nil, fmt.Errorf("terrain grid has %d bytes, want %d", len(grid), terrainRows*terrainCols*2)
+	}
+	return grid, nil
+}
+
+// TerrainAvailable reports whether an elevation model is embedded, i.e.
+// whether AltitudeAnomalyM can report anything.
+func TerrainAvailable() bool {
+	return loadTerrain() != nil
+}
+
+// rangeAt returns the lowest and highest surface elevation of the cell
+// containing (lat, lon).
+func (g terrainGrid) rangeAt(lat, lon float64) (lo, hi float64) {
+	row := int(math.Floor((90 - lat) / terrainCellDeg))
+	col := int(math.Floor((lon + 180) / terrainCellDeg))
+	row = min(max(row, 0), terrainRows-1) // 90°S falls in the last row
+	col = ((col % terrainCols) + terrainCols) % terrainCols
+	i := (row*terrainCols + col) * 2
+	return float64(g[i]) * terrainStepM, float64(g[i+1]) * terrainStepM
+}
+
+// anomaly returns how far altM lies below (negative) or above (positive)
+// the terrain range at (lat, lon); 0 if within it.
+func (g terrainGrid) anomaly(lat, lon, altM float64) float64 {
+	lo, hi := g.rangeAt(lat, lon)
+	switch {
+	case altM < lo:
+		return altM - lo
+	case altM > hi:
+		return altM - hi
+	}
+	return 0
+}
+
+// AltitudeAnomalyM compares the record's altitude with the embedded terrain
+// model. It returns how many meters the altitude lies below (negative) or
+// above (positive) the range of surface elevations in the record's 1° cell,
+// 0 inside it, and whether that exceeds AltitudeAnomalyThresholdM, e.g. 0m
+// in the Himalayas.
+//
+// The model is coarse: a cell spans about 110km, so only altitudes that
+// fit nowhere in it are caught, and records right at a cell edge are
+// compared with one side only. Water counts as sea level, so lake surfaces,
+// depressions such as the Dead Sea and underwater installations may show
+// small negative deltas. Without an embedded model it returns 0, false.
+func (r LOCRecord) AltitudeAnomalyM() (float64, bool) {
+	grid := loadTerrain()
+	if grid == nil {
+		return 0, false
+	}
+	delta := grid.anomaly(r.Latitude, r.Longitude, r.AltitudeM)
+	return delta, math.Abs(delta) > AltitudeAnomalyThresholdM
+}
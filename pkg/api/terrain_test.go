@@ -0,0 +1,67 @@
+package api
+
+import (
+	"bytes"
+	"compress/gzip"
+	"testing"
+)
+
+// testTerrain returns a grid at sea level everywhere except the given cells.
+func testTerrain(cells map[[2]int][2]byte) terrainGrid {
+	g := make(terrainGrid, terrainRows*terrainCols*2)
+	for rc, v := range cells {
+		i := (rc[0]*terrainCols + rc[1]) * 2
+		g[i], g[i+1] = v[0], v[1]
+	}
+	return g
+}
+
+func TestTerrainAnomaly(t *testing.T) {
+	g := testTerrain(map[[2]int][2]byte{
+		{62, 266}: {75, 200}, // 27-28°N 86-87°E: 3000m to 8000m
+		{0, 0}:    {0, 50},   // 89-90°N 180-179°W
+	})
+
+	tests := []struct {
+		name     string
+		lat, lon float64
+		alt      float64
+		want     float64
+	}{
+		{name: "sea level in the Himalayas", lat: 27.99, lon: 86.93, alt: 0, want: -3000},
+		{name: "within the range", lat: 27.5, lon: 86.5, alt: 5364, want: 0},
+		{name: "above the peaks", lat: 27.5, lon: 86.5, alt: 9000, want: 1000},
+		{name: "ocean", lat: 10, lon: -30, alt: 0, want: 0},
+		{name: "in the air over the ocean", lat: 10, lon: -30, alt: 1500, want: 1500},
+		{name: "north pole", lat: 90, lon: -180, alt: 2500, want: 500},
+		{name: "180E wraps to 180W", lat: 89.5, lon: 180, alt: 2500, want: 500},
+		{name: "south pole", lat: -90, lon: 0, alt: 0, want: 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := g.anomaly(tt.lat, tt.lon, tt.alt); got != tt.want {
+				t.Errorf("anomaly(%v, %v, %v) = %v, want %v", tt.lat, tt.lon, tt.alt, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDecodeTerrainGrid(t *testing.T) {
+	compress := func(b []byte) []byte {
+		var buf bytes.Buffer
+		zw := gzip.NewWriter(&buf)
+		_, _ = zw.Write(b)
+		_ = zw.Close()
+		return buf.Bytes()
+	}
+
+	if _, err := decodeTerrainGrid(compress(testTerrain(nil))); err != nil {
+		t.Errorf("full grid: %v", err)
+	}
+	if _, err := decodeTerrainGrid(compress(make([]byte, 100))); err == nil {
+		t.Error("short grid: expected error")
+	}
+	if _, err := decodeTerrainGrid([]byte("not gzip")); err == nil {
+		t.Error("not gzip: expected error")
+	}
+}
@@ -1,13 +1,20 @@
 // Package api contains shared types for the coordinator API.
 package api
 
-import "time"
+import (
+	"encoding/json"
+	"time"
+)
 
 // --- Admin API Types ---
 
 // RegisterClientRequest is the request body for POST /api/admin/clients.
 type RegisterClientRequest struct {
 	Name string `json:"name"`
+	// CertIdentity is a TLS client certificate identity (a DNS, URI or
+	// email SAN, or the subject CN) that authenticates the client when the
+	// coordinator runs with SCANNER_AUTH=mtls or any.
+	CertIdentity string `json:"cert_identity,omitempty"`
 }
 
 // RegisterClientResponse is the response for POST /api/admin/clients.
@@ -25,6 +32,10 @@ type ClientInfo struct {
 	LastHeartbeat *time.Time `json:"last_heartbeat,omitempty"`
 	ActiveBatches int        `json:"active_batches"`
 	IsAlive       bool       `json:"is_alive"`
+	Version       string     `json:"version,omitempty"`      // Last reported scanner version
+	Capabilities  []string   `json:"capabilities,omitempty"` // Last reported scanner capabilities
+	// CertIdentity is the client certificate identity used for mTLS, if set.
+	CertIdentity string `json:"cert_identity,omitempty"`
 }
 
 // ListClientsResponse is the response for GET /api/admin/clients.
@@ -32,16 +43,120 @@ type ListClientsResponse struct {
 	Clients []ClientInfo `json:"clients"`
 }
 
+// PruneClientsRequest is the request body for POST /api/admin/clients/prune.
+type PruneClientsRequest struct {
+	OlderThan string `json:"older_than"` // Go duration, e.g. "720h"
+	DryRun    bool   `json:"dry_run"`
+}
+
+// PruneClientsResponse is the response for POST /api/admin/clients/prune.
+// With DryRun, ClientIDs are the clients that would have been deleted.
+type PruneClientsResponse struct {
+	ClientIDs []string `json:"client_ids"`
+	DryRun    bool     `json:"dry_run"`
+}
+
+// MaintenanceMode is the request and response body for
+// GET/PUT /api/admin/maintenance.
+type MaintenanceMode struct {
+	Enabled bool `json:"enabled"`
+}
+
+// ReparseResponse is the response for POST /api/admin/reparse.
+type ReparseResponse struct {
+	Checked      int `json:"checked"`       // Stored records re-parsed
+	Changed      int `json:"changed"`       // Stored records whose parsed fields were updated
+	Failing      int `json:"failing"`       // Stored records the current parser rejects (left unchanged)
+	Fixed        int `json:"fixed"`         // Unparsed answers that now parse and were stored
+	Rejected     int `json:"rejected"`      // Unparsed answers that now parse but the TLD or precision filter rejects
+	StillFailing int `json:"still_failing"` // Unparsed answers that still fail
+}
+
+// UpdateRecordRequest is the request body for PATCH /api/admin/records/{fqdn}.
+// Only the attribution can be corrected; the LOC fields come from scanning.
+type UpdateRecordRequest struct {
+	RootDomain string `json:"root_domain"`
+}
+
+// SchemaVersion is the response for GET /api/admin/schema.
+type SchemaVersion struct {
+	Version  uint `json:"version"` // Applied migration, 0 before the first
+	Dirty    bool `json:"dirty"`   // The last migration failed halfway
+	Latest   uint `json:"latest"`  // Newest migration embedded in this build
+	UpToDate bool `json:"up_to_date"`
+}
+
 // DiscoverFilesResponse is the response for POST /api/admin/discover-files.
 type DiscoverFilesResponse struct {
 	FilesDiscovered int `json:"files_discovered"`
 }
 
+// CachePurgeResponse is the response for POST /api/admin/cache/purge.
+// Purged names the caches that were dropped (empty if none is enabled).
+type CachePurgeResponse struct {
+	Purged []string `json:"purged"`
+}
+
 // ResetScanResponse is the response for POST /api/admin/reset-scan.
 type ResetScanResponse struct {
 	FilesReset int `json:"files_reset"`
 }
 
+// ScanErrorInfo is a failing FQDN in the admin scan errors list.
+type ScanErrorInfo struct {
+	FQDN          string    `json:"fqdn"`
+	Class         string    `json:"class"`
+	LastError     string    `json:"last_error"`
+	ScanAttempts  int       `json:"scan_attempts"`
+	FirstFailedAt time.Time `json:"first_failed_at"`
+	LastFailedAt  time.Time `json:"last_failed_at"`
+}
+
+// ListScanErrorsResponse is the response for GET /api/admin/scan-errors.
+type ListScanErrorsResponse struct {
+	Errors  []ScanErrorInfo `json:"errors"`
+	ByClass map[string]int  `json:"by_class"`
+	Pagination
+}
+
+// UnparsedRecord is a LOC answer that failed to parse, in the admin list.
+type UnparsedRecord struct {
+	FQDN        string    `json:"fqdn"`
+	RawRecord   string    `json:"raw_record"`
+	ParseError  string    `json:"parse_error"`
+	Occurrences int       `json:"occurrences"`
+	FirstSeenAt time.Time `json:"first_seen_at"`
+	LastSeenAt  time.Time `json:"last_seen_at"`
+}
+
+// ListUnparsedRecordsResponse is the response for GET /api/admin/unparsed-records.
+type ListUnparsedRecordsResponse struct {
+	Records []UnparsedRecord `json:"records"`
+	Pagination
+}
+
+// AuditEntry is an administrative change in GET /api/admin/audit.
+type AuditEntry struct {
+	ID        int64           `json:"id"`
+	Action    string          `json:"action"`           // e.g. "client.create"
+	Target    string          `json:"target,omitempty"` // What was changed, e.g. a client ID or FQDN
+	Details   json.RawMessage `json:"details"`
+	Actor     string          `json:"actor,omitempty"` // Hashed identifier of the admin key used
+	CreatedAt time.Time       `json:"created_at"`
+}
+
+// ListAuditResponse is the response for GET /api/admin/audit.
+type ListAuditResponse struct {
+	Entries []AuditEntry `json:"entries"`
+	Pagination
+}
+
+// RescanFileResponse is the response for POST /api/admin/files/{id}/rescan.
+type RescanFileResponse struct {
+	FileID       int   `json:"file_id"`
+	DomainsReset int64 `json:"domains_reset"` // Lines already fed from the file, now queued again
+}
+
 // ManualScanRequest is the request body for POST /api/admin/manual-scan.
 type ManualScanRequest struct {
 	Domains []string `json:"domains"`
@@ -57,6 +172,10 @@ type ManualScanResponse struct {
 // GetBatchRequest is the request body for POST /api/scanner/jobs.
 type GetBatchRequest struct {
 	SessionID string `json:"session_id"`
+	// ResumeSessionID is the session ID used by this scanner before a restart.
+	// While that session still holds leased batches, they are handed over to
+	// SessionID one per request instead of claiming new ones.
+	ResumeSessionID string `json:"resume_session_id,omitempty"`
 }
 
 // GetBatchResponse is the response for POST /api/scanner/jobs.
@@ -64,13 +183,27 @@ type GetBatchRequest struct {
 type GetBatchResponse struct {
 	BatchID int64    `json:"batch_id,omitempty"`
 	Domains []string `json:"domains"`
+	// Resumed is set when the batch was leased to ResumeSessionID. Once it
+	// isn't, the previous session has nothing left to resume.
+	Resumed bool `json:"resumed,omitempty"`
 }
 
 // HeartbeatRequest is the request body for POST /api/scanner/heartbeat.
 type HeartbeatRequest struct {
-	SessionID string `json:"session_id"`
+	SessionID    string   `json:"session_id"`
+	Version      string   `json:"version,omitempty"`
+	Capabilities []string `json:"capabilities,omitempty"` // Capability* values
 }
 
+// Scanner capabilities reported in HeartbeatRequest.Capabilities.
+const (
+	CapabilityCNAMEChain     = "cname_chain"     // Follows CNAMEs and reports LOCRecord.CNAMEChain
+	CapabilityScanErrors     = "scan_errors"     // Reports SubmitBatchRequest.ScanErrors
+	CapabilityParseErrors    = "parse_errors"    // Reports SubmitBatchRequest.ParseErrors
+	CapabilityIdempotencyKey = "idempotency_key" // Sends Idempotency-Key on result submissions
+	CapabilityDomainFacts    = "domain_facts"    // Reports SubmitBatchRequest.DomainFacts
+)
+
 // HeartbeatResponse is the response for POST /api/scanner/heartbeat.
 type HeartbeatResponse struct {
 	OK bool `json:"ok"`
@@ -78,26 +211,116 @@ type HeartbeatResponse struct {
 
 // LOCRecord represents a discovered LOC record.
 type LOCRecord struct {
-	FQDN       string  `json:"fqdn"`
-	RawRecord  string  `json:"raw_record"`
-	Latitude   float64 `json:"latitude"`
-	Longitude  float64 `json:"longitude"`
-	AltitudeM  float64 `json:"altitude_m"`
-	SizeM      float64 `json:"size_m"`
+	FQDN      string  `json:"fqdn"`
+	RawRecord string  `json:"raw_record"`
+	Latitude  float64 `json:"latitude"`
+	Longitude float64 `json:"longitude"`
+	AltitudeM float64 `json:"altitude_m"`
+	// SizeM is the DIAMETER of a sphere enclosing the described entity
+	// (RFC 1876 "SIZE"), not a radius. Use SizeRadiusM when drawing circles.
+	SizeM float64 `json:"size_m"`
+	// HorizPrecM and VertPrecM are the diameters of the circle/cylinder of
+	// error (RFC 1876 "HORIZ PRE"/"VERT PRE").
 	HorizPrecM float64 `json:"horiz_prec_m"`
 	VertPrecM  float64 `json:"vert_prec_m"`
+	// CNAMEChain lists the CNAME targets followed from FQDN to the name that
+	// holds the LOC record (the last entry). Empty for direct records.
+	CNAMEChain []string `json:"cname_chain,omitempty"`
+	// ObservedAt is when the scanner received the record. Scanners may submit
+	// long after looking up, so this is used for first/last seen instead of
+	// the submission time when set.
+	ObservedAt *time.Time `json:"observed_at,omitempty"`
 }
 
+// ScannerAPIVersionHeader carries the SubmitBatchRequest payload version.
+// Scanners that don't send it are treated as version 1.
+const ScannerAPIVersionHeader = "X-Scanner-API-Version"
+
+// ScannerAPIVersion is the current SubmitBatchRequest payload version.
+//
+//	1  batch_id, domains_checked, loc_records
+//	2  adds scan_errors, parse_errors and keep_best_precision
+const ScannerAPIVersion = 2
+
 // SubmitBatchRequest is the request body for POST /api/scanner/results.
 type SubmitBatchRequest struct {
 	BatchID        int64       `json:"batch_id"`
 	DomainsChecked int         `json:"domains_checked"`
 	LOCRecords     []LOCRecord `json:"loc_records"`
+	// ScanErrors lists FQDNs whose lookup failed (SERVFAIL, timeout, ...),
+	// so they can be told apart from FQDNs without a LOC record.
+	ScanErrors []ScanError `json:"scan_errors,omitempty"`
+	// ParseErrors lists LOC answers the scanner received but could not parse.
+	// Parsing happens on the scanner; the coordinator only aggregates outcomes.
+	ParseErrors []ParseError `json:"parse_errors,omitempty"`
+	// KeepBestPrecision prevents these records from replacing stored data
+	// with lower precision (larger horiz/vert precision values).
+	KeepBestPrecision bool `json:"keep_best_precision,omitempty"`
+	// ReplaceIfNewer relaxes KeepBestPrecision: lower precision records
+	// still replace stored data last seen before they were observed.
+	ReplaceIfNewer bool `json:"replace_if_newer,omitempty"`
+	// DomainFacts are optional side observations for FQDNs whose lookups
+	// succeeded, sent by scanners with CapabilityDomainFacts.
+	DomainFacts []DomainFacts `json:"domain_facts,omitempty"`
+}
+
+// DomainFacts are lightweight observations about an FQDN, collected next to
+// its LOC lookup to tell live sites from dead names.
+type DomainFacts struct {
+	FQDN     string `json:"fqdn"`
+	Resolves bool   `json:"resolves"` // Has A or AAAA records
+	HasAAAA  bool   `json:"has_aaaa"`
+	HasMX    bool   `json:"has_mx"`
+
+	CheckedAt *time.Time `json:"checked_at,omitempty"` // Set by the coordinator
+}
+
+// Scan error classes reported in ScanError.Class.
+const (
+	ScanErrorTimeout  = "timeout"
+	ScanErrorServFail = "servfail"
+	ScanErrorRefused  = "refused"
+	ScanErrorOther    = "other"
+)
+
+// ScanErrorClasses lists all valid scan error classes.
+var ScanErrorClasses = []string{ScanErrorTimeout, ScanErrorServFail, ScanErrorRefused, ScanErrorOther}
+
+// ScanError reports a lookup that failed to resolve.
+type ScanError struct {
+	FQDN    string `json:"fqdn"`
+	Class   string `json:"class"`
+	Message string `json:"message,omitempty"`
+}
+
+// ParseError reports a LOC record that could not be turned into an LOCRecord.
+type ParseError struct {
+	FQDN      string `json:"fqdn"`
+	RawRecord string `json:"raw_record,omitempty"`
+	Message   string `json:"message"`
+}
+
+// ParseStats is the parse outcome of a submitted batch. Failed includes both
+// scanner-side parse failures and records rejected by the coordinator.
+type ParseStats struct {
+	Parsed int          `json:"parsed"`
+	Failed int          `json:"failed"`
+	Errors []ParseError `json:"errors,omitempty"`
 }
 
 // SubmitBatchResponse is the response for POST /api/scanner/results.
 type SubmitBatchResponse struct {
-	Accepted int `json:"accepted"`
+	Accepted int        `json:"accepted"`
+	Parse    ParseStats `json:"parse"`
+	// Rejected lists records the coordinator does not store by policy,
+	// such as names outside its TLD allowlist or too imprecise records.
+	Rejected []RejectedRecord `json:"rejected,omitempty"`
+}
+
+// RejectedRecord is a submitted LOC record that was refused, and why.
+type RejectedRecord struct {
+	FQDN   string `json:"fqdn"`
+	Reason string `json:"reason"`
 }
 
 // --- Public API Types ---
@@ -110,11 +333,45 @@ type PublicLOCRecord struct {
 	Latitude    float64   `json:"latitude"`
 	Longitude   float64   `json:"longitude"`
 	AltitudeM   float64   `json:"altitude_m"`
-	SizeM       float64   `json:"size_m"`
+	SizeM       float64   `json:"size_m"` // Diameter; kept for compatibility, same as SizeDiameterM
 	HorizPrecM  float64   `json:"horiz_prec_m"`
 	VertPrecM   float64   `json:"vert_prec_m"`
 	FirstSeenAt time.Time `json:"first_seen_at"`
 	LastSeenAt  time.Time `json:"last_seen_at"`
+
+	// SizeDiameterM is the diameter of the sphere enclosing the entity (RFC 1876).
+	SizeDiameterM float64 `json:"size_diameter_m"`
+	// SizeRadiusM is half of SizeDiameterM, for rendering as a circle radius.
+	SizeRadiusM float64 `json:"size_radius_m"`
+
+	CNAMEChain []string `json:"cname_chain,omitempty"` // See LOCRecord.CNAMEChain
+	Geohash    string   `json:"geohash"`               // GeohashPrecision characters
+
+	// AgeSeconds is the time since LastSeenAt when the response was made.
+	AgeSeconds int64 `json:"age_seconds"`
+	// IsStale is set once the record hasn't been seen for longer than the
+	// coordinator's staleness threshold (STALE_AFTER).
+	IsStale bool `json:"is_stale"`
+
+	// AltitudeAnomaly is set on GET /api/public/records/{fqdn} with
+	// terrain=true.
+	AltitudeAnomaly *AltitudeAnomaly `json:"altitude_anomaly,omitempty"`
+}
+
+// Record returns the underlying LOCRecord fields, so LOCRecord helpers can be
+// used on public records.
+func (r PublicLOCRecord) Record() LOCRecord {
+	return LOCRecord{
+		FQDN:       r.FQDN,
+		RawRecord:  r.RawRecord,
+		Latitude:   r.Latitude,
+		Longitude:  r.Longitude,
+		AltitudeM:  r.AltitudeM,
+		SizeM:      r.SizeM,
+		HorizPrecM: r.HorizPrecM,
+		VertPrecM:  r.VertPrecM,
+		CNAMEChain: r.CNAMEChain,
+	}
 }
 
 // AggregatedLocation represents multiple LOC records at the same coordinates.
@@ -126,6 +383,7 @@ type AggregatedLocation struct {
 	Latitude    float64   `json:"latitude"`
 	Longitude   float64   `json:"longitude"`
 	AltitudeM   float64   `json:"altitude_m"`
+	SizeM       float64   `json:"size_m"` // Diameter (RFC 1876)
 	Count       int       `json:"count"`
 	FirstSeenAt time.Time `json:"first_seen_at"`
 	LastSeenAt  time.Time `json:"last_seen_at"`
@@ -134,9 +392,140 @@ type AggregatedLocation struct {
 // ListRecordsResponse is the response for GET /api/public/records.
 type ListRecordsResponse struct {
 	Records []PublicLOCRecord `json:"records"`
-	Total   int               `json:"total"`
-	Limit   int               `json:"limit"`
-	Offset  int               `json:"offset"`
+	Pagination
+}
+
+// RootDomainCount is a root domain and the number of LOC records under it.
+type RootDomainCount struct {
+	RootDomain  string `json:"root_domain"`
+	RecordCount int    `json:"record_count"`
+}
+
+// ListRootDomainsResponse is the response for GET /api/public/root-domains.
+type ListRootDomainsResponse struct {
+	RootDomains []RootDomainCount `json:"root_domains"`
+	Pagination
+}
+
+// BoundsResponse is the response for GET /api/public/bounds.
+// With no matching records, Count is 0 and the bounds cover the whole world
+// at altitude 0.
+type BoundsResponse struct {
+	Count        int     `json:"count"`
+	MinLatitude  float64 `json:"min_latitude"`
+	MaxLatitude  float64 `json:"max_latitude"`
+	MinLongitude float64 `json:"min_longitude"`
+	MaxLongitude float64 `json:"max_longitude"`
+	MinAltitudeM float64 `json:"min_altitude_m"`
+	MaxAltitudeM float64 `json:"max_altitude_m"`
+}
+
+// ParseRecordRequest is the request body for POST /api/public/parse.
+// The response is the parsed LOCRecord. Give either Raw or Decimal; with
+// Decimal the response's raw_record is the generated LOC text.
+type ParseRecordRequest struct {
+	FQDN    string      `json:"fqdn"`
+	Raw     string      `json:"raw,omitempty"`
+	Decimal *DecimalLOC `json:"decimal,omitempty"`
+}
+
+// DecimalLOC describes a LOC record in decimal degrees and meters, see
+// NewLOCRecordFromDecimal. Omitted size and precisions take the RFC 1876
+// defaults; altitude defaults to 0m.
+type DecimalLOC struct {
+	Latitude   *float64 `json:"latitude"`
+	Longitude  *float64 `json:"longitude"`
+	AltitudeM  float64  `json:"altitude_m"`
+	SizeM      *float64 `json:"size_m,omitempty"`
+	HorizPrecM *float64 `json:"horiz_prec_m,omitempty"`
+	VertPrecM  *float64 `json:"vert_prec_m,omitempty"`
+}
+
+// ParseRecordResponse is the response for POST /api/public/parse.
+type ParseRecordResponse struct {
+	LOCRecord
+	// Warnings lists LOCRecord.PlausibilityWarnings when requested with
+	// warnings=true.
+	Warnings []string `json:"warnings,omitempty"`
+	// AltitudeAnomaly is set when requested with terrain=true.
+	AltitudeAnomaly *AltitudeAnomaly `json:"altitude_anomaly,omitempty"`
+}
+
+// AltitudeAnomaly compares a record's altitude with the embedded terrain
+// model (see LOCRecord.AltitudeAnomalyM).
+type AltitudeAnomaly struct {
+	// DeltaM is how far the altitude lies below (negative) or above the
+	// surface elevations of the record's 1° cell; 0 within them.
+	DeltaM float64 `json:"delta_m"`
+	// Anomalous is set when DeltaM exceeds AltitudeAnomalyThresholdM.
+	Anomalous bool `json:"anomalous"`
+}
+
+// VerifyLocationResponse is the response for GET /api/public/records/{fqdn}/verify.
+type VerifyLocationResponse struct {
+	FQDN             string  `json:"fqdn"`
+	Latitude         float64 `json:"latitude"`
+	Longitude        float64 `json:"longitude"`
+	ClaimedLatitude  float64 `json:"claimed_latitude"`
+	ClaimedLongitude float64 `json:"claimed_longitude"`
+	DistanceKm       float64 `json:"distance_km"`
+	ThresholdKm      float64 `json:"threshold_km"`
+	Within           bool    `json:"within"`
+}
+
+// SimilarRecord is a record near another one, with the distance between them.
+type SimilarRecord struct {
+	PublicLOCRecord
+	DistanceM float64 `json:"distance_m"`
+}
+
+// CountRecordsResponse is the response for GET /api/public/records?count_only=true.
+type CountRecordsResponse struct {
+	Total int `json:"total"`
+}
+
+// ChangesResponse is the response for GET /api/public/changes.
+// Records written since the page's position are split into Added (first
+// seen after Since) and Updated (seen again or edited, possibly unchanged);
+// Removed lists records deleted since then that have not reappeared. Pass
+// NextCursor as the cursor of the next call; HasMore means more changes are
+// ready right away.
+type ChangesResponse struct {
+	Since      time.Time         `json:"since"`
+	Added      []PublicLOCRecord `json:"added"`
+	Updated    []PublicLOCRecord `json:"updated"`
+	Removed    []RemovedRecord   `json:"removed"`
+	NextCursor string            `json:"next_cursor"`
+	HasMore    bool              `json:"has_more"`
+}
+
+// RemovedRecord is a LOC record that was removed from the dataset.
+type RemovedRecord struct {
+	FQDN       string    `json:"fqdn"`
+	RootDomain string    `json:"root_domain"`
+	RawRecord  string    `json:"raw_record"`
+	RemovedAt  time.Time `json:"removed_at"`
+}
+
+// RecentRecordsResponse is the response for GET /api/public/records/recent.
+type RecentRecordsResponse struct {
+	Records []PublicLOCRecord `json:"records"`
+}
+
+// RecordDetailResponse is the response for GET /api/public/records/{fqdn}.
+// A name may publish several LOC records; they are listed most precise first.
+type RecordDetailResponse struct {
+	FQDN    string            `json:"fqdn"`
+	Records []PublicLOCRecord `json:"records"`
+}
+
+// SimilarRecordsResponse is the response for GET /api/public/records/{fqdn}/similar.
+type SimilarRecordsResponse struct {
+	FQDN       string          `json:"fqdn"`
+	Latitude   float64         `json:"latitude"`
+	Longitude  float64         `json:"longitude"`
+	ToleranceM float64         `json:"tolerance_m"`
+	Records    []SimilarRecord `json:"records"`
 }
 
 // DomainFileStats holds statistics for domain file processing.
@@ -178,6 +567,70 @@ type StatsResponse struct {
 	CurrentFile *CurrentFileProgress `json:"current_file,omitempty"`
 }
 
+// StatsSummaryResponse is the response for GET /api/public/stats/summary.
+type StatsSummaryResponse struct {
+	TotalLOCRecords int `json:"total_loc_records"`
+	ActiveScanners  int `json:"active_scanners"`
+}
+
+// Domain statuses reported in DomainStatusResponse.Status.
+const (
+	DomainStatusHasLOC = "has_loc" // At least one LOC record is stored
+	DomainStatusQueued = "queued"  // Waiting in, or being scanned in, a batch
+	DomainStatusError  = "error"   // The last lookup failed (see ErrorClass)
+	// DomainStatusNoLOC means no LOC record and no failed lookup are known.
+	// Completed scans aren't kept per FQDN, so this covers both "scanned,
+	// nothing found" and "not in the scan list".
+	DomainStatusNoLOC = "no_loc"
+)
+
+// DomainStatusResponse is the response for GET /api/public/domains/{domain}/status.
+// The domain matches records whose root domain or FQDN equals it.
+type DomainStatusResponse struct {
+	Domain       string     `json:"domain"`
+	Status       string     `json:"status"` // DomainStatus* value
+	RecordCount  int        `json:"record_count"`
+	FirstSeenAt  *time.Time `json:"first_seen_at,omitempty"` // Earliest first sighting of any record
+	LastSeenAt   *time.Time `json:"last_seen_at,omitempty"`  // Latest sighting of any record
+	ErrorClass   string     `json:"error_class,omitempty"`   // ScanError* class of the failed lookup
+	LastFailedAt *time.Time `json:"last_failed_at,omitempty"`
+	// Facts are the latest DomainFacts for the exact name, when a scanner
+	// collecting them has looked it up.
+	Facts *DomainFacts `json:"facts,omitempty"`
+}
+
+// RecordsPerDomainBucket counts root domains whose record count falls in
+// [Min, Max]. Max is null for the open-ended last bucket.
+type RecordsPerDomainBucket struct {
+	Label   string `json:"label"` // e.g. "2-5" or "21+"
+	Min     int    `json:"min"`
+	Max     *int   `json:"max"`
+	Domains int    `json:"domains"`
+}
+
+// RecordsPerDomainResponse is the response for
+// GET /api/public/stats/records-per-domain.
+type RecordsPerDomainResponse struct {
+	TotalDomains int                      `json:"total_domains"`
+	Buckets      []RecordsPerDomainBucket `json:"buckets"`
+}
+
+// DensityCell is a grid cell of GET /api/public/stats/density. Bounds are
+// in degrees; cells at the poles and the antimeridian are cut off there.
+type DensityCell struct {
+	MinLat float64 `json:"min_lat"`
+	MinLon float64 `json:"min_lon"`
+	MaxLat float64 `json:"max_lat"`
+	MaxLon float64 `json:"max_lon"`
+	Count  int     `json:"count"`
+}
+
+// DensityResponse is the response for GET /api/public/stats/density.
+type DensityResponse struct {
+	Grid  float64       `json:"grid"`  // Cell size in degrees
+	Cells []DensityCell `json:"cells"` // Densest first
+}
+
 // ErrorResponse is a standard error response.
 type ErrorResponse struct {
 	Error string `json:"error"`
@@ -188,14 +641,38 @@ type ErrorResponse struct {
 // GeoJSONFeatureCollection is a GeoJSON FeatureCollection.
 type GeoJSONFeatureCollection struct {
 	Type     string           `json:"type"` // Always "FeatureCollection"
+	CRS      *GeoJSONCRS      `json:"crs,omitempty"`
 	Features []GeoJSONFeature `json:"features"`
 }
 
+// GeoJSONCRS is a named coordinate reference system, in the 2008 GeoJSON
+// format. RFC 7946 dropped it (coordinates are always WGS84), so it is only
+// set for non-WGS84 output.
+type GeoJSONCRS struct {
+	Type       string            `json:"type"` // Always "name"
+	Properties map[string]string `json:"properties"`
+}
+
 // GeoJSONFeature is a GeoJSON Feature with Point geometry.
 type GeoJSONFeature struct {
-	Type       string         `json:"type"` // Always "Feature"
-	Geometry   GeoJSONPoint   `json:"geometry"`
-	Properties map[string]any `json:"properties"`
+	Type       string               `json:"type"` // Always "Feature"
+	Geometry   GeoJSONPoint         `json:"geometry"`
+	Properties LOCFeatureProperties `json:"properties"`
+}
+
+// LOCFeatureProperties are the properties of a records.geojson feature,
+// describing all records at one location (see AggregatedLocation).
+// Timestamps are RFC 3339 in UTC.
+type LOCFeatureProperties struct {
+	FQDNs         []string  `json:"fqdns"`
+	RootDomains   []string  `json:"root_domains"`
+	RawRecord     string    `json:"raw_record"`
+	AltitudeM     float64   `json:"altitude_m"`
+	SizeDiameterM float64   `json:"size_diameter_m"`
+	SizeRadiusM   float64   `json:"size_radius_m"`
+	Count         int       `json:"count"`
+	FirstSeen     time.Time `json:"first_seen"`
+	LastSeen      time.Time `json:"last_seen"`
 }
 
 // GeoJSONPoint is a GeoJSON Point geometry.
@@ -0,0 +1,245 @@
+// Package client provides a typed Go client for the coordinator HTTP API.
+//
+// Public endpoints need no credentials. Admin endpoints require AdminKey to be
+// set; it is sent as the X-Admin-Key header.
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/locplace/scanner/pkg/api"
+)
+
+// Client is an HTTP client for the coordinator API.
+type Client struct {
+	BaseURL    string
+	AdminKey   string // Optional: required for admin endpoints
+	HTTPClient *http.Client
+}
+
+// New creates a client for the coordinator at baseURL (e.g. "https://loc.place").
+func New(baseURL string) *Client {
+	return &Client{
+		BaseURL: strings.TrimSuffix(baseURL, "/"),
+		HTTPClient: &http.Client{
+			Timeout: 30 * time.Second,
+		},
+	}
+}
+
+// Error is returned when the coordinator responds with a non-2xx status.
+type Error struct {
+	StatusCode int
+	Message    string
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("coordinator returned %d: %s", e.StatusCode, e.Message)
+}
+
+// RecordQuery holds the filters and pagination for ListRecords.
+type RecordQuery struct {
+	Limit  int // 0 = server default
+	Offset int
+	Domain string // Exact root domain
+
+	// Params holds any additional filter parameters (e.g. "bbox", "max_horiz_prec_m").
+	Params url.Values
+}
+
+func (q RecordQuery) values() url.Values {
+	v := url.Values{}
+	for key, vals := range q.Params {
+		v[key] = append([]string(nil), vals...)
+	}
+	if q.Limit > 0 {
+		v.Set("limit", strconv.Itoa(q.Limit))
+	}
+	if q.Offset > 0 {
+		v.Set("offset", strconv.Itoa(q.Offset))
+	}
+	if q.Domain != "" {
+		v.Set("domain", q.Domain)
+	}
+	return v
+}
+
+// --- Public API ---
+
+// ListRecords returns one page of LOC records.
+func (c *Client) ListRecords(ctx context.Context, q RecordQuery) (*api.ListRecordsResponse, error) {
+	var resp api.ListRecordsResponse
+	if err := c.do(ctx, http.MethodGet, "/api/public/records", q.values(), nil, &resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// ListAllRecords pages through ListRecords and returns every matching record.
+// q.Offset is used as the starting offset.
+func (c *Client) ListAllRecords(ctx context.Context, q RecordQuery) ([]api.PublicLOCRecord, error) {
+	var all []api.PublicLOCRecord
+	for {
+		page, err := c.ListRecords(ctx, q)
+		if err != nil {
+			return nil, err
+		}
+		all = append(all, page.Records...)
+		if !page.HasMore || page.NextOffset == nil {
+			return all, nil
+		}
+		q.Offset = *page.NextOffset
+	}
+}
+
+// GetRecordsGeoJSON returns LOC records aggregated by location as GeoJSON.
+// params accepts the same filters as ListRecords and may be nil.
+func (c *Client) GetRecordsGeoJSON(ctx context.Context, params url.Values) (*api.GeoJSONFeatureCollection, error) {
+	var resp api.GeoJSONFeatureCollection
+	if err := c.do(ctx, http.MethodGet, "/api/public/records.geojson", params, nil, &resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// ListRootDomains returns one page of root domains that have LOC records.
+func (c *Client) ListRootDomains(ctx context.Context, limit, offset int) (*api.ListRootDomainsResponse, error) {
+	v := url.Values{}
+	if limit > 0 {
+		v.Set("limit", strconv.Itoa(limit))
+	}
+	if offset > 0 {
+		v.Set("offset", strconv.Itoa(offset))
+	}
+	var resp api.ListRootDomainsResponse
+	if err := c.do(ctx, http.MethodGet, "/api/public/root-domains", v, nil, &resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// GetStats returns scanning statistics and progress.
+func (c *Client) GetStats(ctx context.Context) (*api.StatsResponse, error) {
+	var resp api.StatsResponse
+	if err := c.do(ctx, http.MethodGet, "/api/public/stats", nil, nil, &resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// --- Admin API ---
+
+// RegisterClient registers a new scanner client and returns its token.
+func (c *Client) RegisterClient(ctx context.Context, name string) (*api.RegisterClientResponse, error) {
+	var resp api.RegisterClientResponse
+	req := api.RegisterClientRequest{Name: name}
+	if err := c.do(ctx, http.MethodPost, "/api/admin/clients", nil, req, &resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// ListClients returns all registered scanner clients.
+func (c *Client) ListClients(ctx context.Context) ([]api.ClientInfo, error) {
+	var resp api.ListClientsResponse
+	if err := c.do(ctx, http.MethodGet, "/api/admin/clients", nil, nil, &resp); err != nil {
+		return nil, err
+	}
+	return resp.Clients, nil
+}
+
+// DeleteClient removes a scanner client.
+func (c *Client) DeleteClient(ctx context.Context, id string) error {
+	return c.do(ctx, http.MethodDelete, "/api/admin/clients/"+url.PathEscape(id), nil, nil, nil)
+}
+
+// DiscoverFiles triggers domain file discovery.
+func (c *Client) DiscoverFiles(ctx context.Context) (*api.DiscoverFilesResponse, error) {
+	var resp api.DiscoverFilesResponse
+	if err := c.do(ctx, http.MethodPost, "/api/admin/discover-files", nil, nil, &resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// ResetScan resets all domain files for a full re-scan.
+func (c *Client) ResetScan(ctx context.Context) (*api.ResetScanResponse, error) {
+	var resp api.ResetScanResponse
+	if err := c.do(ctx, http.MethodPost, "/api/admin/reset-scan", nil, nil, &resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// ManualScan queues a list of domains for scanning.
+func (c *Client) ManualScan(ctx context.Context, domains []string) (*api.ManualScanResponse, error) {
+	var resp api.ManualScanResponse
+	req := api.ManualScanRequest{Domains: domains}
+	if err := c.do(ctx, http.MethodPost, "/api/admin/manual-scan", nil, req, &resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// do performs a request and decodes the JSON response into out (if non-nil).
+func (c *Client) do(ctx context.Context, method, path string, query url.Values, in, out any) error {
+	u := c.BaseURL + path
+	if len(query) > 0 {
+		u += "?" + query.Encode()
+	}
+
+	var body io.Reader
+	if in != nil {
+		data, err := json.Marshal(in)
+		if err != nil {
+			return err
+		}
+		body = bytes.NewReader(data)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, u, body)
+	if err != nil {
+		return err
+	}
+	if in != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if c.AdminKey != "" && strings.HasPrefix(path, "/api/admin/") {
+		req.Header.Set("X-Admin-Key", c.AdminKey)
+	}
+
+	resp, err := c.HTTPClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close() //nolint:errcheck // Close error not actionable
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return decodeError(resp)
+	}
+
+	if out == nil || resp.StatusCode == http.StatusNoContent {
+		return nil
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
+
+// decodeError builds an *Error from an error response, using the API's
+// {"error": "..."} body when present.
+func decodeError(resp *http.Response) error {
+	data, _ := io.ReadAll(io.LimitReader(resp.Body, 64*1024)) //nolint:errcheck // Best effort to get error details
+	var e api.ErrorResponse
+	if err := json.Unmarshal(data, &e); err == nil && e.Error != "" {
+		return &Error{StatusCode: resp.StatusCode, Message: e.Error}
+	}
+	return &Error{StatusCode: resp.StatusCode, Message: strings.TrimSpace(string(data))}
+}
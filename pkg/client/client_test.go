@@ -0,0 +1,125 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"testing"
+
+	"github.com/locplace/scanner/pkg/api"
+)
+
+func TestListAllRecords_Paginates(t *testing.T) {
+	const total = 5
+	var calls int
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		if r.URL.Path != "/api/public/records" {
+			t.Errorf("path = %q, want /api/public/records", r.URL.Path)
+		}
+		if got := r.URL.Query().Get("bbox"); got != "4,52,5,53" {
+			t.Errorf("bbox = %q, want passthrough param", got)
+		}
+
+		offset, _ := strconv.Atoi(r.URL.Query().Get("offset"))
+		limit, _ := strconv.Atoi(r.URL.Query().Get("limit"))
+		var records []api.PublicLOCRecord
+		for i := offset; i < total && i < offset+limit; i++ {
+			records = append(records, api.PublicLOCRecord{FQDN: "host" + strconv.Itoa(i) + ".example"})
+		}
+		_ = json.NewEncoder(w).Encode(api.ListRecordsResponse{
+			Records:    records,
+			Pagination: api.NewPagination(total, limit, offset, len(records)),
+		})
+	}))
+	defer srv.Close()
+
+	c := New(srv.URL)
+	records, err := c.ListAllRecords(context.Background(), RecordQuery{
+		Limit:  2,
+		Params: url.Values{"bbox": {"4,52,5,53"}},
+	})
+	if err != nil {
+		t.Fatalf("ListAllRecords() error: %v", err)
+	}
+	if len(records) != total {
+		t.Errorf("got %d records, want %d", len(records), total)
+	}
+	if calls != 3 {
+		t.Errorf("made %d requests, want 3", calls)
+	}
+}
+
+func TestAdminKeyHeader(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if got := r.Header.Get("X-Admin-Key"); got != "secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			_, _ = w.Write([]byte(`{"error":"unauthorized"}`))
+			return
+		}
+		var req api.RegisterClientRequest
+		_ = json.NewDecoder(r.Body).Decode(&req)
+		w.WriteHeader(http.StatusCreated)
+		_ = json.NewEncoder(w).Encode(api.RegisterClientResponse{ID: "id-1", Name: req.Name, Token: "tok"})
+	}))
+	defer srv.Close()
+
+	c := New(srv.URL)
+	c.AdminKey = "secret"
+	resp, err := c.RegisterClient(context.Background(), "scanner-1")
+	if err != nil {
+		t.Fatalf("RegisterClient() error: %v", err)
+	}
+	if resp.Name != "scanner-1" || resp.Token != "tok" {
+		t.Errorf("unexpected response: %+v", resp)
+	}
+}
+
+func TestErrorDecoding(t *testing.T) {
+	tests := []struct {
+		name        string
+		status      int
+		body        string
+		wantMessage string
+	}{
+		{
+			name:        "json error body",
+			status:      http.StatusNotFound,
+			body:        `{"error":"client not found"}`,
+			wantMessage: "client not found",
+		},
+		{
+			name:        "plain text body",
+			status:      http.StatusBadGateway,
+			body:        "bad gateway\n",
+			wantMessage: "bad gateway",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(tt.status)
+				_, _ = w.Write([]byte(tt.body))
+			}))
+			defer srv.Close()
+
+			err := New(srv.URL).DeleteClient(context.Background(), "abc")
+			var apiErr *Error
+			if !errors.As(err, &apiErr) {
+				t.Fatalf("error = %v, want *Error", err)
+			}
+			if apiErr.StatusCode != tt.status {
+				t.Errorf("StatusCode = %d, want %d", apiErr.StatusCode, tt.status)
+			}
+			if apiErr.Message != tt.wantMessage {
+				t.Errorf("Message = %q, want %q", apiErr.Message, tt.wantMessage)
+			}
+		})
+	}
+}
@@ -0,0 +1,225 @@
+// Package loc parses DNS LOC records (RFC 1876) in presentation format.
+package loc
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/locplace/scanner/pkg/api"
+)
+
+// LOC record format from zdns:
+// "52 22 23.000 N 4 53 32.000 E -2.00m 1m 10000m 10m"
+// Format: d1 [m1 [s1]] N/S d2 [m2 [s2]] E/W alt [size [hp [vp]]]
+//
+// Minutes and seconds may be omitted (RFC 1876 presentation format), e.g.
+// "52 N 4 E 0m"; they default to zero. Omitted size and precisions take the
+// RFC 1876 defaults (api.DefaultSizeM, api.DefaultHorizPrecM and
+// api.DefaultVertPrecM).
+
+// dmsPattern matches degrees with optional minutes and seconds.
+const dmsPattern = `(\d+)(?:\s+(\d+)(?:\s+([\d.]+))?)?`
+
+var locRegex = regexp.MustCompile(
+	`^` + dmsPattern + `\s+([NS])\s+` + // latitude
+		dmsPattern + `\s+([EW])\s+` + // longitude
+		`(-?[\d.]+)m` + // altitude
+		`(?:\s*([\d.]+)m?` + // size (optional m suffix)
+		`(?:\s*([\d.]+)m?` + // horiz precision (optional m suffix)
+		`(?:\s*([\d.]+)m?)?)?)?$`, // vert precision (optional m suffix)
+)
+
+// coordRegex matches just the coordinates part, for lenient parsing.
+// Hemispheres may be any word; normalizeHemisphere checks them.
+var coordRegex = regexp.MustCompile(
+	dmsPattern + `\s+([A-Za-z]+)\s+` + dmsPattern + `\s+([A-Za-z]+)`,
+)
+
+// hemispheres maps the lowercased hemisphere tokens accepted by the lenient
+// parser to their RFC 1876 letter.
+var hemispheres = map[string]string{
+	"n": "N", "north": "N",
+	"s": "S", "south": "S",
+	"e": "E", "east": "E",
+	"w": "W", "west": "W",
+}
+
+// normalizeHemisphere returns the letter for a hemisphere token, matched
+// case-insensitively, and checks it belongs to axis ("NS" for latitude,
+// "EW" for longitude), so a swapped "45 0 0 E ..." is rejected.
+func normalizeHemisphere(tok, axis string) (string, error) {
+	h, ok := hemispheres[strings.ToLower(tok)]
+	if !ok {
+		return "", fmt.Errorf("unknown hemisphere %q", tok)
+	}
+	if !strings.Contains(axis, h) {
+		name := "latitude"
+		if axis == "EW" {
+			name = "longitude"
+		}
+		return "", fmt.Errorf("%s hemisphere must be %c or %c, got %q", name, axis[0], axis[1], tok)
+	}
+	return h, nil
+}
+
+// meterRegex matches meter values after the coordinates, for lenient parsing.
+var meterRegex = regexp.MustCompile(`(-?[\d.]+)m`)
+
+// dmsToDecimal converts degrees/minutes/seconds submatches to decimal degrees.
+// Empty minutes or seconds count as zero. maxDeg is 90 for latitude and 180
+// for longitude; minutes and seconds must be below 60.
+func dmsToDecimal(degs, mins, secs, hemi string, maxDeg float64) (float64, error) {
+	d, err := parseNumber(degs, 0)
+	if err != nil {
+		return 0, err
+	}
+	m, err := parseNumber(mins, 0)
+	if err != nil {
+		return 0, err
+	}
+	s, err := parseNumber(secs, 0)
+	if err != nil {
+		return 0, err
+	}
+	if m >= 60 || s >= 60 {
+		return 0, fmt.Errorf("minutes and seconds must be below 60: %s %s %s", degs, mins, secs)
+	}
+
+	v := d + m/60 + s/3600
+	if v > maxDeg {
+		return 0, fmt.Errorf("coordinate %s %s %s %s exceeds %g degrees", degs, mins, secs, hemi, maxDeg)
+	}
+	if hemi == "S" || hemi == "W" {
+		v = -v
+	}
+	return v, nil
+}
+
+// parseCoordinates converts the eight latitude/longitude submatches shared by
+// locRegex and coordRegex to decimal degrees.
+func parseCoordinates(m []string) (lat, lon float64, err error) {
+	if lat, err = dmsToDecimal(m[0], m[1], m[2], m[3], 90); err != nil {
+		return 0, 0, err
+	}
+	if lon, err = dmsToDecimal(m[4], m[5], m[6], m[7], 180); err != nil {
+		return 0, 0, err
+	}
+	return lat, lon, nil
+}
+
+// parseNumber parses an optional numeric submatch, returning def if it is
+// absent. The regexes accept any run of digits and dots, so malformed
+// numbers ("1.2.3") and overflowing ones are rejected here.
+func parseNumber(s string, def float64) (float64, error) {
+	if s == "" {
+		return def, nil
+	}
+	v, err := strconv.ParseFloat(s, 64)
+	if err != nil || math.IsNaN(v) || math.IsInf(v, 0) {
+		return 0, fmt.Errorf("invalid number: %q", s)
+	}
+	return v, nil
+}
+
+// Parse parses a LOC record string from zdns into structured data.
+// Input format: "52 22 23.000 N 4 53 32.000 E -2.00m 1m 10000m 10m"
+func Parse(fqdn, raw string) (*api.LOCRecord, error) {
+	raw = strings.TrimSpace(raw)
+
+	matches := locRegex.FindStringSubmatch(raw)
+	if matches == nil {
+		return nil, fmt.Errorf("invalid LOC record format: %s", raw)
+	}
+
+	latitude, longitude, err := parseCoordinates(matches[1:9])
+	if err != nil {
+		return nil, fmt.Errorf("invalid LOC record %s: %w", raw, err)
+	}
+
+	// Altitude is required by the regex; the rest fall back to RFC 1876 defaults
+	var vals [4]float64
+	defaults := [4]float64{0, api.DefaultSizeM, api.DefaultHorizPrecM, api.DefaultVertPrecM}
+	for i := range vals {
+		if vals[i], err = parseNumber(matches[9+i], defaults[i]); err != nil {
+			return nil, fmt.Errorf("invalid LOC record %s: %w", raw, err)
+		}
+	}
+	altitude, size, horizPrec, vertPrec := vals[0], vals[1], vals[2], vals[3]
+
+	rec := &api.LOCRecord{
+		FQDN:       fqdn,
+		RawRecord:  raw,
+		Latitude:   latitude,
+		Longitude:  longitude,
+		AltitudeM:  altitude,
+		SizeM:      size,
+		HorizPrecM: horizPrec,
+		VertPrecM:  vertPrec,
+	}
+	if err := rec.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid LOC record %s: %w", raw, err)
+	}
+	return rec, nil
+}
+
+// ParseLenient attempts to parse a LOC record with various formats.
+// Falls back to extracting what it can if strict parsing fails, accepting
+// hemispheres in any case and as full words ("North").
+func ParseLenient(fqdn, raw string) (*api.LOCRecord, error) {
+	// Try strict parsing first
+	if rec, err := Parse(fqdn, raw); err == nil {
+		return rec, nil
+	}
+
+	// Try to extract coordinates with more lenient regex
+	// Some records might have slightly different formatting
+	raw = strings.TrimSpace(raw)
+
+	matches := coordRegex.FindStringSubmatch(raw)
+	if matches == nil {
+		return nil, fmt.Errorf("could not parse LOC record: %s", raw)
+	}
+
+	coords := slices.Clone(matches[1:9])
+	var err error
+	if coords[3], err = normalizeHemisphere(coords[3], "NS"); err != nil {
+		return nil, fmt.Errorf("could not parse LOC record %s: %w", raw, err)
+	}
+	if coords[7], err = normalizeHemisphere(coords[7], "EW"); err != nil {
+		return nil, fmt.Errorf("could not parse LOC record %s: %w", raw, err)
+	}
+
+	latitude, longitude, err := parseCoordinates(coords)
+	if err != nil {
+		return nil, fmt.Errorf("could not parse LOC record %s: %w", raw, err)
+	}
+
+	// Try to extract altitude and precision from the rest
+	rest := raw[len(matches[0]):]
+	vals := [4]float64{0, api.DefaultSizeM, api.DefaultHorizPrecM, api.DefaultVertPrecM}
+	for i, m := range meterRegex.FindAllStringSubmatch(rest, len(vals)) {
+		if vals[i], err = parseNumber(m[1], vals[i]); err != nil {
+			return nil, fmt.Errorf("could not parse LOC record %s: %w", raw, err)
+		}
+	}
+	altitude, size, horizPrec, vertPrec := vals[0], vals[1], vals[2], vals[3]
+
+	rec := &api.LOCRecord{
+		FQDN:       fqdn,
+		RawRecord:  raw,
+		Latitude:   latitude,
+		Longitude:  longitude,
+		AltitudeM:  altitude,
+		SizeM:      size,
+		HorizPrecM: horizPrec,
+		VertPrecM:  vertPrec,
+	}
+	if err := rec.Validate(); err != nil {
+		return nil, fmt.Errorf("could not parse LOC record %s: %w", raw, err)
+	}
+	return rec, nil
+}
@@ -0,0 +1,67 @@
+package loc
+
+import (
+	"math"
+	"testing"
+)
+
+// locFuzzSeeds are LOC strings from the parser tests, plus malformed inputs
+// the fuzzer should start mutating from.
+var locFuzzSeeds = []string{
+	"32 53 1.000 N 117 14 25.000 W 107.00m 30m 10m 10m",
+	"42 21 43.528 N 71 5 6.284 W -25.00m 1m 3000m 10m",
+	"52 22 23.000 N 4 53 32.000 E -2.00m 1m 10000m 10m",
+	"90 0 0.000 S 180 0 0.000 W 0.00m 1m 1m 1m",
+	"52 N 4 E 0m",
+	"52 22 N 4 53 E 10m 2m",
+	"52 22 23.000 N 4 53 32.000 E 0.00m 1m 1m 1m ; comment",
+	"",
+	"N E m",
+	"... N ... E ...m",
+	"91 0 0 N 0 0 0 E 0m",
+	"0 60 0 N 0 0 0 E 0m",
+	"99999999999999999999999999999999999999999999 N 0 E 0m",
+}
+
+// checkFuzzedRecord fails if a successfully parsed record holds values no
+// valid LOC record can produce.
+func checkFuzzedRecord(t *testing.T, raw string, lat, lon, alt, size, hp, vp float64) {
+	t.Helper()
+	for name, v := range map[string]float64{"altitude": alt, "size": size, "horiz_prec": hp, "vert_prec": vp} {
+		if math.IsNaN(v) || math.IsInf(v, 0) {
+			t.Fatalf("%q: %s is %v", raw, name, v)
+		}
+	}
+	if math.IsNaN(lat) || lat < -90 || lat > 90 {
+		t.Fatalf("%q: latitude %v out of range", raw, lat)
+	}
+	if math.IsNaN(lon) || lon < -180 || lon > 180 {
+		t.Fatalf("%q: longitude %v out of range", raw, lon)
+	}
+}
+
+func FuzzParse(f *testing.F) {
+	for _, s := range locFuzzSeeds {
+		f.Add(s)
+	}
+	f.Fuzz(func(t *testing.T, raw string) {
+		rec, err := Parse("fuzz.example", raw)
+		if err != nil {
+			return
+		}
+		checkFuzzedRecord(t, raw, rec.Latitude, rec.Longitude, rec.AltitudeM, rec.SizeM, rec.HorizPrecM, rec.VertPrecM)
+	})
+}
+
+func FuzzParseLenient(f *testing.F) {
+	for _, s := range locFuzzSeeds {
+		f.Add(s)
+	}
+	f.Fuzz(func(t *testing.T, raw string) {
+		rec, err := ParseLenient("fuzz.example", raw)
+		if err != nil {
+			return
+		}
+		checkFuzzedRecord(t, raw, rec.Latitude, rec.Longitude, rec.AltitudeM, rec.SizeM, rec.HorizPrecM, rec.VertPrecM)
+	})
+}
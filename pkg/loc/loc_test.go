@@ -1,11 +1,12 @@
-package scanner
+package loc
 
 import (
 	"math"
+	"strings"
 	"testing"
 )
 
-func TestParseLOCRecord(t *testing.T) {
+func TestParse(t *testing.T) {
 	tests := []struct {
 		name      string
 		fqdn      string
@@ -128,17 +129,17 @@ func TestParseLOCRecord(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			got, err := ParseLOCRecord(tt.fqdn, tt.raw)
+			got, err := Parse(tt.fqdn, tt.raw)
 
 			if tt.wantErr {
 				if err == nil {
-					t.Errorf("ParseLOCRecord() expected error, got nil")
+					t.Errorf("Parse() expected error, got nil")
 				}
 				return
 			}
 
 			if err != nil {
-				t.Errorf("ParseLOCRecord() unexpected error: %v", err)
+				t.Errorf("Parse() unexpected error: %v", err)
 				return
 			}
 
@@ -170,7 +171,7 @@ func TestParseLOCRecord(t *testing.T) {
 	}
 }
 
-func TestParseLOCRecordLenient(t *testing.T) {
+func TestParseLenient(t *testing.T) {
 	tests := []struct {
 		name      string
 		fqdn      string
@@ -211,17 +212,17 @@ func TestParseLOCRecordLenient(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			got, err := ParseLOCRecordLenient(tt.fqdn, tt.raw)
+			got, err := ParseLenient(tt.fqdn, tt.raw)
 
 			if tt.wantErr {
 				if err == nil {
-					t.Errorf("ParseLOCRecordLenient() expected error, got nil")
+					t.Errorf("ParseLenient() expected error, got nil")
 				}
 				return
 			}
 
 			if err != nil {
-				t.Errorf("ParseLOCRecordLenient() unexpected error: %v", err)
+				t.Errorf("ParseLenient() unexpected error: %v", err)
 				return
 			}
 
@@ -236,7 +237,7 @@ func TestParseLOCRecordLenient(t *testing.T) {
 }
 
 func TestDMSToDecimal(t *testing.T) {
-	// Test the DMS to decimal conversion logic embedded in ParseLOCRecord
+	// Test the DMS to decimal conversion logic embedded in Parse
 	// by checking specific coordinate conversions
 
 	tests := []struct {
@@ -282,9 +283,9 @@ func TestDMSToDecimal(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			got, err := ParseLOCRecord("test.example", tt.raw)
+			got, err := Parse("test.example", tt.raw)
 			if err != nil {
-				t.Fatalf("ParseLOCRecord() error: %v", err)
+				t.Fatalf("Parse() error: %v", err)
 			}
 
 			if !floatEquals(got.Latitude, tt.wantLat, tt.tolerance) {
@@ -301,7 +302,7 @@ func floatEquals(a, b, tolerance float64) bool {
 	return math.Abs(a-b) <= tolerance
 }
 
-func TestParseLOCRecord_BoundaryValues(t *testing.T) {
+func TestParse_BoundaryValues(t *testing.T) {
 	// Test geographic boundary values
 	tests := []struct {
 		name      string
@@ -365,21 +366,62 @@ func TestParseLOCRecord_BoundaryValues(t *testing.T) {
 			wantErr:   false,
 			tolerance: 0.0001,
 		},
+		{
+			name:    "latitude beyond pole",
+			raw:     "90 0 1.000 N 0 0 0.000 E 0.00m 1m 1m 1m",
+			wantErr: true,
+		},
+		{
+			name:    "longitude beyond date line",
+			raw:     "0 0 0.000 N 180 1 0.000 E 0.00m 1m 1m 1m",
+			wantErr: true,
+		},
+		{
+			name:    "minutes out of range",
+			raw:     "10 60 0.000 N 0 0 0.000 E 0.00m 1m 1m 1m",
+			wantErr: true,
+		},
+		{
+			name:    "seconds out of range",
+			raw:     "10 0 60.000 N 0 0 0.000 E 0.00m 1m 1m 1m",
+			wantErr: true,
+		},
+		{
+			// Found by FuzzParse: [\d.]+ matches more than valid numbers
+			name:    "malformed number",
+			raw:     "10 0 1.2.3 N 0 0 0.000 E 0.00m 1m 1m 1m",
+			wantErr: true,
+		},
+		{
+			name:    "nan token",
+			raw:     "nan 0 0.000 N 0 0 0.000 E 0.00m 1m 1m 1m",
+			wantErr: true,
+		},
+		{
+			name:    "inf altitude",
+			raw:     "10 0 0.000 N 0 0 0.000 E infm 1m 1m 1m",
+			wantErr: true,
+		},
+		{
+			name:    "overflowing altitude",
+			raw:     "10 0 0.000 N 0 0 0.000 E 1" + strings.Repeat("0", 400) + "m",
+			wantErr: true,
+		},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			got, err := ParseLOCRecord("test.example", tt.raw)
+			got, err := Parse("test.example", tt.raw)
 
 			if tt.wantErr {
 				if err == nil {
-					t.Errorf("ParseLOCRecord() expected error, got nil")
+					t.Errorf("Parse() expected error, got nil")
 				}
 				return
 			}
 
 			if err != nil {
-				t.Errorf("ParseLOCRecord() unexpected error: %v", err)
+				t.Errorf("Parse() unexpected error: %v", err)
 				return
 			}
 
@@ -393,7 +435,20 @@ func TestParseLOCRecord_BoundaryValues(t *testing.T) {
 	}
 }
 
-func TestParseLOCRecordLenient_Fallback(t *testing.T) {
+func TestParseNumber(t *testing.T) {
+	// ParseFloat accepts these spellings; the regexes currently don't, but
+	// parseNumber must not rely on that
+	for _, s := range []string{"NaN", "nan", "Inf", "-inf", "+Infinity", "1e400", "1.2.3", "."} {
+		if v, err := parseNumber(s, 0); err == nil {
+			t.Errorf("parseNumber(%q) = %v, want error", s, v)
+		}
+	}
+	if v, err := parseNumber("", 7); err != nil || v != 7 {
+		t.Errorf("parseNumber(\"\") = %v, %v, want default 7", v, err)
+	}
+}
+
+func TestParseLenient_Fallback(t *testing.T) {
 	// Test cases where strict parsing fails but lenient succeeds
 	tests := []struct {
 		name      string
@@ -424,6 +479,31 @@ func TestParseLOCRecordLenient_Fallback(t *testing.T) {
 			wantErr:   false,
 			tolerance: 0.0001,
 		},
+		{
+			name:      "full-word hemispheres",
+			raw:       "52 22 23.000 North 4 53 32.000 East -2.00m",
+			wantLat:   52.373055556,
+			wantLon:   4.892222222,
+			wantAlt:   -2.0,
+			tolerance: 0.0001,
+		},
+		{
+			name:      "lowercase hemispheres",
+			raw:       "33 51 35.9 s 151 12 40 w 0.00m",
+			wantLat:   -33.859972222,
+			wantLon:   -151.211111111,
+			tolerance: 0.0001,
+		},
+		{
+			name:    "swapped hemisphere axes",
+			raw:     "45 0 0 E 10 0 0 N 0.00m",
+			wantErr: true,
+		},
+		{
+			name:    "unknown hemisphere word",
+			raw:     "45 0 0 Up 10 0 0 E 0.00m",
+			wantErr: true,
+		},
 		{
 			// Tabs instead of spaces - \s in regex matches any whitespace including tabs
 			name:      "tabs as separators",
@@ -438,17 +518,17 @@ func TestParseLOCRecordLenient_Fallback(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			got, err := ParseLOCRecordLenient("test.example", tt.raw)
+			got, err := ParseLenient("test.example", tt.raw)
 
 			if tt.wantErr {
 				if err == nil {
-					t.Errorf("ParseLOCRecordLenient() expected error, got nil")
+					t.Errorf("ParseLenient() expected error, got nil")
 				}
 				return
 			}
 
 			if err != nil {
-				t.Errorf("ParseLOCRecordLenient() unexpected error: %v", err)
+				t.Errorf("ParseLenient() unexpected error: %v", err)
 				return
 			}
 
@@ -465,7 +545,7 @@ func TestParseLOCRecordLenient_Fallback(t *testing.T) {
 	}
 }
 
-func TestParseLOCRecord_MeterSuffixVariations(t *testing.T) {
+func TestParse_MeterSuffixVariations(t *testing.T) {
 	// The regex allows optional 'm' suffix on size/horiz/vert fields
 	// Test that both formats work
 	tests := []struct {
@@ -504,9 +584,9 @@ func TestParseLOCRecord_MeterSuffixVariations(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			got, err := ParseLOCRecord("test.example", tt.raw)
+			got, err := Parse("test.example", tt.raw)
 			if err != nil {
-				t.Fatalf("ParseLOCRecord() error: %v", err)
+				t.Fatalf("Parse() error: %v", err)
 			}
 
 			if !floatEquals(got.SizeM, tt.wantSize, tt.tolerance) {
@@ -522,14 +602,14 @@ func TestParseLOCRecord_MeterSuffixVariations(t *testing.T) {
 	}
 }
 
-func TestParseLOCRecord_PreservesRawRecord(t *testing.T) {
+func TestParse_PreservesRawRecord(t *testing.T) {
 	// Verify that RawRecord field contains the original (trimmed) input
 	raw := "  52 22 23.000 N 4 53 32.000 E -2.00m 1m 10000m 10m  "
 	expected := "52 22 23.000 N 4 53 32.000 E -2.00m 1m 10000m 10m"
 
-	got, err := ParseLOCRecord("test.example", raw)
+	got, err := Parse("test.example", raw)
 	if err != nil {
-		t.Fatalf("ParseLOCRecord() error: %v", err)
+		t.Fatalf("Parse() error: %v", err)
 	}
 
 	if got.RawRecord != expected {
@@ -540,3 +620,110 @@ func TestParseLOCRecord_PreservesRawRecord(t *testing.T) {
 		t.Errorf("FQDN = %q, want %q", got.FQDN, "test.example")
 	}
 }
+
+func TestParse_PartialDMS(t *testing.T) {
+	tests := []struct {
+		name      string
+		raw       string
+		wantLat   float64
+		wantLon   float64
+		wantSize  float64
+		wantHoriz float64
+		wantVert  float64
+	}{
+		{
+			name:    "degrees only, RFC defaults for size and precision",
+			raw:     "52 N 4 E 0m",
+			wantLat: 52, wantLon: 4,
+			wantSize: 1, wantHoriz: 10000, wantVert: 10,
+		},
+		{
+			name:    "degrees and minutes",
+			raw:     "52 30 S 4 15 W 0.00m 1m 100m 10m",
+			wantLat: -52.5, wantLon: -4.25,
+			wantSize: 1, wantHoriz: 100, wantVert: 10,
+		},
+		{
+			name:    "degrees-only latitude, full DMS longitude",
+			raw:     "52 N 4 53 32.000 E -2.00m 1m 10000m 10m",
+			wantLat: 52, wantLon: 4.892222,
+			wantSize: 1, wantHoriz: 10000, wantVert: 10,
+		},
+		{
+			name:    "degrees and minutes longitude, size only",
+			raw:     "52 22 23.000 N 4 53 E 5m 20m",
+			wantLat: 52.373056, wantLon: 4.883333,
+			wantSize: 20, wantHoriz: 10000, wantVert: 10,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec, err := Parse("example.com", tt.raw)
+			if err != nil {
+				t.Fatalf("Parse() error: %v", err)
+			}
+			if math.Abs(rec.Latitude-tt.wantLat) > 0.0001 {
+				t.Errorf("Latitude = %v, want %v", rec.Latitude, tt.wantLat)
+			}
+			if math.Abs(rec.Longitude-tt.wantLon) > 0.0001 {
+				t.Errorf("Longitude = %v, want %v", rec.Longitude, tt.wantLon)
+			}
+			if rec.SizeM != tt.wantSize || rec.HorizPrecM != tt.wantHoriz || rec.VertPrecM != tt.wantVert {
+				t.Errorf("size/horiz/vert = %v/%v/%v, want %v/%v/%v",
+					rec.SizeM, rec.HorizPrecM, rec.VertPrecM, tt.wantSize, tt.wantHoriz, tt.wantVert)
+			}
+		})
+	}
+}
+
+func TestParse_StrictHemispheres(t *testing.T) {
+	// Only the lenient parser accepts words and lowercase letters
+	for _, raw := range []string{
+		"52 22 23.000 North 4 53 32.000 East -2.00m 1m 10000m 10m",
+		"52 22 23.000 n 4 53 32.000 e -2.00m 1m 10000m 10m",
+		"45 0 0 E 10 0 0 N 0.00m 1m 10000m 10m",
+	} {
+		if _, err := Parse("test.example", raw); err == nil {
+			t.Errorf("Parse(%q) succeeded, want error", raw)
+		}
+	}
+}
+
+func TestParse_StringRoundTrip(t *testing.T) {
+	for _, raw := range []string{
+		"52 22 23.000 N 4 53 32.000 E -2.00m 1m 10000m 10m",
+		"33 51 24.480 S 151 12 54.720 E 0.00m 0.50m 30m 2m",
+		"0 0 0.000 N 180 0 0.000 W 42849672.95m 90000000m 0m 0m",
+	} {
+		rec, err := Parse("example.com", raw)
+		if err != nil {
+			t.Fatalf("Parse(%q): %v", raw, err)
+		}
+		if got := rec.String(); got != raw {
+			t.Errorf("String() = %q, want %q", got, raw)
+		}
+	}
+}
+
+func TestNormalizeHemisphere(t *testing.T) {
+	tests := []struct {
+		tok, axis string
+		want      string
+		wantErr   bool
+	}{
+		{"N", "NS", "N", false},
+		{"south", "NS", "S", false},
+		{"EAST", "EW", "E", false},
+		{"w", "EW", "W", false},
+		{"E", "NS", "", true},
+		{"North", "EW", "", true},
+		{"Nord", "NS", "", true},
+	}
+	for _, tt := range tests {
+		got, err := normalizeHemisphere(tt.tok, tt.axis)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("normalizeHemisphere(%q, %q) = %q, %v; want %q, error %v", tt.tok, tt.axis, got, err, tt.want, tt.wantErr)
+		}
+	}
+}